@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -345,6 +346,43 @@ func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log)
 	return parseTopics(out, indexed, log.Topics[1:])
 }
 
+// UnpackLogIntoValues unpacks a retrieved log into the provided values, which
+// must be pointers to the event arguments in declaration order. It serves the
+// bindings that cannot declare a typed event structure, like the mobile ones.
+func (c *BoundContract) UnpackLogIntoValues(out []interface{}, event string, log types.Log) error {
+	ev, ok := c.abi.Events[event]
+	if !ok {
+		return fmt.Errorf("unknown event %q", event)
+	}
+	if len(out) != len(ev.Inputs) {
+		return fmt.Errorf("event %s has %d arguments, %d values given", event, len(ev.Inputs), len(out))
+	}
+	// Assemble a structure with a field for each argument to unpack into
+	fields := make([]reflect.StructField, len(out))
+	for i, arg := range ev.Inputs {
+		if arg.Name == "" {
+			return fmt.Errorf("argument %d of event %s is unnamed", i, event)
+		}
+		typ := reflect.TypeOf(out[i])
+		if typ == nil || typ.Kind() != reflect.Ptr {
+			return fmt.Errorf("value %d of event %s is not a pointer", i, event)
+		}
+		fields[i] = reflect.StructField{Name: capitalise(arg.Name), Type: typ.Elem()}
+		if !arg.Indexed {
+			// Topics are matched by field name, data by the original argument name
+			fields[i].Tag = reflect.StructTag(fmt.Sprintf(`abi:"%s"`, arg.Name))
+		}
+	}
+	decoded := reflect.New(reflect.StructOf(fields))
+	if err := c.UnpackLog(decoded.Interface(), event, log); err != nil {
+		return err
+	}
+	for i := range out {
+		reflect.ValueOf(out[i]).Elem().Set(decoded.Elem().Field(i))
+	}
+	return nil
+}
+
 // ensureContext is a helper method to ensure a context is not nil, even if the
 // user specified it as such.
 func ensureContext(ctx context.Context) context.Context {
@@ -0,0 +1,60 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that logs can be unpacked into positional values.
+func TestUnpackLogIntoValues(t *testing.T) {
+	const definition = `[{"anonymous":false,"inputs":[{"indexed":true,"name":"_from","type":"address"},{"indexed":false,"name":"token_value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := NewBoundContract(common.Address{}, parsed, nil, nil, nil)
+
+	from := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	log := types.Log{
+		Topics: []common.Hash{parsed.Events["Transfer"].Id(), common.BytesToHash(from[:])},
+		Data:   common.LeftPadBytes(big.NewInt(1000).Bytes(), 32),
+	}
+	var (
+		gotFrom  common.Address
+		gotValue *big.Int
+	)
+	if err := contract.UnpackLogIntoValues([]interface{}{&gotFrom, &gotValue}, "Transfer", log); err != nil {
+		t.Fatalf("failed to unpack log: %v", err)
+	}
+	if gotFrom != from {
+		t.Errorf("from mismatch: have %x, want %x", gotFrom, from)
+	}
+	if gotValue == nil || gotValue.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("value mismatch: have %v, want 1000", gotValue)
+	}
+	if err := contract.UnpackLogIntoValues([]interface{}{&gotFrom}, "Transfer", log); err == nil {
+		t.Errorf("unpacked log into too few values")
+	}
+}
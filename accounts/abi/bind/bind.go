@@ -329,7 +329,7 @@ func bindTopicTypeGo(kind abi.Type) string {
 // funcionality as for simple types, but dynamic types get converted to hashes.
 func bindTopicTypeJava(kind abi.Type) string {
 	bound := bindTypeJava(kind)
-	if bound == "String" || bound == "Bytes" {
+	if kind.T == abi.StringTy || kind.T == abi.BytesTy {
 		bound = "Hash"
 	}
 	return bound
@@ -866,3 +866,27 @@ func TestBindings(t *testing.T) {
 		t.Fatalf("failed to run binding test: %v\n%s", err, out)
 	}
 }
+
+// Tests that Java bindings contain typed watchers for the contract events.
+func TestBindJavaEvents(t *testing.T) {
+	abi := `[{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"memo","type":"string"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+
+	code, err := Bind([]string{"Token"}, []string{abi}, []string{""}, "bindtest", LangJava)
+	if err != nil {
+		t.Fatalf("failed to generate binding: %v", err)
+	}
+	for _, want := range []string{
+		"public class TransferEvent {",
+		"public Address from;",
+		"public Hash memo;",
+		"public BigInt value;",
+		"value1.setDefaultHash();",
+		"this.Contract.unpackLog(values, \"Transfer\", log);",
+		"public TransferEvent[] filterTransfer(FilterOpts opts) throws Exception {",
+		"public Subscription watchTransfer(WatchOpts opts, final TransferHandler handler) throws Exception {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("binding missing %q", want)
+		}
+	}
+}
@@ -517,6 +517,72 @@ import org.ethereum.geth.internal.*;
 				return this.Contract.transact(opts, "{{.Original.Name}}"	, args);
 			}
 		{{end}}
+
+		{{range .Events}}
+			// {{capitalise .Normalized.Name}}Event represents a {{.Normalized.Name}} event raised by the {{$contract.Type}} contract.
+			public class {{capitalise .Normalized.Name}}Event {
+				{{range .Normalized.Inputs}}public {{if .Indexed}}{{bindtopictype .Type}}{{else}}{{bindtype .Type}}{{end}} {{.Name}};
+				{{end}}
+				public Log Raw; // Blockchain specific contextual infos
+			}
+
+			// {{capitalise .Normalized.Name}}Handler is a callback to invoke on {{.Normalized.Name}} events raised by the {{$contract.Type}} contract.
+			public interface {{capitalise .Normalized.Name}}Handler {
+				void on{{capitalise .Normalized.Name}}({{capitalise .Normalized.Name}}Event event);
+				void onError(String failure);
+			}
+
+			// unpack{{capitalise .Normalized.Name}} decodes a log into a {{.Normalized.Name}} event.
+			private {{capitalise .Normalized.Name}}Event unpack{{capitalise .Normalized.Name}}(Log log) throws Exception {
+				Interfaces values = Geth.newInterfaces({{(len .Normalized.Inputs)}});
+				{{range $index, $item := .Normalized.Inputs}}Interface value{{$index}} = Geth.newInterface(); value{{$index}}.setDefault{{if .Indexed}}{{namedtype (bindtopictype .Type) .Type}}{{else}}{{namedtype (bindtype .Type) .Type}}{{end}}(); values.set({{$index}}, value{{$index}});
+				{{end}}
+				this.Contract.unpackLog(values, "{{.Original.Name}}", log);
+
+				{{capitalise .Normalized.Name}}Event event = new {{capitalise .Normalized.Name}}Event();
+				{{range $index, $item := .Normalized.Inputs}}event.{{.Name}} = values.get({{$index}}).get{{if .Indexed}}{{namedtype (bindtopictype .Type) .Type}}{{else}}{{namedtype (bindtype .Type) .Type}}{{end}}();
+				{{end}}
+				event.Raw = log;
+				return event;
+			}
+
+			// filter{{capitalise .Normalized.Name}} retrieves the past {{.Normalized.Name}} events raised by the contract.
+			//
+			// Solidity: {{.Original.String}}
+			public {{capitalise .Normalized.Name}}Event[] filter{{capitalise .Normalized.Name}}(FilterOpts opts) throws Exception {
+				if (opts == null) {
+					opts = Geth.newFilterOpts();
+				}
+				Logs logs = this.Contract.filterLogs(opts, "{{.Original.Name}}", Geth.newTopicsEmpty());
+
+				{{capitalise .Normalized.Name}}Event[] events = new {{capitalise .Normalized.Name}}Event[(int)logs.size()];
+				for (int i = 0; i < events.length; i++) {
+					events[i] = unpack{{capitalise .Normalized.Name}}(logs.get(i));
+				}
+				return events;
+			}
+
+			// watch{{capitalise .Normalized.Name}} subscribes to the future {{.Normalized.Name}} events raised by the contract.
+			//
+			// Solidity: {{.Original.String}}
+			public Subscription watch{{capitalise .Normalized.Name}}(WatchOpts opts, final {{capitalise .Normalized.Name}}Handler handler) throws Exception {
+				if (opts == null) {
+					opts = Geth.newWatchOpts();
+				}
+				return this.Contract.watchLogs(opts, "{{.Original.Name}}", Geth.newTopicsEmpty(), new FilterLogsHandler() {
+					@Override public void onFilterLogs(Log log) {
+						try {
+							handler.on{{capitalise .Normalized.Name}}(unpack{{capitalise .Normalized.Name}}(log));
+						} catch (Exception e) {
+							handler.onError(e.getMessage());
+						}
+					}
+					@Override public void onError(String failure) {
+						handler.onError(failure);
+					}
+				}, 128);
+			}
+		{{end}}
 	}
 {{end}}
 `
@@ -27,6 +27,14 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 )
 
+// Mime types of the data requested to be signed, telling signers how to interpret
+// and display it.
+const (
+	MimetypeTextPlain = "text/plain"                  // Personal message, hashed with the "\x19Ethereum Signed Message:\n" prefix
+	MimetypeValidator = "data/validator"              // Data for an intended validator contract, as defined by EIP-191 version 0x00
+	MimetypeClique    = "application/x-clique-header" // Clique header without its seal, as encoded by clique.CliqueRLP
+)
+
 // Account represents an Ethereum account located at a specific location defined
 // by the optional URL field.
 // 어카운트 구조체는 URL필드에 정의된 특정위치에 존재하는 이더리움 계정을 나타낸다
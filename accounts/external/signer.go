@@ -0,0 +1,67 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements a client of an external clef signer, letting the
+// node request signatures without holding the keys itself.
+package external
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer is a client of the external API of a clef signer.
+type Signer struct {
+	endpoint string
+	client   *rpc.Client
+}
+
+// NewSigner connects to a clef signer listening on the given endpoint, an HTTP
+// or websocket URL or the path of an IPC socket.
+func NewSigner(endpoint string) (*Signer, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{endpoint: endpoint, client: client}, nil
+}
+
+// Endpoint returns the endpoint of the signer.
+func (s *Signer) Endpoint() string {
+	return s.endpoint
+}
+
+// SignData requests the signer to sign data of the given mime type with an
+// account, waiting for the request to be approved. The V value of the returned
+// signature is 0 or 1 for clique headers and 27 or 28 for everything else.
+func (s *Signer) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	var signature hexutil.Bytes
+	if err := s.client.Call(&signature, "account_signData", mimeType, account.Address, hexutil.Bytes(data)); err != nil {
+		return nil, err
+	}
+	if len(signature) != 65 {
+		return nil, fmt.Errorf("invalid signature length %d", len(signature))
+	}
+	return signature, nil
+}
+
+// Close disconnects from the signer.
+func (s *Signer) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// StubSignerAPI mimics the external API of clef, signing data by repeating its
+// first byte.
+type StubSignerAPI struct {
+	contentType string
+	addr        common.MixedcaseAddress
+}
+
+func (api *StubSignerAPI) SignData(ctx context.Context, contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	api.contentType, api.addr = contentType, addr
+	if len(data) == 0 {
+		return nil, errors.New("nothing to sign")
+	}
+	return bytes.Repeat(data[:1], 65), nil
+}
+
+// Tests that signing requests are relayed to the external API of the signer.
+func TestSignData(t *testing.T) {
+	api := new(StubSignerAPI)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", api); err != nil {
+		t.Fatalf("failed to register signer API: %v", err)
+	}
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	signer, err := NewSigner(httpsrv.URL)
+	if err != nil {
+		t.Fatalf("failed to connect to signer: %v", err)
+	}
+	defer signer.Close()
+
+	account := accounts.Account{Address: common.HexToAddress("0x1337")}
+	sig, err := signer.SignData(account, accounts.MimetypeClique, []byte{0x01, 0x02})
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	if !bytes.Equal(sig, bytes.Repeat([]byte{0x01}, 65)) {
+		t.Errorf("signature mismatch: have %x", sig)
+	}
+	if api.contentType != accounts.MimetypeClique {
+		t.Errorf("content type mismatch: have %s, want %s", api.contentType, accounts.MimetypeClique)
+	}
+	if api.addr.Address() != account.Address {
+		t.Errorf("account mismatch: have %x, want %x", api.addr.Address(), account.Address)
+	}
+	if _, err := signer.SignData(account, accounts.MimetypeClique, nil); err == nil {
+		t.Errorf("expected signer error to be returned")
+	}
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
@@ -101,7 +102,14 @@ var (
 		utils.GCModeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightStateFlag,
 		utils.LightKDFFlag,
+		utils.CheckpointFlag,
+		utils.CheckpointOracleFlag,
+		utils.CheckpointSignersFlag,
+		utils.CheckpointThresholdFlag,
+		utils.ULCServersFlag,
+		utils.ULCFractionFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
@@ -109,6 +117,9 @@ var (
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.ReservedPeersFlag,
+		utils.MaxPeersPerSubnetFlag,
+		utils.PeerEvictionFlag,
 		utils.EtherbaseFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
@@ -135,6 +146,14 @@ var (
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
 		utils.ExtraDataFlag,
+		utils.MinerGasFloorFlag,
+		utils.MinerGasCeilFlag,
+		utils.MinerRecommitIntervalFlag,
+		utils.MinerTxOrderFlag,
+		utils.MinerNotifyFlag,
+		utils.ExternalSignerFlag,
+		utils.MinerStratumFlag,
+		utils.MinerStratumDiffFlag,
 		configFileFlag,
 	}
 
@@ -218,11 +237,6 @@ func init() {
 		//TODO:어떤 용도로 쓰는지는 차후확인
 		//metics/metrics.go 파일을 참조
 		go metrics.CollectProcessMetrics(3 * time.Second)
-
-		//네트워크를 셋업한다
-		//TargetGasLimit값을 설정함
-		//utils/flags.go
-		utils.SetupNetwork(ctx)
 		return nil
 	}
 	//Before는 sub command가 실행된 후 불리우는 함수
@@ -389,6 +403,26 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			}
 		}
 	}()
+	// Follow the checkpoint oracle through the local node
+	if ctx.GlobalIsSet(utils.CheckpointOracleFlag.Name) {
+		rpcClient, err := stack.Attach()
+		if err != nil {
+			utils.Fatalf("Failed to attach to self: %v", err)
+		}
+		if ctx.GlobalBool(utils.LightModeFlag.Name) || ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+			var lightEthereum *les.LightEthereum
+			if err := stack.Service(&lightEthereum); err != nil {
+				utils.Fatalf("Light ethereum service not running: %v", err)
+			}
+			lightEthereum.SetContractBackend(ethclient.NewClient(rpcClient))
+		} else {
+			var ethereum *eth.Ethereum
+			if err := stack.Service(&ethereum); err != nil {
+				utils.Fatalf("Ethereum service not running: %v", err)
+			}
+			ethereum.SetContractBackend(ethclient.NewClient(rpcClient))
+		}
+	}
 	// Start auxiliary services if enabled
 	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
 		// Mining only makes sense if a full Ethereum node is running
@@ -47,14 +47,17 @@ Regular users do not need to execute it.
 	makedagCommand = cli.Command{
 		Action:    utils.MigrateFlags(makedag),
 		Name:      "makedag",
-		Usage:     "Generate ethash mining DAG (for testing)",
-		ArgsUsage: "<blockNum> <outputDir>",
+		Usage:     "Generate ethash mining DAGs",
+		ArgsUsage: "<blockNum> <outputDir> [epochs]",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
-The makedag command generates an ethash DAG in <outputDir>.
+The makedag command generates the ethash DAG of the epoch containing <blockNum>
+in <outputDir>, followed by the DAGs of the next [epochs]-1 epochs (default 1),
+e.g. to prepare the DAG of the next epoch ahead of time.
 
-This command exists to support the system testing project.
-Regular users do not need to execute it.
+A running node can pre-generate DAGs into its own DAG directory in the
+background via the ethash.generateDAG(blockNum) console method, following
+the progress via ethash.progress.
 `,
 	}
 	versionCommand = cli.Command{
@@ -91,18 +94,26 @@ func makecache(ctx *cli.Context) error {
 	return nil
 }
 
-// makedag generates an ethash mining DAG into the provided folder.
+// makedag generates ethash mining DAGs of one or more consecutive epochs into
+// the provided folder.
 func makedag(ctx *cli.Context) error {
 	args := ctx.Args()
-	if len(args) != 2 {
-		utils.Fatalf(`Usage: geth makedag <block number> <outputdir>`)
+	if len(args) != 2 && len(args) != 3 {
+		utils.Fatalf(`Usage: geth makedag <block number> <outputdir> [epochs]`)
 	}
 	block, err := strconv.ParseUint(args[0], 0, 64)
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeDataset(block, args[1])
-
+	epochs := uint64(1)
+	if len(args) == 3 {
+		if epochs, err = strconv.ParseUint(args[2], 0, 64); err != nil || epochs == 0 {
+			utils.Fatalf("Invalid epoch count: %s", args[2])
+		}
+	}
+	for i := uint64(0); i < epochs; i++ {
+		ethash.MakeDataset(block+i*params.EpochDuration, args[1])
+	}
 	return nil
 }
 
@@ -80,9 +80,20 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightStateFlag,
 			utils.LightKDFFlag,
 		},
 	},
+	{Name: "CHECKPOINT",
+		Flags: []cli.Flag{
+			utils.CheckpointFlag,
+			utils.CheckpointOracleFlag,
+			utils.CheckpointSignersFlag,
+			utils.CheckpointThresholdFlag,
+			utils.ULCServersFlag,
+			utils.ULCFractionFlag,
+		},
+	},
 	{Name: "DEVELOPER CHAIN",
 		Flags: []cli.Flag{
 			utils.DeveloperFlag,
@@ -171,6 +182,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.ReservedPeersFlag,
+			utils.MaxPeersPerSubnetFlag,
+			utils.PeerEvictionFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
@@ -188,6 +202,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.MinerGasFloorFlag,
+			utils.MinerGasCeilFlag,
+			utils.MinerRecommitIntervalFlag,
+			utils.MinerTxOrderFlag,
+			utils.MinerNotifyFlag,
+			utils.ExternalSignerFlag,
+			utils.MinerStratumFlag,
+			utils.MinerStratumDiffFlag,
 		},
 	},
 	{
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -52,6 +53,7 @@ func (w *wizard) makeGenesis() {
 	fmt.Println("Which consensus engine to use? (default = clique)")
 	fmt.Println(" 1. Ethash - proof-of-work")
 	fmt.Println(" 2. Clique - proof-of-authority")
+	fmt.Println(" 3. BFT    - proof-of-authority with instant finality")
 
 	choice := w.read()
 	switch {
@@ -98,6 +100,35 @@ func (w *wizard) makeGenesis() {
 			copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
 		}
 
+	case choice == "3":
+		// In the case of BFT, configure the consensus parameters
+		genesis.Difficulty = big.NewInt(1)
+		genesis.Mixhash = bft.MixDigest
+		genesis.Config.BFT = &params.BFTConfig{
+			Period:         5,
+			Epoch:          30000,
+			RequestTimeout: 10000,
+		}
+		fmt.Println()
+		fmt.Println("How many seconds should blocks take? (default = 5)")
+		genesis.Config.BFT.Period = uint64(w.readDefaultInt(5))
+
+		// We also need the initial list of validators
+		fmt.Println()
+		fmt.Println("Which accounts are validators? (mandatory at least one, tolerating a third of them faulty)")
+
+		var validators []common.Address
+		for {
+			if address := w.readAddress(); address != nil {
+				validators = append(validators, *address)
+				continue
+			}
+			if len(validators) > 0 {
+				break
+			}
+		}
+		genesis.ExtraData = bft.GenesisExtra(validators)
+
 	default:
 		log.Crit("Invalid consensus engine choice", "choice", choice)
 	}
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -48,6 +49,7 @@ import (
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
@@ -168,7 +170,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -186,10 +188,42 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: eth.DefaultConfig.LightPeers,
 	}
+	LightStateFlag = cli.Uint64Flag{
+		Name:  "lightstate",
+		Usage: "Number of recent blocks whose state is served to LES clients (0 = all available)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	// Checkpoint settings
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "Trusted checkpoint to sync from (<section index>:<section head>:<cht root>:<bloom trie root>)",
+	}
+	CheckpointOracleFlag = cli.StringFlag{
+		Name:  "checkpoint.oracle",
+		Usage: "Address of the checkpoint oracle contract to follow newer checkpoints from",
+	}
+	CheckpointSignersFlag = cli.StringFlag{
+		Name:  "checkpoint.signers",
+		Usage: "Comma separated addresses of the trusted checkpoint oracle signers",
+	}
+	CheckpointThresholdFlag = cli.Uint64Flag{
+		Name:  "checkpoint.threshold",
+		Usage: "Minimum number of trusted signers required to accept an oracle checkpoint",
+		Value: 1,
+	}
+	// Ultra light client settings
+	ULCServersFlag = cli.StringFlag{
+		Name:  "ulc.servers",
+		Usage: "Comma separated enode URLs of the trusted LES servers of an ultra light client",
+	}
+	ULCFractionFlag = cli.IntFlag{
+		Name:  "ulc.fraction",
+		Usage: "Minimum percentage of trusted ultra light client servers announcing a head",
+		Value: eth.DefaultULCMinTrustedFraction,
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -323,8 +357,18 @@ var (
 	}
 	TargetGasLimitFlag = cli.Uint64Flag{
 		Name:  "targetgaslimit",
-		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine",
-		Value: params.GenesisGasLimit,
+		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine (deprecated, use --minergasfloor)",
+		Value: eth.DefaultConfig.MinerGasFloor,
+	}
+	MinerGasFloorFlag = cli.Uint64Flag{
+		Name:  "minergasfloor",
+		Usage: "Target gas limit of the blocks to mine while they are mostly empty",
+		Value: eth.DefaultConfig.MinerGasFloor,
+	}
+	MinerGasCeilFlag = cli.Uint64Flag{
+		Name:  "minergasceil",
+		Usage: "Target gas limit of the blocks to mine while they are full",
+		Value: eth.DefaultConfig.MinerGasCeil,
 	}
 	EtherbaseFlag = cli.StringFlag{
 		Name:  "etherbase",
@@ -340,6 +384,33 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerRecommitIntervalFlag = cli.DurationFlag{
+		Name:  "minerrecommit",
+		Usage: "Minimum time interval to recreate the block being mined with new transactions",
+		Value: eth.DefaultConfig.MinerRecommit,
+	}
+	MinerTxOrderFlag = cli.StringFlag{
+		Name:  "minertxorder",
+		Usage: "Transaction ordering policy of the mined blocks (" + strings.Join(miner.TxOrderings(), ", ") + ")",
+		Value: miner.DefaultTxOrdering,
+	}
+	MinerNotifyFlag = cli.StringFlag{
+		Name:  "minernotify",
+		Usage: "Comma separated HTTP URLs to push the new work packages of remote miners to",
+	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External clef signer to seal clique blocks with (HTTP/WS URL or IPC path)",
+	}
+	MinerStratumFlag = cli.StringFlag{
+		Name:  "minerstratum",
+		Usage: "Listening address of the stratum mining server (disabled if empty)",
+	}
+	MinerStratumDiffFlag = cli.Float64Flag{
+		Name:  "minerstratumdiff",
+		Usage: "Initial share difficulty of the stratum workers",
+		Value: eth.DefaultConfig.MinerStratumDiff,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -455,6 +526,21 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: 0,
 	}
+	ReservedPeersFlag = cli.IntFlag{
+		Name:  "reservedpeers",
+		Usage: "Number of peer slots reserved for trusted and static nodes",
+		Value: 0,
+	}
+	MaxPeersPerSubnetFlag = cli.IntFlag{
+		Name:  "maxpeerspersubnet",
+		Usage: "Maximum number of inbound peers from the same /24 network (no limit if set to 0)",
+		Value: 0,
+	}
+	PeerEvictionFlag = cli.DurationFlag{
+		Name:  "peereviction",
+		Usage: "Minimum interval between evicting the worst inbound peer for a better one (disabled if set to 0)",
+		Value: 0,
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -838,6 +924,15 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(ReservedPeersFlag.Name) {
+		cfg.ReservedPeers = ctx.GlobalInt(ReservedPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxPeersPerSubnetFlag.Name) {
+		cfg.MaxInboundPerSubnet = ctx.GlobalInt(MaxPeersPerSubnetFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeerEvictionFlag.Name) {
+		cfg.EvictionInterval = ctx.GlobalDuration(PeerEvictionFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -1000,6 +1095,58 @@ func checkExclusive(ctx *cli.Context, args ...interface{}) {
 	}
 }
 
+// setCheckpoint creates the trusted checkpoint and checkpoint oracle configs
+// from the command line flags.
+func setCheckpoint(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(CheckpointFlag.Name) {
+		checkpoint, err := params.ParseTrustedCheckpoint(ctx.GlobalString(CheckpointFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", CheckpointFlag.Name, err)
+		}
+		cfg.Checkpoint = checkpoint
+	}
+	if ctx.GlobalIsSet(CheckpointOracleFlag.Name) {
+		address := ctx.GlobalString(CheckpointOracleFlag.Name)
+		if !common.IsHexAddress(address) {
+			Fatalf("Option %q: invalid oracle address %q", CheckpointOracleFlag.Name, address)
+		}
+		oracle := &params.CheckpointOracleConfig{
+			Address:   common.HexToAddress(address),
+			Threshold: ctx.GlobalUint64(CheckpointThresholdFlag.Name),
+		}
+		for _, signer := range strings.Split(ctx.GlobalString(CheckpointSignersFlag.Name), ",") {
+			if signer = strings.TrimSpace(signer); signer == "" {
+				continue
+			}
+			if !common.IsHexAddress(signer) {
+				Fatalf("Option %q: invalid signer address %q", CheckpointSignersFlag.Name, signer)
+			}
+			oracle.Signers = append(oracle.Signers, common.HexToAddress(signer))
+		}
+		if oracle.Threshold == 0 || uint64(len(oracle.Signers)) < oracle.Threshold {
+			Fatalf("Checkpoint oracle needs at least %d trusted signers, %d given", oracle.Threshold, len(oracle.Signers))
+		}
+		cfg.CheckpointOracle = oracle
+	}
+}
+
+// setULC creates the ultra light client config from the command line flags.
+func setULC(ctx *cli.Context, cfg *eth.Config) {
+	if !ctx.GlobalIsSet(ULCServersFlag.Name) {
+		return
+	}
+	if cfg.SyncMode != downloader.LightSync {
+		Fatalf("Option %q is only supported in light sync mode", ULCServersFlag.Name)
+	}
+	ulc := &eth.ULCConfig{MinTrustedFraction: ctx.GlobalInt(ULCFractionFlag.Name)}
+	for _, url := range strings.Split(ctx.GlobalString(ULCServersFlag.Name), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			ulc.TrustedServers = append(ulc.TrustedServers, url)
+		}
+	}
+	cfg.ULC = ulc
+}
+
 // SetShhConfig applies shh-related command line flags to the config.
 func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 	if ctx.GlobalIsSet(WhisperMaxMessageSizeFlag.Name) {
@@ -1023,6 +1170,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
+	setCheckpoint(ctx, cfg)
 
 	switch {
 	case ctx.GlobalIsSet(SyncModeFlag.Name):
@@ -1032,12 +1180,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	case ctx.GlobalBool(LightModeFlag.Name):
 		cfg.SyncMode = downloader.LightSync
 	}
+	setULC(ctx, cfg)
+
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightStateFlag.Name) {
+		cfg.LightState = ctx.GlobalUint64(LightStateFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1067,6 +1220,38 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
+	if ctx.GlobalIsSet(TargetGasLimitFlag.Name) {
+		cfg.MinerGasFloor = ctx.GlobalUint64(TargetGasLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasFloorFlag.Name) {
+		cfg.MinerGasFloor = ctx.GlobalUint64(MinerGasFloorFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasCeilFlag.Name) {
+		cfg.MinerGasCeil = ctx.GlobalUint64(MinerGasCeilFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerRecommitIntervalFlag.Name) {
+		cfg.MinerRecommit = ctx.GlobalDuration(MinerRecommitIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerTxOrderFlag.Name) {
+		cfg.MinerTxOrder = ctx.GlobalString(MinerTxOrderFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
+		cfg.MinerNotify = nil
+		for _, url := range strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				cfg.MinerNotify = append(cfg.MinerNotify, url)
+			}
+		}
+	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumFlag.Name) {
+		cfg.MinerStratum = ctx.GlobalString(MinerStratumFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumDiffFlag.Name) {
+		cfg.MinerStratumDiff = ctx.GlobalFloat64(MinerStratumDiffFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
@@ -1203,12 +1388,6 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
-// SetupNetwork configures the system for either the main net or some test network.
-func SetupNetwork(ctx *cli.Context) {
-	// TODO(fjl): move target gas limit into config
-	params.TargetGasLimit = ctx.GlobalUint64(TargetGasLimitFlag.Name)
-}
-
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	var (
@@ -1251,6 +1430,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	var engine consensus.Engine
 	if config.Clique != nil {
 		engine = clique.New(config.Clique, chainDb)
+	} else if config.BFT != nil {
+		engine = bft.New(config.BFT, chainDb)
 	} else {
 		engine = ethash.NewFaker()
 		if !ctx.GlobalBool(FakePoWFlag.Name) {
@@ -0,0 +1,727 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bft implements a byzantine fault tolerant proof-of-authority consensus
+// engine with instant finality.
+//
+// Every block is agreed on by the validators in a round based protocol: the
+// proposer of the round broadcasts the block (pre-prepare), the validators accept
+// it (prepare), and once a quorum prepared it, they commit to it by signing it
+// (commit). A block needs the committed seals of a quorum of validators to be
+// valid, so blocks once imported are never reverted.
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	checkpointInterval = 1024 // Number of blocks after which to save the vote snapshot to the database
+	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+)
+
+// BFT protocol constants.
+var (
+	epochLength    = uint64(30000) // Default number of blocks after which to checkpoint and reset the pending votes
+	requestTimeout = uint64(10000) // Default milliseconds to wait for a round to finish before changing it
+
+	extraVanity = types.BFTExtraVanity // Fixed number of extra-data prefix bytes reserved for validator vanity
+
+	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Magic nonce number to vote on adding a new validator
+	nonceDropVote = hexutil.MustDecode("0x0000000000000000") // Magic nonce number to vote on removing a validator.
+
+	// MixDigest is the fixed mix digest of the blocks sealed by the BFT engine.
+	MixDigest = types.BFTMixDigest
+
+	uncleHash  = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+	difficulty = big.NewInt(1)            // Block difficulty, meaningless with instant finality
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidCheckpointBeneficiary is returned if a checkpoint block contains a
+	// non-zero beneficiary.
+	errInvalidCheckpointBeneficiary = errors.New("beneficiary in checkpoint block non-zero")
+
+	// errInvalidVote is returned if a nonce value is something else that the two
+	// allowed constants of 0x00..0 or 0xff..f.
+	errInvalidVote = errors.New("vote nonce not 0x00..0 or 0xff..f")
+
+	// errInvalidCheckpointVote is returned if a checkpoint block has a vote nonce
+	// set to non-zeroes.
+	errInvalidCheckpointVote = errors.New("vote nonce in checkpoint block non-zero")
+
+	// errInvalidExtraData is returned if the extra-data of a block can't be decoded
+	// into the vanity and the validator and seal fields.
+	errInvalidExtraData = errors.New("invalid extra-data")
+
+	// errExtraValidators is returned if non-checkpoint block contain validator
+	// data in their extra-data fields.
+	errExtraValidators = errors.New("non-checkpoint block contains extra validator list")
+
+	// errInvalidCheckpointValidators is returned if a checkpoint block contains a
+	// list of validators not matching the voted ones.
+	errInvalidCheckpointValidators = errors.New("invalid validator list on checkpoint block")
+
+	// errInvalidMixDigest is returned if a block's mix digest isn't the BFT one.
+	errInvalidMixDigest = errors.New("invalid mix digest")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is not 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// ErrInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errInvalidVotingChain is returned if an authorization list is attempted to
+	// be modified via out-of-range or non-contiguous headers.
+	errInvalidVotingChain = errors.New("invalid voting chain")
+
+	// errUnauthorized is returned if a header is proposed by a non-validator.
+	errUnauthorized = errors.New("unauthorized")
+
+	// errInvalidCommittedSeals is returned if a block isn't committed by a quorum
+	// of the validators.
+	errInvalidCommittedSeals = errors.New("invalid committed seals")
+
+	// errNotStarted is returned if a block is attempted to be sealed without the
+	// consensus protocol running.
+	errNotStarted = errors.New("consensus not started")
+)
+
+// SignerFn is a signer callback function to request a hash to be signed by a
+// backing account.
+type SignerFn func(accounts.Account, []byte) ([]byte, error)
+
+// decodeExtra extracts the BFT specific fields from the extra-data of a header.
+func decodeExtra(header *types.Header) (*types.BFTExtra, error) {
+	extra, err := types.ExtractBFTExtra(header)
+	if err != nil {
+		return nil, errInvalidExtraData
+	}
+	return extra, nil
+}
+
+// encodeExtra assembles the extra-data of a header from the vanity and the BFT
+// specific fields.
+func encodeExtra(vanity []byte, extra *types.BFTExtra) ([]byte, error) {
+	return types.EncodeBFTExtra(vanity, extra)
+}
+
+// GenesisExtra assembles the extra-data of a genesis block with the given
+// initial set of validators.
+func GenesisExtra(validators []common.Address) []byte {
+	extra, _ := encodeExtra(nil, &types.BFTExtra{Validators: sortAddresses(validators)})
+	return extra
+}
+
+// sigHash returns the hash which is signed by the proposer of a block. It is the
+// hash of the entire header apart from the seals in the extra-data.
+func sigHash(header *types.Header) common.Hash {
+	return types.BFTFilteredHeader(header, false).Hash()
+}
+
+// proposalHash returns the hash identifying the proposal of a block, signed by
+// the validators committing it. It is the hash of the entire header apart from
+// the committed seals in the extra-data, matching the hash of the committed block.
+func proposalHash(header *types.Header) common.Hash {
+	return types.BFTFilteredHeader(header, true).Hash()
+}
+
+// commitHash returns the hash signed by the validators to commit a proposal.
+func commitHash(proposal common.Hash) []byte {
+	return crypto.Keccak256(proposal[:], []byte{byte(msgCommit)})
+}
+
+// recoverAddress extracts the address of the account that signed a hash.
+func recoverAddress(hash []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// ecrecover extracts the Ethereum account address of the proposer of a block.
+func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
+	hash := proposalHash(header)
+	if address, known := sigcache.Get(hash); known {
+		return address.(common.Address), nil
+	}
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	proposer, err := recoverAddress(sigHash(header).Bytes(), extra.Seal)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigcache.Add(hash, proposer)
+	return proposer, nil
+}
+
+// quorum returns the number of validators needed to agree on a block, more than
+// two thirds of them so that up to a third of faulty ones are tolerated.
+func quorum(validators int) int {
+	return (2*validators + 2) / 3
+}
+
+// BFT is the byzantine fault tolerant proof-of-authority consensus engine.
+type BFT struct {
+	config *params.BFTConfig // Consensus engine configuration parameters
+	db     ethdb.Database    // Database to store and retrieve snapshot checkpoints
+
+	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
+	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
+
+	proposals map[common.Address]bool // Current list of proposals we are pushing
+
+	signer common.Address // Ethereum address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer fields
+
+	core  *core    // Round based consensus protocol, nil if not validating
+	peers *peerSet // Peers speaking the consensus sub-protocol
+}
+
+// New creates a BFT proof-of-authority consensus engine with the initial
+// validators set to the ones in the genesis block.
+func New(config *params.BFTConfig, db ethdb.Database) *BFT {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = epochLength
+	}
+	if conf.RequestTimeout == 0 {
+		conf.RequestTimeout = requestTimeout
+	}
+	// Allocate the snapshot caches and create the engine
+	recents, _ := lru.NewARC(inmemorySnapshots)
+	signatures, _ := lru.NewARC(inmemorySignatures)
+
+	return &BFT{
+		config:     &conf,
+		db:         db,
+		recents:    recents,
+		signatures: signatures,
+		proposals:  make(map[common.Address]bool),
+		peers:      newPeerSet(),
+	}
+}
+
+// Author implements consensus.Engine, returning the Ethereum address recovered
+// from the proposer seal in the header's extra-data section.
+func (b *BFT) Author(header *types.Header) (common.Address, error) {
+	return ecrecover(header, b.signatures)
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+func (b *BFT) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	return b.verifyHeader(chain, header, nil)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (b *BFT) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := b.verifyHeader(chain, header, headers[:i])
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules, including
+// the committed seals of a quorum of validators.
+func (b *BFT) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	if err := b.verifyProposalHeader(chain, header, parents); err != nil {
+		return err
+	}
+	if header.Number.Uint64() == 0 {
+		return nil
+	}
+	return b.verifyCommittedSeals(chain, header, parents)
+}
+
+// verifyProposalHeader checks whether a header conforms to the consensus rules,
+// apart from being committed by the validators. The caller may optionally pass
+// in a batch of parents (ascending order) to avoid looking those up from the
+// database.
+func (b *BFT) verifyProposalHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+		return consensus.ErrFutureBlock
+	}
+	// Checkpoint blocks need to enforce zero beneficiary and vote
+	checkpoint := (number % b.config.Epoch) == 0
+	if checkpoint && header.Coinbase != (common.Address{}) {
+		return errInvalidCheckpointBeneficiary
+	}
+	if !bytes.Equal(header.Nonce[:], nonceAuthVote) && !bytes.Equal(header.Nonce[:], nonceDropVote) {
+		return errInvalidVote
+	}
+	if checkpoint && !bytes.Equal(header.Nonce[:], nonceDropVote) {
+		return errInvalidCheckpointVote
+	}
+	// Ensure that the extra-data contains a validator list on checkpoint, but none otherwise
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return err
+	}
+	if !checkpoint && len(extra.Validators) != 0 {
+		return errExtraValidators
+	}
+	if checkpoint && len(extra.Validators) == 0 {
+		return errInvalidCheckpointValidators
+	}
+	// Ensure that the fixed fields are set correctly
+	if header.MixDigest != MixDigest {
+		return errInvalidMixDigest
+	}
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	if header.Difficulty == nil || header.Difficulty.Cmp(difficulty) != 0 {
+		return errInvalidDifficulty
+	}
+	// If all checks passed, validate any special fields for hard forks
+	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
+		return err
+	}
+	// The genesis block is the always valid dead-end
+	if number == 0 {
+		return nil
+	}
+	// Ensure that the block's timestamp isn't too close to it's parent
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time.Uint64()+b.config.Period > header.Time.Uint64() {
+		return ErrInvalidTimestamp
+	}
+	// Retrieve the snapshot needed to verify this header and cache it
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	// If the block is a checkpoint block, verify the validator list
+	if checkpoint {
+		validators := snap.validators()
+		if len(validators) != len(extra.Validators) {
+			return errInvalidCheckpointValidators
+		}
+		for i, validator := range validators {
+			if extra.Validators[i] != validator {
+				return errInvalidCheckpointValidators
+			}
+		}
+	}
+	// Ensure the block was proposed by a validator
+	proposer, err := ecrecover(header, b.signatures)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Validators[proposer]; !ok {
+		return errUnauthorized
+	}
+	return nil
+}
+
+// verifyCommittedSeals checks whether a header was committed by a quorum of the
+// validators authorized at its parent.
+func (b *BFT) verifyCommittedSeals(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	number := header.Number.Uint64()
+
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return err
+	}
+	hash := commitHash(proposalHash(header))
+
+	committers := make(map[common.Address]struct{})
+	for _, seal := range extra.CommittedSeal {
+		committer, err := recoverAddress(hash, seal)
+		if err != nil {
+			return errInvalidCommittedSeals
+		}
+		if _, ok := snap.Validators[committer]; !ok {
+			return errInvalidCommittedSeals
+		}
+		if _, ok := committers[committer]; ok {
+			return errInvalidCommittedSeals
+		}
+		committers[committer] = struct{}{}
+	}
+	if len(committers) < quorum(len(snap.Validators)) {
+		return errInvalidCommittedSeals
+	}
+	return nil
+}
+
+// snapshot retrieves the authorization snapshot at a given point in time.
+func (b *BFT) snapshot(chain consensus.ChainReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
+	// Search for a snapshot in memory or on disk for checkpoints
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for snap == nil {
+		// If an in-memory snapshot was found, use that
+		if s, ok := b.recents.Get(hash); ok {
+			snap = s.(*Snapshot)
+			break
+		}
+		// If an on-disk checkpoint snapshot can be found, use that
+		if number%checkpointInterval == 0 {
+			if s, err := loadSnapshot(b.config, b.signatures, b.db, hash); err == nil {
+				snap = s
+				break
+			}
+		}
+		// If we're at block zero, make a snapshot
+		if number == 0 {
+			genesis := chain.GetHeaderByNumber(0)
+			extra, err := decodeExtra(genesis)
+			if err != nil {
+				return nil, err
+			}
+			snap = newSnapshot(b.config, b.signatures, 0, genesis.Hash(), extra.Validators)
+			if err := snap.store(b.db); err != nil {
+				return nil, err
+			}
+			break
+		}
+		// No snapshot for this header, gather the header and move backward
+		var header *types.Header
+		if len(parents) > 0 {
+			// If we have explicit parents, pick from there (enforced)
+			header = parents[len(parents)-1]
+			if header.Hash() != hash || header.Number.Uint64() != number {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			parents = parents[:len(parents)-1]
+		} else {
+			// No explicit parents (or no more left), reach out to the database
+			header = chain.GetHeader(hash, number)
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+	// Previous snapshot found, apply any pending headers on top of it
+	for i := 0; i < len(headers)/2; i++ {
+		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+	}
+	snap, err := snap.apply(headers)
+	if err != nil {
+		return nil, err
+	}
+	b.recents.Add(snap.Hash, snap)
+
+	// If we've generated a new checkpoint snapshot, save to disk
+	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
+		if err = snap.store(b.db); err != nil {
+			return nil, err
+		}
+	}
+	return snap, err
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+func (b *BFT) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return nil
+}
+
+// VerifySeal implements consensus.Engine, checking whether the block was proposed
+// by a validator and committed by a quorum of them.
+func (b *BFT) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	proposer, err := ecrecover(header, b.signatures)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Validators[proposer]; !ok {
+		return errUnauthorized
+	}
+	return b.verifyCommittedSeals(chain, header, nil)
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (b *BFT) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	// If the block isn't a checkpoint, cast a random vote (good enough for now)
+	header.Coinbase = common.Address{}
+	header.Nonce = types.BlockNonce{}
+
+	number := header.Number.Uint64()
+	// Assemble the voting snapshot to check which votes make sense
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if number%b.config.Epoch != 0 {
+		b.lock.RLock()
+
+		// Gather all the proposals that make sense voting on
+		addresses := make([]common.Address, 0, len(b.proposals))
+		for address, authorize := range b.proposals {
+			if snap.validVote(address, authorize) {
+				addresses = append(addresses, address)
+			}
+		}
+		// If there's pending proposals, cast a vote on them
+		if len(addresses) > 0 {
+			header.Coinbase = addresses[int(number)%len(addresses)]
+			if b.proposals[header.Coinbase] {
+				copy(header.Nonce[:], nonceAuthVote)
+			} else {
+				copy(header.Nonce[:], nonceDropVote)
+			}
+		}
+		b.lock.RUnlock()
+	}
+	// Set the fixed fields and the validator list on checkpoints
+	header.Difficulty = new(big.Int).Set(difficulty)
+	header.MixDigest = MixDigest
+
+	extra := new(types.BFTExtra)
+	if number%b.config.Epoch == 0 {
+		extra.Validators = snap.validators()
+	}
+	if header.Extra, err = encodeExtra(header.Extra, extra); err != nil {
+		return err
+	}
+	// Ensure the timestamp has the correct delay
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(b.config.Period))
+	if header.Time.Int64() < time.Now().Unix() {
+		header.Time = big.NewInt(time.Now().Unix())
+	}
+	return nil
+}
+
+// Finalize implements consensus.Engine, ensuring no uncles are set, nor block
+// rewards given, and returns the final block.
+func (b *BFT) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// No block rewards in PoA, so the state remains as is and uncles are dropped
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	header.UncleHash = types.CalcUncleHash(nil)
+
+	// Assemble and return the final block for sealing
+	return types.NewBlock(header, txs, nil, receipts), nil
+}
+
+// Authorize injects a private key into the consensus engine to propose and
+// commit new blocks with.
+func (b *BFT) Authorize(signer common.Address, signFn SignerFn) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.signer = signer
+	b.signFn = signFn
+}
+
+// sign signs a hash with the authorized key of the validator.
+func (b *BFT) sign(hash []byte) ([]byte, error) {
+	b.lock.RLock()
+	signer, signFn := b.signer, b.signFn
+	b.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errNotStarted
+	}
+	return signFn(accounts.Account{Address: signer}, hash)
+}
+
+// Start starts participating in the consensus protocol as a validator. Proposed
+// blocks are agreed on only if fully verified by the validate callback, and the
+// blocks committed by the validators are inserted through the commit callback.
+func (b *BFT) Start(chain consensus.ChainReader, validate, commit func(*types.Block) error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.core != nil {
+		return
+	}
+	b.core = newCore(b, chain, validate, commit)
+	b.core.start()
+}
+
+// Stop stops participating in the consensus protocol.
+func (b *BFT) Stop() {
+	b.lock.Lock()
+	core := b.core
+	b.core = nil
+	b.lock.Unlock()
+
+	if core != nil {
+		core.stop()
+	}
+}
+
+// Seal implements consensus.Engine, signing the block as its proposer and handing
+// it to the consensus protocol. The block gets proposed once the validator is the
+// proposer of a round, and is inserted into the chain once committed, so Seal
+// itself never returns a sealed block.
+func (b *BFT) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil, errUnknownBlock
+	}
+	b.lock.RLock()
+	core, signer := b.core, b.signer
+	b.lock.RUnlock()
+
+	if core == nil {
+		return nil, errNotStarted
+	}
+	// Bail out if we're unauthorized to propose a block
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, authorized := snap.Validators[signer]; !authorized {
+		return nil, errUnauthorized
+	}
+	// Sign all the things and hand the proposal over to the consensus protocol
+	sighash, err := b.sign(sigHash(header).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	extra.Seal = sighash
+	if header.Extra, err = encodeExtra(header.Extra, extra); err != nil {
+		return nil, err
+	}
+	core.propose(block.WithSeal(header))
+	return nil, nil
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It always returns 1 as
+// all blocks are final, leaving nothing for the fork choice to weigh.
+func (b *BFT) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(difficulty)
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to allow
+// controlling the validator voting.
+func (b *BFT) APIs(chain consensus.ChainReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "bft",
+		Version:   "1.0",
+		Service:   &API{chain: chain, bft: b},
+		Public:    false,
+	}}
+}
+
+// Protocols returns the p2p sub-protocol the validators exchange the consensus
+// messages over.
+func (b *BFT) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    protocolName,
+		Version: protocolVersion,
+		Length:  protocolLength,
+		Run:     b.handlePeer,
+	}}
+}
+
+// sortAddresses sorts a list of addresses in ascending order.
+func sortAddresses(addrs []common.Address) []common.Address {
+	for i := 0; i < len(addrs); i++ {
+		for j := i + 1; j < len(addrs); j++ {
+			if bytes.Compare(addrs[i][:], addrs[j][:]) > 0 {
+				addrs[i], addrs[j] = addrs[j], addrs[i]
+			}
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,258 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testerValidator is a validator key used by the tests.
+type testerValidator struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+// newTesterValidators creates a number of validators, sorted by address.
+func newTesterValidators(n int) []*testerValidator {
+	validators := make([]*testerValidator, n)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		validators[i] = &testerValidator{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if validators[j].addr.Big().Cmp(validators[i].addr.Big()) < 0 {
+				validators[i], validators[j] = validators[j], validators[i]
+			}
+		}
+	}
+	return validators
+}
+
+func (v *testerValidator) signFn(account accounts.Account, hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, v.key)
+}
+
+// testerChain implements consensus.ChainReader on top of an in-memory chain of
+// headers.
+type testerChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+	lock    sync.RWMutex
+}
+
+func newTesterChain(config *params.BFTConfig, validators []*testerValidator) *testerChain {
+	addrs := make([]common.Address, len(validators))
+	for i, validator := range validators {
+		addrs[i] = validator.addr
+	}
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Time:       big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		GasLimit:   params.GenesisGasLimit,
+		MixDigest:  MixDigest,
+		UncleHash:  types.EmptyUncleHash,
+		TxHash:     types.EmptyRootHash,
+		Extra:      GenesisExtra(addrs),
+	}
+	return &testerChain{
+		config:  &params.ChainConfig{ChainId: big.NewInt(1), BFT: config},
+		headers: []*types.Header{genesis},
+	}
+}
+
+func (c *testerChain) Config() *params.ChainConfig { return c.config }
+
+func (c *testerChain) CurrentHeader() *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testerChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *testerChain) GetHeaderByNumber(number uint64) *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+func (c *testerChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *testerChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if header := c.GetHeader(hash, number); header != nil {
+		return types.NewBlockWithHeader(header)
+	}
+	return nil
+}
+
+// insert verifies a committed block and appends it to the chain, ignoring it if
+// already known.
+func (c *testerChain) insert(engine *BFT, block *types.Block) error {
+	if err := engine.VerifyHeader(c, block.Header(), true); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if number := block.NumberU64(); number < uint64(len(c.headers)) && c.headers[number].Hash() == block.Hash() {
+		return nil
+	}
+	if block.NumberU64() != uint64(len(c.headers)) {
+		return errors.New("non contiguous insert")
+	}
+	c.headers = append(c.headers, block.Header())
+	return nil
+}
+
+// newTesterBlock assembles a block on top of the chain head, proposed by the
+// given validator.
+func newTesterBlock(t *testing.T, engine *BFT, chain *testerChain, proposer *testerValidator) *types.Block {
+	parent := chain.CurrentHeader()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	block := types.NewBlock(header, nil, nil, nil)
+
+	header = block.Header()
+	extra, _ := decodeExtra(header)
+	extra.Seal, _ = crypto.Sign(sigHash(header).Bytes(), proposer.key)
+	header.Extra, _ = encodeExtra(header.Extra, extra)
+
+	return block.WithSeal(header)
+}
+
+// commitTesterBlock adds the committed seals of the given validators to a block.
+func commitTesterBlock(block *types.Block, committers ...*testerValidator) *types.Block {
+	header := block.Header()
+	extra, _ := decodeExtra(header)
+	for _, committer := range committers {
+		seal, _ := crypto.Sign(commitHash(proposalHash(header)), committer.key)
+		extra.CommittedSeal = append(extra.CommittedSeal, seal)
+	}
+	header.Extra, _ = encodeExtra(header.Extra, extra)
+	return block.WithSeal(header)
+}
+
+// Tests that the extra-data fields survive encoding, and that the seals don't
+// influence the hashes they are signing.
+func TestExtraData(t *testing.T) {
+	validators := []common.Address{common.HexToAddress("0x02"), common.HexToAddress("0x01")}
+	header := &types.Header{Number: big.NewInt(0), Extra: GenesisExtra(validators)}
+
+	extra, err := decodeExtra(header)
+	if err != nil {
+		t.Fatalf("failed to decode genesis extra-data: %v", err)
+	}
+	if len(extra.Validators) != 2 || extra.Validators[0] != common.HexToAddress("0x01") {
+		t.Errorf("validators mismatch: have %x, want sorted %x", extra.Validators, validators)
+	}
+	unsealed, proposed := sigHash(header), proposalHash(header)
+
+	extra.Seal = []byte{0x01}
+	header.Extra, _ = encodeExtra(header.Extra, extra)
+	if sigHash(header) != unsealed {
+		t.Errorf("proposer seal changed the signature hash")
+	}
+	if proposalHash(header) == proposed {
+		t.Errorf("proposer seal didn't change the proposal hash")
+	}
+	proposed = proposalHash(header)
+
+	extra.CommittedSeal = [][]byte{{0x02}}
+	header.Extra, _ = encodeExtra(header.Extra, extra)
+	if proposalHash(header) != proposed {
+		t.Errorf("committed seals changed the proposal hash")
+	}
+	if _, err := decodeExtra(&types.Header{Extra: make([]byte, extraVanity-1)}); err != errInvalidExtraData {
+		t.Errorf("short extra-data error mismatch: have %v, want %v", err, errInvalidExtraData)
+	}
+}
+
+// Tests that blocks are only accepted if committed by a quorum of the validators.
+func TestCommittedSeals(t *testing.T) {
+	validators := newTesterValidators(4)
+	outsider := newTesterValidators(1)[0]
+
+	config := &params.BFTConfig{Epoch: 30000}
+	chain := newTesterChain(config, validators)
+	engine := New(config, ethdb.NewMemDatabase())
+
+	block := newTesterBlock(t, engine, chain, validators[1])
+
+	tests := []struct {
+		committers []*testerValidator
+		err        error
+	}{
+		{validators[:2], errInvalidCommittedSeals}, // below quorum
+		{validators[:3], nil},                      // quorum
+		{validators, nil},                          // all validators
+		{[]*testerValidator{validators[0], validators[1], validators[1]}, errInvalidCommittedSeals}, // duplicate seal
+		{[]*testerValidator{validators[0], validators[1], outsider}, errInvalidCommittedSeals},      // non-validator seal
+	}
+	for i, tt := range tests {
+		committed := commitTesterBlock(block, tt.committers...)
+		if err := engine.VerifyHeader(chain, committed.Header(), true); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if committed.Hash() != block.Hash() {
+			t.Errorf("test %d: committed seals changed the block hash", i)
+		}
+	}
+	// Blocks proposed by a non-validator are rejected even if committed
+	committed := commitTesterBlock(newTesterBlock(t, engine, chain, outsider), validators...)
+	if err := engine.VerifyHeader(chain, committed.Header(), true); err != errUnauthorized {
+		t.Errorf("outsider proposal error mismatch: have %v, want %v", err, errUnauthorized)
+	}
+}
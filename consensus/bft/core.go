@@ -0,0 +1,585 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	maxBacklog       = 1024                   // Maximum number of future messages to keep around
+	maxFutureBlocks  = 16                     // Maximum number of blocks ahead to accept messages for
+	maxRoundShift    = 8                      // Maximum doubling of the round timeout
+	headPollInterval = 100 * time.Millisecond // Interval to check the chain for a new head
+)
+
+// roundState is the progress of the agreement within a round.
+type roundState int
+
+const (
+	stateNew         roundState = iota // Waiting for the proposal of the round
+	statePreprepared                   // Proposal accepted, collecting prepares
+	statePrepared                      // Quorum prepared, collecting commits
+	stateCommitted                     // Quorum committed, waiting for the block to be imported
+)
+
+// core runs the round based consensus protocol agreeing on the blocks with the
+// other validators.
+//
+// Each block (sequence) is agreed on in rounds, each having a dedicated proposer
+// rotating through the validators. The proposer broadcasts its block in a
+// pre-prepare message, which the validators accept with a prepare message. Once
+// a quorum of the validators prepared it, they lock on the block and commit to
+// it with their committed seals. Once a quorum committed, every validator
+// assembles the sealed block from the seals it collected and imports it. If a
+// round doesn't finish in time, the validators move on to the next round with a
+// new proposer.
+type core struct {
+	bft      *BFT
+	chain    consensus.ChainReader
+	validate func(*types.Block) error // Callback to fully verify a proposed block
+	commit   func(*types.Block) error // Callback to import a committed block
+
+	sequence uint64        // Number of the block being agreed on
+	parent   *types.Header // Parent of the block being agreed on
+	snap     *Snapshot     // Validators voting on the block
+	round    uint64        // Current round of the agreement
+	state    roundState    // Progress of the agreement in the current round
+	desired  uint64        // Highest round the local validator requested to change to
+
+	proposal *types.Block // Block proposed in the current round
+	locked   *types.Block // Block prepared by a quorum, the only one to accept from now on
+
+	prepares     map[common.Address]*message            // Prepare messages of the current round
+	commits      map[common.Address]*message            // Commit messages of the current round
+	roundChanges map[uint64]map[common.Address]*message // Round change messages of the future rounds
+	backlog      []*message                             // Messages of future sequences and rounds
+
+	roundTimer *time.Timer      // Timer to change rounds if the current one doesn't finish
+	proposeAt  <-chan time.Time // Timer to send the pre-prepare once the block is due
+
+	pending     *types.Block  // Block sealed locally, proposed when being the proposer
+	pendingCh   chan struct{} // Notification channel of a new locally sealed block
+	pendingLock sync.Mutex    // Protects the pending block
+
+	msgCh chan *message
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newCore(bft *BFT, chain consensus.ChainReader, validate, commit func(*types.Block) error) *core {
+	return &core{
+		bft:       bft,
+		chain:     chain,
+		validate:  validate,
+		commit:    commit,
+		pendingCh: make(chan struct{}, 1),
+		msgCh:     make(chan *message, maxQueuedMsgs),
+		quit:      make(chan struct{}),
+	}
+}
+
+// start launches the consensus protocol.
+func (c *core) start() {
+	c.roundTimer = time.NewTimer(0)
+	<-c.roundTimer.C
+
+	c.wg.Add(1)
+	go c.loop()
+}
+
+// stop terminates the consensus protocol.
+func (c *core) stop() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// deliver hands a message received from the network to the consensus protocol.
+func (c *core) deliver(m *message) {
+	select {
+	case c.msgCh <- m:
+	case <-c.quit:
+	}
+}
+
+// propose hands a block sealed locally to the consensus protocol, replacing any
+// previous one.
+func (c *core) propose(block *types.Block) {
+	c.pendingLock.Lock()
+	c.pending = block
+	c.pendingLock.Unlock()
+
+	select {
+	case c.pendingCh <- struct{}{}:
+	default:
+	}
+}
+
+// pendingBlock returns the block sealed locally if it extends the current head.
+func (c *core) pendingBlock() *types.Block {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	if c.pending == nil || c.pending.NumberU64() != c.sequence || c.pending.ParentHash() != c.parent.Hash() {
+		return nil
+	}
+	return c.pending
+}
+
+// loop is the main event loop of the consensus protocol.
+func (c *core) loop() {
+	defer c.wg.Done()
+	defer c.roundTimer.Stop()
+
+	ticker := time.NewTicker(headPollInterval)
+	defer ticker.Stop()
+
+	c.checkHead()
+	for {
+		select {
+		case m := <-c.msgCh:
+			c.handle(m)
+
+		case <-c.pendingCh:
+			if c.parent != nil && c.state == stateNew && c.isProposer() {
+				c.schedulePreprepare()
+			}
+
+		case <-c.proposeAt:
+			c.proposeAt = nil
+			c.sendPreprepare()
+
+		case <-c.roundTimer.C:
+			// Round timed out, request the next one and keep escalating until agreed
+			c.desired++
+			c.resetTimer(c.desired)
+			c.sendRoundChange(c.desired)
+
+		case <-ticker.C:
+			c.checkHead()
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// checkHead starts agreeing on the next block if the chain head advanced.
+func (c *core) checkHead() {
+	head := c.chain.CurrentHeader()
+	if c.parent != nil && head.Number.Uint64() < c.sequence {
+		return
+	}
+	if c.parent != nil && head.Hash() == c.parent.Hash() {
+		return
+	}
+	snap, err := c.bft.snapshot(c.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		log.Warn("Failed to retrieve validator snapshot", "number", head.Number, "hash", head.Hash(), "err", err)
+		return
+	}
+	c.sequence, c.parent, c.snap = head.Number.Uint64()+1, head, snap
+	c.locked, c.desired = nil, 0
+	c.roundChanges = make(map[uint64]map[common.Address]*message)
+
+	c.startRound(0)
+}
+
+// startRound moves the agreement on the current block to a new round.
+func (c *core) startRound(round uint64) {
+	log.Debug("Starting consensus round", "number", c.sequence, "round", round, "proposer", c.snap.proposer(round))
+
+	c.round, c.state = round, stateNew
+	c.proposal, c.proposeAt = nil, nil
+	c.prepares = make(map[common.Address]*message)
+	c.commits = make(map[common.Address]*message)
+	for r := range c.roundChanges {
+		if r <= round {
+			delete(c.roundChanges, r)
+		}
+	}
+	if c.desired < round {
+		c.desired = round
+	}
+	c.resetTimer(round)
+
+	if c.isProposer() {
+		c.schedulePreprepare()
+	}
+	c.processBacklog()
+}
+
+// resetTimer restarts the round change timer with the timeout of the given round,
+// the block period plus an exponentially growing request timeout.
+func (c *core) resetTimer(round uint64) {
+	if round > maxRoundShift {
+		round = maxRoundShift
+	}
+	timeout := time.Duration(c.bft.config.Period)*time.Second + time.Duration(c.bft.config.RequestTimeout<<round)*time.Millisecond
+
+	if !c.roundTimer.Stop() {
+		select {
+		case <-c.roundTimer.C:
+		default:
+		}
+	}
+	c.roundTimer.Reset(timeout)
+}
+
+// isProposer returns whether the local validator proposes the current round.
+func (c *core) isProposer() bool {
+	c.bft.lock.RLock()
+	defer c.bft.lock.RUnlock()
+
+	return c.snap.proposer(c.round) == c.bft.signer
+}
+
+// schedulePreprepare arranges for the proposal to be sent once the block is
+// due, if there's anything to propose.
+func (c *core) schedulePreprepare() {
+	block := c.locked
+	if block == nil {
+		if block = c.pendingBlock(); block == nil {
+			return
+		}
+	}
+	delay := time.Unix(block.Time().Int64(), 0).Sub(time.Now())
+	if delay < 0 {
+		delay = 0
+	}
+	c.proposeAt = time.After(delay)
+}
+
+// sendPreprepare broadcasts the proposal of the current round.
+func (c *core) sendPreprepare() {
+	if c.state != stateNew || !c.isProposer() {
+		return
+	}
+	block := c.locked
+	if block == nil {
+		block = c.pendingBlock()
+	}
+	if block == nil {
+		return
+	}
+	blob, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode proposal", "err", err)
+		return
+	}
+	c.broadcast(&message{
+		Code:     msgPreprepare,
+		Sequence: c.sequence,
+		Round:    c.round,
+		Digest:   proposalHash(block.Header()),
+		Proposal: blob,
+	})
+}
+
+// sendRoundChange broadcasts the request to move on to a new round.
+func (c *core) sendRoundChange(round uint64) {
+	if c.parent == nil {
+		return
+	}
+	log.Debug("Requesting round change", "number", c.sequence, "round", round)
+	c.broadcast(&message{
+		Code:     msgRoundChange,
+		Sequence: c.sequence,
+		Round:    round,
+	})
+}
+
+// broadcast signs a message with the local validator's key, sends it to the
+// peers and processes it locally. Nodes not being validators stay silent.
+func (c *core) broadcast(m *message) {
+	c.bft.lock.RLock()
+	signer := c.bft.signer
+	c.bft.lock.RUnlock()
+
+	if _, ok := c.snap.Validators[signer]; !ok {
+		return
+	}
+	if err := m.sign(c.bft.sign); err != nil {
+		log.Error("Failed to sign consensus message", "err", err)
+		return
+	}
+	m.sender = signer
+	c.handle(m)
+}
+
+// relay propagates a verified message to the peers not knowing it yet.
+func (c *core) relay(m *message) {
+	blob, err := encodeMessage(m)
+	if err != nil {
+		return
+	}
+	c.bft.peers.broadcast(m.hash(), blob)
+}
+
+// handle processes a consensus message, either received or sent locally.
+func (c *core) handle(m *message) {
+	if c.parent == nil {
+		return
+	}
+	// Messages of past blocks are useless, future ones are kept for later
+	switch {
+	case m.Sequence < c.sequence:
+		return
+	case m.Sequence > c.sequence:
+		if m.Sequence <= c.sequence+maxFutureBlocks {
+			c.storeBacklog(m)
+		}
+		return
+	}
+	if _, ok := c.snap.Validators[m.sender]; !ok {
+		log.Debug("Discarding consensus message of non-validator", "sender", m.sender)
+		return
+	}
+	// Round changes are collected for any future round
+	if m.Code == msgRoundChange {
+		if m.Round > c.round {
+			c.relay(m)
+			c.handleRoundChange(m)
+		}
+		return
+	}
+	switch {
+	case m.Round < c.round:
+		return
+	case m.Round > c.round:
+		c.storeBacklog(m)
+		return
+	}
+	c.relay(m)
+
+	switch m.Code {
+	case msgPreprepare:
+		c.handlePreprepare(m)
+	case msgPrepare:
+		c.handlePrepare(m)
+	case msgCommit:
+		c.handleCommit(m)
+	}
+}
+
+// storeBacklog keeps a message of a future sequence or round for processing once
+// the agreement gets there.
+func (c *core) storeBacklog(m *message) {
+	if len(c.backlog) >= maxBacklog {
+		c.backlog = c.backlog[1:]
+	}
+	c.backlog = append(c.backlog, m)
+}
+
+// processBacklog processes the backlogged messages which became current, and
+// drops the obsolete ones.
+func (c *core) processBacklog() {
+	var current, future []*message
+	for _, m := range c.backlog {
+		switch {
+		case m.Sequence < c.sequence || (m.Sequence == c.sequence && m.Round < c.round):
+		case m.Sequence == c.sequence && m.Round == c.round:
+			current = append(current, m)
+		default:
+			future = append(future, m)
+		}
+	}
+	c.backlog = future
+	for _, m := range current {
+		c.handle(m)
+	}
+}
+
+// handlePreprepare verifies the proposal of the round, accepting it with a
+// prepare message if valid.
+func (c *core) handlePreprepare(m *message) {
+	if c.state != stateNew || m.sender != c.snap.proposer(c.round) {
+		return
+	}
+	block, err := m.block()
+	if err != nil {
+		log.Debug("Discarding invalid proposal", "sender", m.sender, "err", err)
+		return
+	}
+	// Once locked, only the locked block may be agreed on
+	if c.locked != nil && c.locked.Hash() != block.Hash() {
+		log.Debug("Discarding proposal conflicting with locked block", "sender", m.sender)
+		return
+	}
+	if block.ParentHash() != c.parent.Hash() {
+		return
+	}
+	if err := c.bft.verifyProposalHeader(c.chain, block.Header(), []*types.Header{c.parent}); err != nil {
+		log.Debug("Discarding invalid proposal", "sender", m.sender, "err", err)
+		return
+	}
+	if types.DeriveSha(block.Transactions()) != block.TxHash() {
+		log.Debug("Discarding proposal with invalid transactions", "sender", m.sender)
+		return
+	}
+	// Execute the proposal before agreeing on it, unless already done when locking
+	if c.locked == nil {
+		if err := c.validate(block); err != nil {
+			log.Warn("Discarding invalid proposal", "number", block.Number(), "hash", block.Hash(), "sender", m.sender, "err", err)
+			return
+		}
+	}
+	c.proposal, c.state = block, statePreprepared
+
+	c.broadcast(&message{
+		Code:     msgPrepare,
+		Sequence: c.sequence,
+		Round:    c.round,
+		Digest:   m.Digest,
+	})
+	// Messages received before the proposal may complete the round already
+	c.checkPrepared()
+	c.checkCommitted()
+}
+
+// handlePrepare collects the prepares of the round, committing to the proposal
+// once a quorum prepared it.
+func (c *core) handlePrepare(m *message) {
+	c.prepares[m.sender] = m
+	c.checkPrepared()
+}
+
+// checkPrepared locks and commits to the proposal if a quorum prepared it.
+func (c *core) checkPrepared() {
+	if c.state != statePreprepared || c.count(c.prepares) < quorum(len(c.snap.Validators)) {
+		return
+	}
+	c.locked, c.state = c.proposal, statePrepared
+
+	digest := proposalHash(c.proposal.Header())
+	seal, err := c.bft.sign(commitHash(digest))
+	if err != nil {
+		log.Error("Failed to sign committed seal", "err", err)
+		return
+	}
+	c.broadcast(&message{
+		Code:          msgCommit,
+		Sequence:      c.sequence,
+		Round:         c.round,
+		Digest:        digest,
+		CommittedSeal: seal,
+	})
+}
+
+// handleCommit collects the commits of the round, finishing the agreement once
+// a quorum committed to the proposal.
+func (c *core) handleCommit(m *message) {
+	committer, err := recoverAddress(commitHash(m.Digest), m.CommittedSeal)
+	if err != nil || committer != m.sender {
+		log.Debug("Discarding commit with invalid seal", "sender", m.sender)
+		return
+	}
+	c.commits[m.sender] = m
+	c.checkCommitted()
+}
+
+// checkCommitted finishes the agreement if a quorum committed to the proposal,
+// importing the block sealed with the collected committed seals.
+func (c *core) checkCommitted() {
+	if c.state == stateNew || c.state == stateCommitted || c.count(c.commits) < quorum(len(c.snap.Validators)) {
+		return
+	}
+	c.locked, c.state = c.proposal, stateCommitted
+
+	// Assemble the committed seals in a deterministic order and import the block
+	digest := proposalHash(c.proposal.Header())
+
+	committers := make([]common.Address, 0, len(c.commits))
+	for committer, m := range c.commits {
+		if m.Digest == digest {
+			committers = append(committers, committer)
+		}
+	}
+	sort.Slice(committers, func(i, j int) bool {
+		return bytes.Compare(committers[i][:], committers[j][:]) < 0
+	})
+	header := c.proposal.Header()
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return
+	}
+	for _, committer := range committers {
+		extra.CommittedSeal = append(extra.CommittedSeal, c.commits[committer].CommittedSeal)
+	}
+	if header.Extra, err = encodeExtra(header.Extra, extra); err != nil {
+		return
+	}
+	block := c.proposal.WithSeal(header)
+	if err := c.commit(block); err != nil {
+		log.Error("Failed to import committed block", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	log.Info("Committed new block", "number", block.Number(), "hash", block.Hash(), "round", c.round, "seals", len(committers))
+	c.checkHead()
+}
+
+// count returns the number of messages agreeing on the proposal of the round.
+func (c *core) count(msgs map[common.Address]*message) int {
+	if c.proposal == nil {
+		return 0
+	}
+	digest := proposalHash(c.proposal.Header())
+
+	count := 0
+	for _, m := range msgs {
+		if m.Digest == digest {
+			count++
+		}
+	}
+	return count
+}
+
+// handleRoundChange collects the requests to move on to future rounds. Once more
+// than the faulty validators requested a round, the local validator joins them,
+// and once a quorum did, the round is started.
+func (c *core) handleRoundChange(m *message) {
+	changes := c.roundChanges[m.Round]
+	if changes == nil {
+		changes = make(map[common.Address]*message)
+		c.roundChanges[m.Round] = changes
+	}
+	changes[m.sender] = m
+
+	validators := len(c.snap.Validators)
+	switch {
+	case len(changes) >= quorum(validators):
+		c.startRound(m.Round)
+
+	case len(changes) > (validators-1)/3:
+		c.bft.lock.RLock()
+		signer := c.bft.signer
+		c.bft.lock.RUnlock()
+
+		if _, ok := changes[signer]; !ok {
+			c.sendRoundChange(m.Round)
+		}
+	}
+}
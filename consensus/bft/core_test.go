@@ -0,0 +1,165 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a network of validators agrees on blocks, both with all of them
+// online and with one faulty validator forcing round changes, either by being
+// offline or by proposing blocks failing validation.
+func TestConsensus(t *testing.T) {
+	t.Run("online", func(t *testing.T) { testConsensus(t, -1, false) })
+	t.Run("offline", func(t *testing.T) { testConsensus(t, 1, false) })  // proposer of the first block
+	t.Run("byzantine", func(t *testing.T) { testConsensus(t, 1, true) }) // proposer of the first block
+}
+
+func testConsensus(t *testing.T, faulty int, byzantine bool) {
+	validators := newTesterValidators(4)
+
+	config := &params.BFTConfig{Epoch: 30000, RequestTimeout: 200}
+
+	// Create the engines, each with its own chain, and connect them over the
+	// consensus protocol
+	var (
+		engines = make([]*BFT, len(validators))
+		chains  = make([]*testerChain, len(validators))
+	)
+	for i, validator := range validators {
+		engines[i] = New(config, ethdb.NewMemDatabase())
+		engines[i].Authorize(validator.addr, validator.signFn)
+		chains[i] = newTesterChain(config, validators)
+	}
+	for i := 0; i < len(engines); i++ {
+		for j := i + 1; j < len(engines); j++ {
+			rw1, rw2 := p2p.MsgPipe()
+			defer rw1.Close()
+
+			go engines[i].handlePeer(p2p.NewPeer(discover.NodeID{byte(j + 1)}, "", nil), rw1)
+			go engines[j].handlePeer(p2p.NewPeer(discover.NodeID{byte(i + 1)}, "", nil), rw2)
+		}
+	}
+	// A byzantine validator proposes blocks with a state root the others reject
+	// when executing them
+	badRoot := common.HexToHash("0xbad")
+	validate := func(block *types.Block) error {
+		if block.Root() == badRoot {
+			return errors.New("invalid state root")
+		}
+		return nil
+	}
+	// Start the online validators, sealing a new block whenever the head changes
+	var (
+		quit = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	var honest []int
+	for i := range engines {
+		if i == faulty && !byzantine {
+			continue
+		}
+		if i != faulty {
+			honest = append(honest, i)
+		}
+		engine, chain := engines[i], chains[i]
+		engine.Start(chain, validate, func(block *types.Block) error { return chain.insert(engine, block) })
+		defer engine.Stop()
+
+		root := common.Hash{}
+		if i == faulty {
+			root = badRoot
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var sealed common.Hash
+			for {
+				select {
+				case <-quit:
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+				parent := chain.CurrentHeader()
+				if parent.Hash() == sealed {
+					continue
+				}
+				header := &types.Header{
+					ParentHash: parent.Hash(),
+					Number:     new(big.Int).Add(parent.Number, common.Big1),
+					GasLimit:   parent.GasLimit,
+					Root:       root,
+				}
+				if err := engine.Prepare(chain, header); err != nil {
+					t.Errorf("failed to prepare header: %v", err)
+					return
+				}
+				if _, err := engine.Seal(chain, types.NewBlock(header, nil, nil, nil), nil); err != nil {
+					t.Errorf("failed to seal block: %v", err)
+					return
+				}
+				sealed = parent.Hash()
+			}
+		}()
+	}
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// Wait for a few blocks to be committed, every honest validator importing them
+	deadline := time.Now().Add(10 * time.Second)
+	for _, i := range honest {
+		for chains[i].CurrentHeader().Number.Uint64() < 3 {
+			if time.Now().After(deadline) {
+				t.Fatalf("validator %d: consensus stalled at block %d", i, chains[i].CurrentHeader().Number)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	for number := uint64(1); number <= 3; number++ {
+		header := chains[honest[0]].GetHeaderByNumber(number)
+		for _, i := range honest[1:] {
+			if hash := chains[i].GetHeaderByNumber(number).Hash(); hash != header.Hash() {
+				t.Errorf("validator %d: block %d mismatch: have %x, want %x", i, number, hash, header.Hash())
+			}
+		}
+		if header.Root == badRoot {
+			t.Errorf("block %d: invalid proposal committed", number)
+		}
+	}
+	// The first block can't have been proposed by the faulty validator
+	proposer, err := engines[0].Author(chains[honest[0]].GetHeaderByNumber(1))
+	if err != nil {
+		t.Fatalf("failed to recover proposer: %v", err)
+	}
+	if faulty >= 0 && proposer == validators[faulty].addr {
+		t.Errorf("block proposed by faulty validator")
+	}
+}
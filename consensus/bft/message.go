@@ -0,0 +1,141 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// msgCode is the type of a consensus message exchanged between the validators.
+type msgCode uint64
+
+const (
+	msgPreprepare  msgCode = iota // Block proposed by the proposer of a round
+	msgPrepare                    // Acceptance of the proposal by a validator
+	msgCommit                     // Commitment to the proposal by a validator, carrying its committed seal
+	msgRoundChange                // Request to move on to a new round
+)
+
+func (c msgCode) String() string {
+	switch c {
+	case msgPreprepare:
+		return "preprepare"
+	case msgPrepare:
+		return "prepare"
+	case msgCommit:
+		return "commit"
+	case msgRoundChange:
+		return "roundchange"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint64(c))
+	}
+}
+
+var (
+	// errInvalidMessage is returned if a consensus message is malformed.
+	errInvalidMessage = errors.New("invalid consensus message")
+
+	// errInvalidSignature is returned if the signature of a consensus message
+	// can't be recovered.
+	errInvalidSignature = errors.New("invalid message signature")
+)
+
+// message is a consensus message signed by a validator.
+type message struct {
+	Code          msgCode     // Type of the message
+	Sequence      uint64      // Number of the block agreed on
+	Round         uint64      // Round of the agreement on the block
+	Digest        common.Hash // Proposal hash of the block agreed on (zero on round changes)
+	Proposal      []byte      // RLP encoded block of pre-prepare messages
+	CommittedSeal []byte      // Signature of the proposal on commit messages
+	Signature     []byte      // Signature of the validator over all the fields above
+
+	sender common.Address // Validator that sent the message, recovered from the signature
+}
+
+// sigHash returns the hash of the message signed by its sender.
+func (m *message) sigHash() []byte {
+	blob, _ := rlp.EncodeToBytes([]interface{}{
+		uint64(m.Code), m.Sequence, m.Round, m.Digest, m.Proposal, m.CommittedSeal,
+	})
+	return crypto.Keccak256(blob)
+}
+
+// hash returns the hash identifying the message, used to avoid gossiping the
+// same message multiple times.
+func (m *message) hash() common.Hash {
+	return crypto.Keccak256Hash(m.sigHash(), m.Signature)
+}
+
+// sign signs the message with the key of the local validator.
+func (m *message) sign(signFn func([]byte) ([]byte, error)) error {
+	sig, err := signFn(m.sigHash())
+	if err != nil {
+		return err
+	}
+	m.Signature = sig
+	return nil
+}
+
+// recoverSender recovers the validator that sent the message from its signature.
+func (m *message) recoverSender() error {
+	sender, err := recoverAddress(m.sigHash(), m.Signature)
+	if err != nil {
+		return errInvalidSignature
+	}
+	m.sender = sender
+	return nil
+}
+
+// block decodes the block carried by a pre-prepare message.
+func (m *message) block() (*types.Block, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(m.Proposal, block); err != nil {
+		return nil, errInvalidMessage
+	}
+	if block.NumberU64() != m.Sequence || proposalHash(block.Header()) != m.Digest {
+		return nil, errInvalidMessage
+	}
+	return block, nil
+}
+
+// encodeMessage serializes a signed consensus message for the network.
+func encodeMessage(m *message) ([]byte, error) {
+	return rlp.EncodeToBytes(m)
+}
+
+// decodeMessage deserializes a consensus message from the network and recovers
+// its sender.
+func decodeMessage(blob []byte) (*message, error) {
+	m := new(message)
+	if err := rlp.DecodeBytes(blob, m); err != nil {
+		return nil, errInvalidMessage
+	}
+	if m.Code > msgRoundChange {
+		return nil, errInvalidMessage
+	}
+	if err := m.recoverSender(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
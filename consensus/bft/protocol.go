@@ -0,0 +1,178 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"gopkg.in/fatih/set.v0"
+)
+
+// Constants of the consensus sub-protocol.
+const (
+	protocolName       = "bft"
+	protocolVersion    = 1
+	protocolLength     = 1                // Number of message codes used by the protocol
+	protocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+
+	consensusMsg = 0x00 // Message code carrying a signed consensus message
+
+	maxKnownMsgs  = 4096 // Maximum message hashes to keep in the known list (prevent DOS)
+	maxQueuedMsgs = 256  // Maximum number of messages to queue up for a peer before dropping
+)
+
+// peer is a remote node speaking the consensus sub-protocol.
+type peer struct {
+	id    string
+	rw    p2p.MsgReadWriter
+	known *set.Set      // Hashes of the messages known to be known by the peer
+	queue chan []byte   // Queue of messages to send to the peer
+	term  chan struct{} // Termination channel to stop the sender
+}
+
+// markMessage marks a message as known by the peer, ensuring that it will never
+// be propagated to this particular peer.
+func (p *peer) markMessage(hash common.Hash) {
+	for p.known.Size() >= maxKnownMsgs {
+		p.known.Pop()
+	}
+	p.known.Add(hash)
+}
+
+// send queues a message for propagation to the peer, dropping it if the peer
+// can't keep up.
+func (p *peer) send(hash common.Hash, blob []byte) {
+	p.markMessage(hash)
+	select {
+	case p.queue <- blob:
+	default:
+		log.Debug("Dropping consensus message to slow peer", "peer", p.id)
+	}
+}
+
+// broadcastLoop sends the queued messages to the peer.
+func (p *peer) broadcastLoop() {
+	for {
+		select {
+		case blob := <-p.queue:
+			if err := p2p.Send(p.rw, consensusMsg, blob); err != nil {
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// peerSet is the set of peers speaking the consensus sub-protocol.
+type peerSet struct {
+	peers map[string]*peer
+	lock  sync.RWMutex
+}
+
+func newPeerSet() *peerSet {
+	return &peerSet{peers: make(map[string]*peer)}
+}
+
+// register injects a new peer into the set.
+func (ps *peerSet) register(p *peer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[p.id]; ok {
+		return fmt.Errorf("peer %s already registered", p.id)
+	}
+	ps.peers[p.id] = p
+	return nil
+}
+
+// unregister removes a peer from the set.
+func (ps *peerSet) unregister(id string) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	delete(ps.peers, id)
+}
+
+// broadcast propagates a message to all the peers not yet knowing about it.
+func (ps *peerSet) broadcast(hash common.Hash, blob []byte) {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	for _, p := range ps.peers {
+		if !p.known.Has(hash) {
+			p.send(hash, blob)
+		}
+	}
+}
+
+// handlePeer is the callback invoked by the p2p server to run the consensus
+// sub-protocol with a remote peer. The received messages are handed to the
+// consensus protocol if running, which relays them to the other peers once
+// verified to originate from a validator.
+func (b *BFT) handlePeer(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	bp := &peer{
+		id:    fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+		rw:    rw,
+		known: set.New(),
+		queue: make(chan []byte, maxQueuedMsgs),
+		term:  make(chan struct{}),
+	}
+	if err := b.peers.register(bp); err != nil {
+		return err
+	}
+	defer b.peers.unregister(bp.id)
+
+	go bp.broadcastLoop()
+	defer close(bp.term)
+
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > protocolMaxMsgSize {
+			msg.Discard()
+			return fmt.Errorf("message too large: %v > %v", msg.Size, protocolMaxMsgSize)
+		}
+		if msg.Code != consensusMsg {
+			msg.Discard()
+			return fmt.Errorf("invalid message code: %v", msg.Code)
+		}
+		var blob []byte
+		if err := msg.Decode(&blob); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+		m, err := decodeMessage(blob)
+		if err != nil {
+			return err
+		}
+		bp.markMessage(m.hash())
+
+		b.lock.RLock()
+		core := b.core
+		b.lock.RUnlock()
+
+		if core != nil {
+			core.deliver(m)
+		}
+	}
+}
@@ -88,6 +88,22 @@ func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
 	return snap.signers(), nil
 }
 
+// Status retrieves the signing activity of the currently authorized signers over
+// the given number of recent blocks (64 if none requested, 4096 at most): the
+// blocks signed in and out of turn, the in-turn slots missed, the last block
+// signed and whether the signer signed any block at all.
+func (api *API) Status(window *uint64) (*Status, error) {
+	if window == nil {
+		window = new(uint64)
+		*window = defaultStatusWindow
+	}
+	header := api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.clique.status(api.chain, header, *window)
+}
+
 // Proposals returns the current proposals the node tries to uphold and vote on.
 func (api *API) Proposals() map[common.Address]bool {
 	api.clique.lock.RLock()
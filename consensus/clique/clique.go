@@ -21,6 +21,7 @@ package clique
 import (
 	"bytes"
 	"errors"
+	"io"
 	"math/big"
 	"math/rand"
 	"sync"
@@ -133,9 +134,11 @@ var (
 	errWaitTransactions = errors.New("waiting for transactions")
 )
 
-// SignerFn is a signer callback function to request a hash to be signed by a
-// backing account.
-type SignerFn func(accounts.Account, []byte) ([]byte, error)
+// SignerFn is a signer callback function to request a header to be signed by a
+// backing account. The data to sign is the header encoded by CliqueRLP, passed
+// along with its mime type so external signers know how to interpret it. The
+// signature is expected with the V value as 0 or 1.
+type SignerFn func(account accounts.Account, mimeType string, data []byte) ([]byte, error)
 
 // sigHash returns the hash which is used as input for the proof-of-authority
 // signing. It is the hash of the entire header apart from the 65 byte signature
@@ -146,8 +149,24 @@ type SignerFn func(accounts.Account, []byte) ([]byte, error)
 // or not), which could be abused to produce different hashes for the same header.
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
+	encodeSigHeader(hasher, header)
+	hasher.Sum(hash[:0])
+	return hash
+}
 
-	rlp.Encode(hasher, []interface{}{
+// CliqueRLP returns the RLP encoding of the header which is signed for the
+// proof-of-authority sealing, hashing to sigHash. It is the entire header apart
+// from the 65 byte signature contained at the end of the extra data, and panics
+// the same way if the extra data is too short.
+func CliqueRLP(header *types.Header) []byte {
+	b := new(bytes.Buffer)
+	encodeSigHeader(b, header)
+	return b.Bytes()
+}
+
+// encodeSigHeader writes the RLP encoding of the header without its signature.
+func encodeSigHeader(w io.Writer, header *types.Header) {
+	rlp.Encode(w, []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -164,8 +183,6 @@ func sigHash(header *types.Header) (hash common.Hash) {
 		header.MixDigest,
 		header.Nonce,
 	})
-	hasher.Sum(hash[:0])
-	return hash
 }
 
 // ecrecover extracts the Ethereum account address from a signed header.
@@ -203,6 +220,7 @@ type Clique struct {
 	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
+	liveness  *liveness               // Activity of the signers reported via metrics
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
@@ -227,6 +245,7 @@ func New(config *params.CliqueConfig, db ethdb.Database) *Clique {
 		recents:    recents,
 		signatures: signatures,
 		proposals:  make(map[common.Address]bool),
+		liveness:   newLiveness(),
 	}
 }
 
@@ -303,6 +322,9 @@ func (c *Clique) verifyHeader(chain consensus.ChainReader, header *types.Header,
 	if checkpoint && signersBytes%common.AddressLength != 0 {
 		return errInvalidCheckpointSigners
 	}
+	if checkpoint && c.config.Governance != nil && signersBytes == 0 {
+		return errInvalidCheckpointSigners
+	}
 	// Ensure that the mix digest is zero as we don't have fork protection currently
 	if header.MixDigest != (common.Hash{}) {
 		return errInvalidMixDigest
@@ -353,8 +375,9 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainReader, header *type
 	if err != nil {
 		return err
 	}
-	// If the block is a checkpoint block, verify the signer list
-	if number%c.config.Epoch == 0 {
+	// If the block is a checkpoint block, verify the signer list. With a governance
+	// contract the list is verified against its storage when finalizing the block.
+	if number%c.config.Epoch == 0 && c.config.Governance == nil {
 		signers := make([]byte, len(snap.Signers)*common.AddressLength)
 		for i, signer := range snap.signers() {
 			copy(signers[i*common.AddressLength:], signer[:])
@@ -503,10 +526,29 @@ func (c *Clique) verifySeal(chain consensus.ChainReader, header *types.Header, p
 	return nil
 }
 
+// InsertedBlock updates the liveness metrics of the signers with a block that
+// was inserted into the canonical chain.
+func (c *Clique) InsertedBlock(chain consensus.ChainReader, header *types.Header) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return
+	}
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return
+	}
+	signer, err := ecrecover(header, c.signatures)
+	if err != nil {
+		return
+	}
+	c.liveness.update(snap, number, signer, snap.inturn(number, signer))
+}
+
 // Prepare implements consensus.Engine, preparing all the consensus fields of the
 // header for running the transactions on top.
 func (c *Clique) Prepare(chain consensus.ChainReader, header *types.Header) error {
-	// If the block isn't a checkpoint, cast a random vote (good enough for now)
+	// If the block isn't a checkpoint, cast a random vote (good enough for now),
+	// unless the signers are governed by a contract instead of votes
 	header.Coinbase = common.Address{}
 	header.Nonce = types.BlockNonce{}
 
@@ -516,7 +558,7 @@ func (c *Clique) Prepare(chain consensus.ChainReader, header *types.Header) erro
 	if err != nil {
 		return err
 	}
-	if number%c.config.Epoch != 0 {
+	if number%c.config.Epoch != 0 && c.config.Governance == nil {
 		c.lock.RLock()
 
 		// Gather all the proposals that make sense voting on
@@ -546,7 +588,7 @@ func (c *Clique) Prepare(chain consensus.ChainReader, header *types.Header) erro
 	}
 	header.Extra = header.Extra[:extraVanity]
 
-	if number%c.config.Epoch == 0 {
+	if number%c.config.Epoch == 0 && c.config.Governance == nil {
 		for _, signer := range snap.signers() {
 			header.Extra = append(header.Extra, signer[:]...)
 		}
@@ -571,6 +613,12 @@ func (c *Clique) Prepare(chain consensus.ChainReader, header *types.Header) erro
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given, and returns the final block.
 func (c *Clique) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// Checkpoints carry the signer set of the governance contract, if configured
+	if c.config.Governance != nil && header.Number.Uint64()%c.config.Epoch == 0 {
+		if err := c.finalizeSigners(header, state); err != nil {
+			return nil, err
+		}
+	}
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
@@ -644,7 +692,7 @@ func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, stop <-ch
 	case <-time.After(delay):
 	}
 	// Sign all the things!
-	sighash, err := signFn(accounts.Account{Address: signer}, sigHash(header).Bytes())
+	sighash, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, CliqueRLP(header))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,107 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxGovernanceSigners is the maximum number of signers read from the governance
+// contract, protecting against a corrupted signer list length.
+const maxGovernanceSigners = 1024
+
+var (
+	// governanceSignersSlot is the storage slot of the signer list in the governance
+	// contract, laid out as a Solidity `address[] signers` state variable declared
+	// first: the length in the slot itself, the items from keccak256(slot) onwards.
+	governanceSignersSlot = common.Hash{}
+
+	// errNoGovernanceSigners is returned if the governance contract doesn't hold a
+	// usable list of signers on a checkpoint block.
+	errNoGovernanceSigners = errors.New("governance contract holds no valid signer list")
+)
+
+// governanceSigners reads the signer list from the storage of the governance
+// contract, returning the unique non-zero signers in ascending order.
+func governanceSigners(statedb *state.StateDB, contract common.Address) ([]common.Address, error) {
+	length := statedb.GetState(contract, governanceSignersSlot).Big()
+	if length.Sign() == 0 || length.Cmp(big.NewInt(maxGovernanceSigners)) > 0 {
+		return nil, errNoGovernanceSigners
+	}
+	base := crypto.Keccak256Hash(governanceSignersSlot[:]).Big()
+
+	unique := make(map[common.Address]struct{})
+	for i := int64(0); i < length.Int64(); i++ {
+		slot := common.BigToHash(new(big.Int).Add(base, big.NewInt(i)))
+		if signer := common.BytesToAddress(statedb.GetState(contract, slot).Bytes()); signer != (common.Address{}) {
+			unique[signer] = struct{}{}
+		}
+	}
+	if len(unique) == 0 {
+		return nil, errNoGovernanceSigners
+	}
+	signers := make([]common.Address, 0, len(unique))
+	for signer := range unique {
+		signers = append(signers, signer)
+	}
+	for i := 0; i < len(signers); i++ {
+		for j := i + 1; j < len(signers); j++ {
+			if bytes.Compare(signers[i][:], signers[j][:]) > 0 {
+				signers[i], signers[j] = signers[j], signers[i]
+			}
+		}
+	}
+	return signers, nil
+}
+
+// finalizeSigners embeds the signer list of the governance contract into a
+// checkpoint header being sealed, or verifies the embedded one of an imported
+// checkpoint header against the contract.
+func (c *Clique) finalizeSigners(header *types.Header, statedb *state.StateDB) error {
+	signers, err := governanceSigners(statedb, *c.config.Governance)
+	if err != nil {
+		return err
+	}
+	list := make([]byte, 0, len(signers)*common.AddressLength)
+	for _, signer := range signers {
+		list = append(list, signer[:]...)
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	extraSuffix := len(header.Extra) - extraSeal
+
+	// Headers being sealed don't have the signer list yet, fill it in
+	if extraSuffix == extraVanity {
+		extra := make([]byte, 0, extraVanity+len(list)+extraSeal)
+		extra = append(extra, header.Extra[:extraVanity]...)
+		extra = append(extra, list...)
+		header.Extra = append(extra, header.Extra[extraSuffix:]...)
+		return nil
+	}
+	if !bytes.Equal(header.Extra[extraVanity:extraSuffix], list) {
+		return errInvalidCheckpointSigners
+	}
+	return nil
+}
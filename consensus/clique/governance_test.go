@@ -0,0 +1,154 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// sortAddresses sorts a list of addresses in ascending order, matching the order
+// of the signer lists in the checkpoint headers.
+func sortAddresses(addrs []common.Address) []common.Address {
+	for i := 0; i < len(addrs); i++ {
+		for j := i + 1; j < len(addrs); j++ {
+			if bytes.Compare(addrs[i][:], addrs[j][:]) > 0 {
+				addrs[i], addrs[j] = addrs[j], addrs[i]
+			}
+		}
+	}
+	return addrs
+}
+
+// Tests that with a governance contract configured the votes in the headers are
+// ignored, and the signer set is switched to the one of the checkpoints.
+func TestGovernanceSnapshot(t *testing.T) {
+	accounts := newTesterAccountPool()
+	governance := common.HexToAddress("0x0000000000000000000000000000000000001000")
+	config := &params.CliqueConfig{Epoch: 3, Governance: &governance}
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genesis.ExtraData[extraVanity:], accounts.address("A").Bytes())
+
+	db := ethdb.NewMemDatabase()
+	genesis.Commit(db)
+
+	// A votes in C (passing instantly with header voting), then checkpoints B and C
+	checkpoint := sortAddresses([]common.Address{accounts.address("B"), accounts.address("C")})
+	signers := []string{"A", "A", "A", "B", "C"}
+
+	headers := make([]*types.Header, len(signers))
+	for i, signer := range signers {
+		headers[i] = &types.Header{
+			Number: big.NewInt(int64(i) + 1),
+			Time:   big.NewInt(int64(i) * int64(blockPeriod)),
+			Extra:  make([]byte, extraVanity+extraSeal),
+		}
+		if i > 0 {
+			headers[i].ParentHash = headers[i-1].Hash()
+		}
+		if i == 0 {
+			headers[i].Coinbase = accounts.address("C")
+			copy(headers[i].Nonce[:], nonceAuthVote)
+		}
+		if (i+1)%int(config.Epoch) == 0 {
+			extra := make([]byte, extraVanity)
+			for _, addr := range checkpoint {
+				extra = append(extra, addr[:]...)
+			}
+			headers[i].Extra = append(extra, make([]byte, extraSeal)...)
+		}
+		accounts.sign(headers[i], signer)
+	}
+	engine := New(config, db)
+
+	// The vote must not have passed before the checkpoint
+	snap, err := engine.snapshot(&testerChainReader{db: db}, 2, headers[1].Hash(), headers[:2])
+	if err != nil {
+		t.Fatalf("failed to create snapshot before checkpoint: %v", err)
+	}
+	if len(snap.Signers) != 1 {
+		t.Errorf("signers before checkpoint mismatch: have %x, want [%x]", snap.signers(), accounts.address("A"))
+	}
+	// The checkpoint must have replaced the signer set
+	head := headers[len(headers)-1]
+	if snap, err = engine.snapshot(&testerChainReader{db: db}, head.Number.Uint64(), head.Hash(), headers); err != nil {
+		t.Fatalf("failed to create snapshot after checkpoint: %v", err)
+	}
+	result := snap.signers()
+	if len(result) != len(checkpoint) || result[0] != checkpoint[0] || result[1] != checkpoint[1] {
+		t.Errorf("signers after checkpoint mismatch: have %x, want %x", result, checkpoint)
+	}
+}
+
+// Tests that checkpoint headers are filled with the signer list of the governance
+// contract when sealing, and verified against it when importing.
+func TestGovernanceFinalize(t *testing.T) {
+	governance := common.HexToAddress("0x0000000000000000000000000000000000001000")
+	engine := New(&params.CliqueConfig{Epoch: 30000, Governance: &governance}, ethdb.NewMemDatabase())
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	checkpoint := func(extra []byte) *types.Header {
+		return &types.Header{
+			Number: big.NewInt(30000),
+			Extra:  append(append(make([]byte, extraVanity), extra...), make([]byte, extraSeal)...),
+		}
+	}
+	// An empty governance contract can't produce checkpoints
+	if err := engine.finalizeSigners(checkpoint(nil), statedb); err != errNoGovernanceSigners {
+		t.Fatalf("empty contract error mismatch: have %v, want %v", err, errNoGovernanceSigners)
+	}
+	// Store an unsorted signer list with a duplicate into the contract
+	signers := []common.Address{
+		common.HexToAddress("0x0000000000000000000000000000000000000003"),
+		common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		common.HexToAddress("0x0000000000000000000000000000000000000003"),
+	}
+	base := crypto.Keccak256Hash(governanceSignersSlot[:]).Big()
+	statedb.SetState(governance, governanceSignersSlot, common.BigToHash(big.NewInt(int64(len(signers)))))
+	for i, signer := range signers {
+		statedb.SetState(governance, common.BigToHash(new(big.Int).Add(base, big.NewInt(int64(i)))), signer.Hash())
+	}
+	want := append(signers[1][:], signers[0][:]...)
+
+	// Sealed checkpoints get the list filled in
+	header := checkpoint(nil)
+	if err := engine.finalizeSigners(header, statedb); err != nil {
+		t.Fatalf("failed to fill in signers: %v", err)
+	}
+	if have := header.Extra[extraVanity : len(header.Extra)-extraSeal]; !bytes.Equal(have, want) {
+		t.Errorf("filled in signers mismatch: have %x, want %x", have, want)
+	}
+	// Imported checkpoints get the list verified
+	if err := engine.finalizeSigners(checkpoint(want), statedb); err != nil {
+		t.Errorf("valid signers rejected: %v", err)
+	}
+	if err := engine.finalizeSigners(checkpoint(signers[0][:]), statedb); err != errInvalidCheckpointSigners {
+		t.Errorf("invalid signers error mismatch: have %v, want %v", err, errInvalidCheckpointSigners)
+	}
+}
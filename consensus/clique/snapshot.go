@@ -215,6 +215,28 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		}
 		snap.Recents[number] = signer
 
+		// If the signers are governed by a contract, votes are meaningless and the
+		// checkpoints carry the new signer set verified against the contract
+		if s.config.Governance != nil {
+			if number%s.config.Epoch == 0 {
+				signers := (len(header.Extra) - extraVanity - extraSeal) / common.AddressLength
+				if signers <= 0 {
+					return nil, errInvalidCheckpointSigners
+				}
+				snap.Signers = make(map[common.Address]struct{})
+				for i := 0; i < signers; i++ {
+					snap.Signers[common.BytesToAddress(header.Extra[extraVanity+i*common.AddressLength:extraVanity+(i+1)*common.AddressLength])] = struct{}{}
+				}
+				// Signer list changed, delete any recents outside the new limit
+				limit := uint64(len(snap.Signers)/2 + 1)
+				for block := range snap.Recents {
+					if block+limit <= number {
+						delete(snap.Recents, block)
+					}
+				}
+			}
+			continue
+		}
 		// Header authorized, discard any previous votes from the signer
 		for i, vote := range snap.Votes {
 			if vote.Signer == signer && vote.Address == header.Coinbase {
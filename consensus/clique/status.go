@@ -0,0 +1,195 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// defaultStatusWindow is the number of recent blocks the signer statistics
+	// are gathered over if not requested otherwise.
+	defaultStatusWindow = 64
+
+	// maxStatusWindow is the maximum number of recent blocks the signer statistics
+	// are gathered over, limiting the work of a single request.
+	maxStatusWindow = 4096
+)
+
+var (
+	inactiveSignersGauge = metrics.NewRegisteredGauge("clique/signers/inactive", nil)
+	missedSlotMeter      = metrics.NewRegisteredMeter("clique/slots/missed", nil)
+)
+
+// SignerStatus is the signing activity of an authorized signer within a window
+// of recent blocks.
+type SignerStatus struct {
+	Signed      uint64  `json:"signed"`      // Number of blocks signed within the window
+	InTurn      uint64  `json:"inTurn"`      // Number of blocks signed while in-turn
+	OutOfTurn   uint64  `json:"outOfTurn"`   // Number of blocks signed while out-of-turn
+	InTurnRatio float64 `json:"inTurnRatio"` // Ratio of the signed blocks signed in-turn
+	Missed      uint64  `json:"missed"`      // Number of in-turn slots sealed by another signer
+	LastSigned  uint64  `json:"lastSigned"`  // Number of the last block signed (0 if none within the window)
+	Inactive    bool    `json:"inactive"`    // Whether the signer didn't sign any block within the window
+}
+
+// Status is the signing activity of the authorized signers within a window of
+// recent blocks.
+type Status struct {
+	Window      uint64                           `json:"window"`      // Number of blocks the statistics were gathered over
+	InTurnRatio float64                          `json:"inTurnRatio"` // Ratio of the blocks signed in-turn
+	Signers     map[common.Address]*SignerStatus `json:"signers"`     // Activity of the currently authorized signers
+	Inactive    []common.Address                 `json:"inactive"`    // Signers without any block signed within the window
+}
+
+// status gathers the signing activity of the signers authorized at the given
+// header over the window of blocks ending with it, capped at maxStatusWindow.
+func (c *Clique) status(chain consensus.ChainReader, header *types.Header, window uint64) (*Status, error) {
+	if window > maxStatusWindow {
+		window = maxStatusWindow
+	}
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{
+		Signers:  make(map[common.Address]*SignerStatus),
+		Inactive: []common.Address{},
+	}
+	for signer := range snap.Signers {
+		status.Signers[signer] = new(SignerStatus)
+	}
+	var inturn uint64
+	for ; status.Window < window && header != nil && header.Number.Sign() > 0; status.Window++ {
+		number := header.Number.Uint64()
+
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return nil, err
+		}
+		// Out-of-turn blocks mean the in-turn signer missed its slot
+		if header.Difficulty.Cmp(diffInTurn) == 0 {
+			inturn++
+		} else {
+			parent, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+			if err != nil {
+				return nil, err
+			}
+			signers := parent.signers()
+			if stat := status.Signers[signers[number%uint64(len(signers))]]; stat != nil {
+				stat.Missed++
+			}
+		}
+		if stat := status.Signers[signer]; stat != nil {
+			stat.Signed++
+			if header.Difficulty.Cmp(diffInTurn) == 0 {
+				stat.InTurn++
+			} else {
+				stat.OutOfTurn++
+			}
+			if stat.LastSigned == 0 {
+				stat.LastSigned = number
+			}
+		}
+		header = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if status.Window > 0 {
+		status.InTurnRatio = float64(inturn) / float64(status.Window)
+	}
+	for _, signer := range snap.signers() {
+		stat := status.Signers[signer]
+		if stat.Signed > 0 {
+			stat.InTurnRatio = float64(stat.InTurn) / float64(stat.Signed)
+		} else {
+			stat.Inactive = true
+			status.Inactive = append(status.Inactive, signer)
+		}
+	}
+	return status, nil
+}
+
+// liveness tracks the blocks sealed by the authorized signers as they are
+// inserted into the canonical chain, reporting the number of blocks since the
+// last one of each signer via metrics, so that offline signers can be alerted on.
+type liveness struct {
+	head   uint64                           // Number of the highest block verified
+	first  uint64                           // Number of the first block verified
+	last   map[common.Address]uint64        // Number of the last block sealed by each signer
+	gauges map[common.Address]metrics.Gauge // Idle block gauges of the signers
+	lock   sync.Mutex
+}
+
+func newLiveness() *liveness {
+	return &liveness{
+		last:   make(map[common.Address]uint64),
+		gauges: make(map[common.Address]metrics.Gauge),
+	}
+}
+
+// update records a canonical block sealed by a signer on top of the given parent
+// snapshot. Signers are considered inactive after missing two rounds of in-turn
+// slots.
+func (l *liveness) update(snap *Snapshot, number uint64, signer common.Address, inturn bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !inturn {
+		missedSlotMeter.Mark(1)
+	}
+	// Old blocks (e.g. side chains) don't say anything about the current liveness
+	if number < l.head {
+		return
+	}
+	if l.first == 0 {
+		l.first = number
+	}
+	l.head, l.last[signer] = number, number
+
+	inactive := 0
+	for addr := range snap.Signers {
+		last := l.last[addr]
+		if last < l.first {
+			last = l.first
+		}
+		idle := number - last
+		if idle > 2*uint64(len(snap.Signers)) {
+			inactive++
+		}
+		gauge, ok := l.gauges[addr]
+		if !ok {
+			gauge = metrics.GetOrRegisterGauge(fmt.Sprintf("clique/signers/%x/idle", addr), nil)
+			l.gauges[addr] = gauge
+		}
+		gauge.Update(int64(idle))
+	}
+	inactiveSignersGauge.Update(int64(inactive))
+
+	// Drop the gauges of the deauthorized signers
+	for addr := range l.gauges {
+		if _, ok := snap.Signers[addr]; !ok {
+			metrics.DefaultRegistry.Unregister(fmt.Sprintf("clique/signers/%x/idle", addr))
+			delete(l.gauges, addr)
+			delete(l.last, addr)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testerHeaderReader implements consensus.ChainReader to access a chain of
+// headers on top of the genesis block.
+type testerHeaderReader struct {
+	testerChainReader
+	headers map[common.Hash]*types.Header
+}
+
+func (r *testerHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number == 0 {
+		return r.GetHeaderByNumber(0)
+	}
+	return r.headers[hash]
+}
+
+// Tests that the signing activity of the signers is gathered correctly, with
+// one of three signers being offline.
+func TestStatus(t *testing.T) {
+	accounts := newTesterAccountPool()
+
+	// Create three signers, named by their position in the in-turn order
+	names := map[common.Address]string{}
+	for _, name := range []string{"A", "B", "C"} {
+		names[accounts.address(name)] = name
+	}
+	signers := make([]common.Address, 0, len(names))
+	for signer := range names {
+		signers = append(signers, signer)
+	}
+	for i := 0; i < len(signers); i++ {
+		for j := i + 1; j < len(signers); j++ {
+			if bytes.Compare(signers[i][:], signers[j][:]) > 0 {
+				signers[i], signers[j] = signers[j], signers[i]
+			}
+		}
+	}
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+	}
+	for i, signer := range signers {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], signer[:])
+	}
+	db := ethdb.NewMemDatabase()
+	genesis.Commit(db)
+
+	// The last signer is offline, the others take over its slots
+	chain := &testerHeaderReader{testerChainReader{db: db}, make(map[common.Hash]*types.Header)}
+	parent := chain.GetHeaderByNumber(0)
+
+	sealers := []int{1, 0, 1, 0, 1, 0}
+	for i, sealer := range sealers {
+		number := uint64(i + 1)
+
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).SetUint64(number),
+			Time:       big.NewInt(int64(number * blockPeriod)),
+			Difficulty: diffNoTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if number%uint64(len(signers)) == uint64(sealer) {
+			header.Difficulty = diffInTurn
+		}
+		accounts.sign(header, names[signers[sealer]])
+		chain.headers[header.Hash()] = header
+		parent = header
+	}
+	engine := New(&params.CliqueConfig{Epoch: 30000}, db)
+
+	// Check the statistics over the whole chain
+	status, err := engine.status(chain, parent, 10)
+	if err != nil {
+		t.Fatalf("failed to gather status: %v", err)
+	}
+	if status.Window != 6 {
+		t.Errorf("window mismatch: have %d, want %d", status.Window, 6)
+	}
+	if status.InTurnRatio != 2.0/6 {
+		t.Errorf("in-turn ratio mismatch: have %v, want %v", status.InTurnRatio, 2.0/6)
+	}
+	want := []SignerStatus{
+		{Signed: 3, InTurn: 1, OutOfTurn: 2, InTurnRatio: 1.0 / 3, Missed: 1, LastSigned: 6},
+		{Signed: 3, InTurn: 1, OutOfTurn: 2, InTurnRatio: 1.0 / 3, Missed: 1, LastSigned: 5},
+		{Missed: 2, Inactive: true},
+	}
+	for i, signer := range signers {
+		if have := *status.Signers[signer]; have != want[i] {
+			t.Errorf("signer %d: status mismatch: have %+v, want %+v", i, have, want[i])
+		}
+	}
+	if len(status.Inactive) != 1 || status.Inactive[0] != signers[2] {
+		t.Errorf("inactive signers mismatch: have %x, want [%x]", status.Inactive, signers[2])
+	}
+	// Check the statistics over a shorter window
+	if status, err = engine.status(chain, parent, 2); err != nil {
+		t.Fatalf("failed to gather status: %v", err)
+	}
+	for i, signer := range signers[:2] {
+		if signed := status.Signers[signer].Signed; signed != 1 {
+			t.Errorf("signer %d: signed blocks mismatch: have %d, want %d", i, signed, 1)
+		}
+	}
+	if missed := status.Signers[signers[2]].Missed; missed != 1 {
+		t.Errorf("missed slots mismatch: have %d, want %d", missed, 1)
+	}
+	// Check that the window is capped
+	if status, err = engine.status(chain, parent, maxStatusWindow+1); err != nil {
+		t.Fatalf("failed to gather status: %v", err)
+	}
+	if status.Window != 6 {
+		t.Errorf("capped window mismatch: have %d, want %d", status.Window, 6)
+	}
+	// Check that the liveness is tracked from the canonical blocks inserted
+	var headers []*types.Header
+	for header := parent; header.Number.Sign() > 0; header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+		headers = append([]*types.Header{header}, headers...)
+	}
+	for _, header := range headers {
+		engine.InsertedBlock(chain, header)
+	}
+	if engine.liveness.head != 6 {
+		t.Errorf("liveness head mismatch: have %d, want %d", engine.liveness.head, 6)
+	}
+	for i, want := range []uint64{6, 5, 0} {
+		if have := engine.liveness.last[signers[i]]; have != want {
+			t.Errorf("signer %d: last sealed block mismatch: have %d, want %d", i, have, want)
+		}
+	}
+}
@@ -143,7 +143,7 @@ func seedHash(block uint64) []byte {
 // 캐시 생성 과정은 32mb 메모리를 순서대로 채우고 2pass의 
 // Sergio Demian Lerner's RandMemoHash algorithm을 수행한다 
 // 출력은 524288개의 64byte 값이다
-func generateCache(dest []uint32, epoch uint64, seed []byte) {
+func generateCache(dest []uint32, epoch uint64, seed []byte, tracker *progressTracker) {
 	// Print some debug logs to allow analysis on low end devices
 	logger := log.New("epoch", epoch)
 
@@ -170,6 +170,9 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 	// Start a monitoring goroutine to report progress on low end devices
 	var progress uint32
 
+	gen := tracker.begin("cache", epoch, uint64(rows)*(cacheRounds+1))
+	defer tracker.end(gen)
+
 	done := make(chan struct{})
 	defer close(done)
 
@@ -191,6 +194,7 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 	for offset := uint64(hashBytes); offset < size; offset += hashBytes {
 		keccak512(cache[offset:], cache[offset-hashBytes:offset])
 		atomic.AddUint32(&progress, 1)
+		gen.advance()
 	}
 	// Use a low-round version of randmemohash
 	temp := make([]byte, hashBytes)
@@ -206,6 +210,7 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 			keccak512(cache[dstOff:], temp)
 
 			atomic.AddUint32(&progress, 1)
+			gen.advance()
 		}
 	}
 	// Swap the byte order on big endian systems and return
@@ -290,7 +295,7 @@ func generateDatasetItem(cache []uint32, index uint32, keccak512 hasher) []byte
 // This method places the result into dest in machine byte order.
 // generateDataset함수는 마이닝을 위한 전체 ethashdataset을 생성한다
 // 이 함수는 결과를 머신 byte순서로 저장한다
-func generateDataset(dest []uint32, epoch uint64, cache []uint32) {
+func generateDataset(dest []uint32, epoch uint64, cache []uint32, tracker *progressTracker) {
 	// Print some debug logs to allow analysis on low end devices
 	logger := log.New("epoch", epoch)
 
@@ -321,6 +326,9 @@ func generateDataset(dest []uint32, epoch uint64, cache []uint32) {
 	var pend sync.WaitGroup
 	pend.Add(threads)
 
+	gen := tracker.begin("dag", epoch, size/hashBytes)
+	defer tracker.end(gen)
+
 	var progress uint32
 	for i := 0; i < threads; i++ {
 		go func(id int) {
@@ -344,6 +352,7 @@ func generateDataset(dest []uint32, epoch uint64, cache []uint32) {
 					swap(item)
 				}
 				copy(dataset[index*hashBytes:], item)
+				gen.advance()
 
 				if status := atomic.AddUint32(&progress, 1); status%percent == 0 {
 					logger.Info("Generating DAG in progress", "percentage", uint64(status*100)/(size/hashBytes), "elapsed", common.PrettyDuration(time.Since(start)))
@@ -0,0 +1,71 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// API is a user facing RPC API to monitor and control the generation of the
+// ethash verification caches and mining DAGs.
+type API struct {
+	ethash *Ethash
+}
+
+// GetProgress returns the state of the currently running cache and DAG
+// generations, empty if none is running.
+func (api *API) GetProgress() []Progress {
+	return api.ethash.Progress()
+}
+
+// Progress creates a subscription that is notified when a cache or DAG generation
+// starts, periodically while it's running and when it finishes.
+func (api *API) Progress(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		progress := make(chan Progress, 16)
+		sub := api.ethash.SubscribeProgress(progress)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case p := <-progress:
+				notifier.Notify(rpcSub.ID, p)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// GenerateDAG starts generating the mining DAG of the given block's epoch in the
+// background, e.g. to prepare the next epoch ahead of time. The progress can be
+// followed via GetProgress or the progress subscription.
+func (api *API) GenerateDAG(block hexutil.Uint64) error {
+	return api.ethash.GenerateDAG(uint64(block))
+}
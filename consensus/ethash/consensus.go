@@ -546,6 +546,31 @@ func (ethash *Ethash) VerifySeal(chain consensus.ChainReader, header *types.Head
 	return nil
 }
 
+// Hashimoto computes the mix digest and the proof-of-work value of a header sealed
+// with the given nonce, without checking them against the header's difficulty. It
+// is used by mining pools to verify shares of a lower difficulty than the block.
+func (ethash *Ethash) Hashimoto(header *types.Header, nonce types.BlockNonce) (common.Hash, common.Hash) {
+	// If we're running a fake PoW, any nonce is a perfect solution
+	if ethash.config.PowMode == ModeFake || ethash.config.PowMode == ModeFullFake {
+		return common.Hash{}, common.Hash{}
+	}
+	// If we're running a shared PoW, delegate the computation to it
+	if ethash.shared != nil {
+		return ethash.shared.Hashimoto(header, nonce)
+	}
+	number := header.Number.Uint64()
+
+	cache := ethash.cache(number)
+	size := datasetSize(number)
+	if ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
+	}
+	digest, result := hashimotoLight(size, cache.cache, header.HashNoNonce().Bytes(), nonce.Uint64())
+	runtime.KeepAlive(cache)
+
+	return common.BytesToHash(digest), common.BytesToHash(result)
+}
+
 // Prepare implements consensus.Engine, initializing the difficulty field of a
 // header to conform to the ethash protocol. The changes are done inline.
 // Prepare 함수는 합의엔진을 구현한다. 헤더가 ethash 프로토콜을 따르도록 난이도 필드를 초기화 한다.
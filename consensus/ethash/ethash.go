@@ -35,6 +35,7 @@ import (
 
 	mmap "github.com/edsrzf/mmap-go"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -213,6 +214,21 @@ func (lru *lru) get(epoch uint64) (item, future interface{}) {
 	return item, future
 }
 
+// peek retrieves the item for the given epoch if it's already known, without
+// creating it or updating its recentness.
+func (lru *lru) peek(epoch uint64) interface{} {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if item, ok := lru.cache.Peek(epoch); ok {
+		return item
+	}
+	if lru.future > 0 && lru.future == epoch {
+		return lru.futureItem
+	}
+	return nil
+}
+
 // cache wraps an ethash cache with some metadata to allow easier concurrent use.
 // cache 구조체는 쉽고 병렬적인 사용을 위해
 // ethash 캐시를 약간의 메타데이터와 함께 포함한다
@@ -234,7 +250,7 @@ func newCache(epoch uint64) interface{} {
 
 // generate ensures that the cache content is generated before use.
 // generate함수는 캐시컨텐츠가 사용되기 전에 생성되었는지 보증한다
-func (c *cache) generate(dir string, limit int, test bool) {
+func (c *cache) generate(dir string, limit int, test bool, progress *progressTracker) {
 	c.once.Do(func() {
 		size := cacheSize(c.epoch*epochLength + 1)
 		seed := seedHash(c.epoch*epochLength + 1)
@@ -244,7 +260,7 @@ func (c *cache) generate(dir string, limit int, test bool) {
 		// If we don't store anything on disk, generate and return.
 		if dir == "" {
 			c.cache = make([]uint32, size/4)
-			generateCache(c.cache, c.epoch, seed)
+			generateCache(c.cache, c.epoch, seed, progress)
 			return
 		}
 		// Disk storage is needed, this will get fancy
@@ -269,12 +285,12 @@ func (c *cache) generate(dir string, limit int, test bool) {
 		logger.Debug("Failed to load old ethash cache", "err", err)
 
 		// No previous cache available, create a new cache file to fill
-		c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, func(buffer []uint32) { generateCache(buffer, c.epoch, seed) })
+		c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, func(buffer []uint32) { generateCache(buffer, c.epoch, seed, progress) })
 		if err != nil {
 			logger.Error("Failed to generate mapped ethash cache", "err", err)
 
 			c.cache = make([]uint32, size/4)
-			generateCache(c.cache, c.epoch, seed)
+			generateCache(c.cache, c.epoch, seed, progress)
 		}
 		// Iterate over all previous instances and delete old ones
 		for ep := int(c.epoch) - limit; ep >= 0; ep-- {
@@ -321,7 +337,7 @@ func newDataset(epoch uint64) interface{} {
 
 // generate ensures that the dataset content is generated before use.
 // generate 함수는 데이터셋 컨텐츠가 사용전에 생성되었는지 확증한다
-func (d *dataset) generate(dir string, limit int, test bool) {
+func (d *dataset) generate(dir string, limit int, test bool, progress *progressTracker) {
 	d.once.Do(func() {
 		csize := cacheSize(d.epoch*epochLength + 1)
 		dsize := datasetSize(d.epoch*epochLength + 1)
@@ -333,10 +349,10 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		// If we don't store anything on disk, generate and return
 		if dir == "" {
 			cache := make([]uint32, csize/4)
-			generateCache(cache, d.epoch, seed)
+			generateCache(cache, d.epoch, seed, progress)
 
 			d.dataset = make([]uint32, dsize/4)
-			generateDataset(d.dataset, d.epoch, cache)
+			generateDataset(d.dataset, d.epoch, cache, progress)
 		}
 		// Disk storage is needed, this will get fancy
 		var endian string
@@ -361,14 +377,14 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 
 		// No previous dataset available, create a new dataset file to fill
 		cache := make([]uint32, csize/4)
-		generateCache(cache, d.epoch, seed)
+		generateCache(cache, d.epoch, seed, progress)
 
-		d.dump, d.mmap, d.dataset, err = memoryMapAndGenerate(path, dsize, func(buffer []uint32) { generateDataset(buffer, d.epoch, cache) })
+		d.dump, d.mmap, d.dataset, err = memoryMapAndGenerate(path, dsize, func(buffer []uint32) { generateDataset(buffer, d.epoch, cache, progress) })
 		if err != nil {
 			logger.Error("Failed to generate mapped ethash dataset", "err", err)
 
 			d.dataset = make([]uint32, dsize/2)
-			generateDataset(d.dataset, d.epoch, cache)
+			generateDataset(d.dataset, d.epoch, cache, progress)
 		}
 		// Iterate over all previous instances and delete old ones
 		for ep := int(d.epoch) - limit; ep >= 0; ep-- {
@@ -393,14 +409,14 @@ func (d *dataset) finalizer() {
 // MakeCache 함수는 새로운 ehtash 캐시를 생성하고 디스크에 선택적으로 저장한다
 func MakeCache(block uint64, dir string) {
 	c := cache{epoch: block / epochLength}
-	c.generate(dir, math.MaxInt32, false)
+	c.generate(dir, math.MaxInt32, false, nil)
 }
 
 // MakeDataset generates a new ethash dataset and optionally stores it to disk.
 // MakeDataset함수는 새로운 ehtash 데이터셋을 생성하고 디스크에 선택적으로 저장한다
 func MakeDataset(block uint64, dir string) {
 	d := dataset{epoch: block / epochLength}
-	d.generate(dir, math.MaxInt32, false)
+	d.generate(dir, math.MaxInt32, false, nil)
 }
 
 // Mode defines the type and amount of PoW verification an ethash engine makes.
@@ -448,6 +464,9 @@ type Ethash struct {
 	// 마이닝 파라미터 변경을 위한 노티채널
 	hashrate metrics.Meter // Meter tracking the average hashrate
 
+	progress *progressTracker    // Progress of the running cache and DAG generations
+	dags     map[uint64]*dataset // DAGs being generated on demand, not kept in memory
+
 	// The fields below are hooks for testing
 	// 테스트에 관련된 훅들
 	shared    *Ethash       // Shared PoW verifier to avoid cache regeneration
@@ -476,6 +495,7 @@ func New(config Config) *Ethash {
 		datasets: newlru("dataset", config.DatasetsInMem, newDataset),
 		update:   make(chan struct{}),
 		hashrate: metrics.NewMeter(),
+		progress: newProgressTracker(),
 	}
 }
 
@@ -560,12 +580,12 @@ func (ethash *Ethash) cache(block uint64) *cache {
 	current := currentI.(*cache)
 
 	// Wait for generation finish.
-	current.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest)
+	current.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest, ethash.progress)
 
 	// If we need a new future cache, now's a good time to regenerate it.
 	if futureI != nil {
 		future := futureI.(*cache)
-		go future.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest)
+		go future.generate(ethash.config.CacheDir, ethash.config.CachesOnDisk, ethash.config.PowMode == ModeTest, ethash.progress)
 	}
 	return current
 }
@@ -581,12 +601,12 @@ func (ethash *Ethash) dataset(block uint64) *dataset {
 	current := currentI.(*dataset)
 
 	// Wait for generation finish.
-	current.generate(ethash.config.DatasetDir, ethash.config.DatasetsOnDisk, ethash.config.PowMode == ModeTest)
+	current.generate(ethash.config.DatasetDir, ethash.config.DatasetsOnDisk, ethash.config.PowMode == ModeTest, ethash.progress)
 
 	// If we need a new future dataset, now's a good time to regenerate it.
 	if futureI != nil {
 		future := futureI.(*dataset)
-		go future.generate(ethash.config.DatasetDir, ethash.config.DatasetsOnDisk, ethash.config.PowMode == ModeTest)
+		go future.generate(ethash.config.DatasetDir, ethash.config.DatasetsOnDisk, ethash.config.PowMode == ModeTest, ethash.progress)
 	}
 
 	return current
@@ -636,11 +656,90 @@ func (ethash *Ethash) Hashrate() float64 {
 	return ethash.hashrate.Rate1()
 }
 
-// APIs implements consensus.Engine, returning the user facing RPC APIs. Currently
-// that is empty.
+// Progress returns the state of the currently running verification cache and
+// mining DAG generations.
+func (ethash *Ethash) Progress() []Progress {
+	if ethash.shared != nil {
+		return ethash.shared.Progress()
+	}
+	return ethash.progress.list()
+}
+
+// SubscribeProgress registers a subscription for the progress reports of the
+// verification cache and mining DAG generations. A report is sent when a
+// generation starts, periodically while it's running and when it finishes.
+func (ethash *Ethash) SubscribeProgress(ch chan<- Progress) event.Subscription {
+	if ethash.shared != nil {
+		return ethash.shared.SubscribeProgress(ch)
+	}
+	if ethash.progress == nil {
+		// Fake engines never generate anything
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			<-quit
+			return nil
+		})
+	}
+	return ethash.progress.subscribe(ch)
+}
+
+// GenerateDAG starts generating the mining DAG of the given block's epoch in the
+// background and stores it on disk, so that mining can switch over to the epoch
+// without stalling. The DAG isn't kept in memory afterwards, and generating it
+// doesn't delete the DAGs of older epochs from disk.
+//
+// If the DAG of the epoch is already being generated, either on demand or for
+// mining, the running generation is reused.
+func (ethash *Ethash) GenerateDAG(block uint64) error {
+	if ethash.shared != nil {
+		return ethash.shared.GenerateDAG(block)
+	}
+	if ethash.config.PowMode == ModeFake || ethash.config.PowMode == ModeFullFake {
+		return errors.New("fake ethash doesn't generate DAGs")
+	}
+	if ethash.config.DatasetDir == "" || ethash.config.DatasetsOnDisk <= 0 {
+		return errors.New("DAG disk storage disabled")
+	}
+	epoch := block / epochLength
+
+	// Reuse the dataset of the miner if it's already known
+	if item := ethash.datasets.peek(epoch); item != nil {
+		go item.(*dataset).generate(ethash.config.DatasetDir, ethash.config.DatasetsOnDisk, ethash.config.PowMode == ModeTest, ethash.progress)
+		return nil
+	}
+	// Otherwise start a standalone generation, unless one is already running
+	ethash.lock.Lock()
+	defer ethash.lock.Unlock()
+
+	if _, ok := ethash.dags[epoch]; ok {
+		return nil
+	}
+	if ethash.dags == nil {
+		ethash.dags = make(map[uint64]*dataset)
+	}
+	d := &dataset{epoch: epoch}
+	ethash.dags[epoch] = d
+
+	go func() {
+		// Don't purge any DAGs, the requested epoch may be far from the mined one
+		d.generate(ethash.config.DatasetDir, math.MaxInt32, ethash.config.PowMode == ModeTest, ethash.progress)
+
+		ethash.lock.Lock()
+		delete(ethash.dags, epoch)
+		ethash.lock.Unlock()
+	}()
+	return nil
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC APIs to report
+// and control the generation of the mining DAGs.
 // APIs 함수는 합의엔진을 구현하며, 유저가 마주칠 RPC API들을 반환한다
 func (ethash *Ethash) APIs(chain consensus.ChainReader) []rpc.API {
-	return nil
+	return []rpc.API{{
+		Namespace: "ethash",
+		Version:   "1.0",
+		Service:   &API{ethash},
+		Public:    false,
+	}}
 }
 
 // SeedHash is the seed to use for generating a verification cache and the mining
@@ -23,6 +23,7 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
 )
@@ -77,3 +78,157 @@ func verifyTest(wg *sync.WaitGroup, e *Ethash, workerIndex, epochs int) {
 		e.VerifySeal(nil, head)
 	}
 }
+
+// Tests that DAGs can be pre-generated on demand, with the progress of both the
+// cache and the DAG generation reported to the subscribers.
+func TestGenerateDAG(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "ethash-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := NewFaker().GenerateDAG(epochLength); err == nil {
+		t.Errorf("fake ethash generated DAG")
+	}
+	if err := NewTester().GenerateDAG(epochLength); err == nil {
+		t.Errorf("DAG generated without disk storage")
+	}
+	e := New(Config{CachesInMem: 1, DatasetsInMem: 1, DatasetDir: tmpdir, DatasetsOnDisk: 1, PowMode: ModeTest})
+
+	progress := make(chan Progress, 64)
+	sub := e.SubscribeProgress(progress)
+	defer sub.Unsubscribe()
+
+	if err := e.GenerateDAG(epochLength + 1); err != nil {
+		t.Fatalf("failed to start DAG generation: %v", err)
+	}
+	// Wait for both generations to finish, checking the reports along the way
+	finished := make(map[string]bool)
+	timeout := time.After(10 * time.Second)
+	for !finished["dag"] {
+		select {
+		case p := <-progress:
+			if p.Epoch != 1 || p.Block != epochLength {
+				t.Fatalf("epoch mismatch: have %d (block %d), want 1 (block %d)", p.Epoch, p.Block, epochLength)
+			}
+			if finished[p.Kind] {
+				t.Fatalf("%s reported after finishing: %+v", p.Kind, p)
+			}
+			if p.Done {
+				if p.Percentage != 100 {
+					t.Errorf("%s finished at %v%%", p.Kind, p.Percentage)
+				}
+				finished[p.Kind] = true
+			}
+		case <-timeout:
+			t.Fatalf("DAG generation timed out")
+		}
+	}
+	if !finished["cache"] {
+		t.Errorf("cache generation not reported")
+	}
+	if running := e.Progress(); len(running) != 0 {
+		t.Errorf("generations still running: %+v", running)
+	}
+	files, _ := ioutil.ReadDir(tmpdir)
+	if len(files) != 1 {
+		t.Errorf("DAG files mismatch: have %d, want 1", len(files))
+	}
+}
+
+// Tests that subscribers not consuming the progress reports don't stall the
+// generations, and that they eventually receive the final report.
+func TestProgressSlowSubscriber(t *testing.T) {
+	tracker := newProgressTracker()
+
+	progress := make(chan Progress)
+	sub := tracker.subscribe(progress)
+	defer sub.Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := uint64(0); i < 3; i++ {
+			g := tracker.begin("cache", i, 1)
+			g.advance()
+			tracker.end(g)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("generations stalled by the subscriber")
+	}
+	// Stale reports may be dropped, but the final ones are all delivered
+	for finished := uint64(0); finished < 3; {
+		select {
+		case p := <-progress:
+			if p.Done {
+				if p.Epoch != finished {
+					t.Fatalf("finished epoch mismatch: have %d, want %d", p.Epoch, finished)
+				}
+				finished++
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("final report of epoch %d not delivered", finished)
+		}
+	}
+}
+
+// Tests that generating a far future DAG on demand doesn't delete the DAGs of
+// older epochs, and that concurrent requests for an epoch generate it only once.
+func TestGenerateDAGNoPurge(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "ethash-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	e := New(Config{CachesInMem: 1, DatasetsInMem: 1, DatasetDir: tmpdir, DatasetsOnDisk: 1, PowMode: ModeTest})
+
+	progress := make(chan Progress, 64)
+	sub := e.SubscribeProgress(progress)
+	defer sub.Unsubscribe()
+
+	// waitDAG waits for the DAG generation of an epoch to finish, returning the
+	// number of generations finished.
+	waitDAG := func(epoch uint64) int {
+		done := 0
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case p := <-progress:
+				if p.Kind == "dag" && p.Done {
+					if p.Epoch != epoch {
+						t.Fatalf("epoch mismatch: have %d, want %d", p.Epoch, epoch)
+					}
+					done++
+				}
+			case <-time.After(100 * time.Millisecond):
+				if done > 0 {
+					return done
+				}
+			case <-timeout:
+				t.Fatalf("DAG generation timed out")
+			}
+		}
+	}
+	if err := e.GenerateDAG(0); err != nil {
+		t.Fatalf("failed to start DAG generation: %v", err)
+	}
+	waitDAG(0)
+
+	for i := 0; i < 2; i++ {
+		if err := e.GenerateDAG(5 * epochLength); err != nil {
+			t.Fatalf("failed to start DAG generation: %v", err)
+		}
+	}
+	if done := waitDAG(5); done != 1 {
+		t.Errorf("DAG generations mismatch: have %d, want 1", done)
+	}
+	files, _ := ioutil.ReadDir(tmpdir)
+	if len(files) != 2 {
+		t.Errorf("DAG files mismatch: have %d, want 2", len(files))
+	}
+}
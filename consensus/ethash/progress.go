@@ -0,0 +1,222 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// progressInterval is the interval at which the progress of the running cache
+// and DAG generations is reported to the subscribers.
+const progressInterval = time.Second
+
+// Progress is the state of generating an ethash verification cache or mining DAG.
+type Progress struct {
+	Kind       string  `json:"kind"`       // Type of the data being generated ("cache" or "dag")
+	Epoch      uint64  `json:"epoch"`      // Epoch the data is generated for
+	Block      uint64  `json:"block"`      // First block of the epoch
+	Percentage float64 `json:"percentage"` // Percentage of the data generated so far
+	Elapsed    float64 `json:"elapsed"`    // Seconds elapsed since the generation started
+	ETA        float64 `json:"eta"`        // Estimated seconds until the generation finishes
+	Done       bool    `json:"done"`       // Whether the generation finished
+}
+
+// generation is a single running cache or DAG generation.
+type generation struct {
+	done  uint64 // Number of items generated so far (atomic, keep 64 bit aligned)
+	total uint64 // Number of items to generate
+	kind  string
+	epoch uint64
+	start time.Time
+	quit  chan struct{} // Channel to stop the periodic reports
+	term  chan struct{} // Channel closed when the periodic reports stopped
+}
+
+// advance marks an item as generated.
+func (g *generation) advance() {
+	if g != nil {
+		atomic.AddUint64(&g.done, 1)
+	}
+}
+
+// progress returns the current state of the generation, estimating the time left
+// from the rate so far.
+func (g *generation) progress() Progress {
+	done := atomic.LoadUint64(&g.done)
+	if done > g.total {
+		done = g.total
+	}
+	elapsed := time.Since(g.start)
+
+	progress := Progress{
+		Kind:    g.kind,
+		Epoch:   g.epoch,
+		Block:   g.epoch * epochLength,
+		Elapsed: elapsed.Seconds(),
+	}
+	if g.total > 0 {
+		progress.Percentage = float64(done) * 100 / float64(g.total)
+	}
+	if done > 0 {
+		progress.ETA = (elapsed.Seconds() / float64(done)) * float64(g.total-done)
+	}
+	return progress
+}
+
+// progressTracker keeps track of the running cache and DAG generations of an
+// ethash engine, periodically reporting their progress to the subscribers. The
+// reports are delivered in the background, so slow subscribers never stall the
+// generations, only the latest report of each being kept until delivered. All
+// methods are safe to call on a nil tracker, doing nothing.
+type progressTracker struct {
+	active  map[*generation]struct{}
+	queued  map[*generation]Progress // Latest undelivered report of each generation
+	order   []*generation            // Generations with undelivered reports, in order
+	sending bool                     // Whether the reports are being delivered
+	feed    event.Feed
+	lock    sync.Mutex
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{
+		active: make(map[*generation]struct{}),
+		queued: make(map[*generation]Progress),
+	}
+}
+
+// begin registers a new generation of the given number of items, reporting its
+// progress until ended.
+func (t *progressTracker) begin(kind string, epoch uint64, total uint64) *generation {
+	if t == nil {
+		return nil
+	}
+	g := &generation{
+		total: total,
+		kind:  kind,
+		epoch: epoch,
+		start: time.Now(),
+		quit:  make(chan struct{}),
+		term:  make(chan struct{}),
+	}
+	t.lock.Lock()
+	t.active[g] = struct{}{}
+	t.lock.Unlock()
+
+	t.report(g, g.progress())
+	go func() {
+		defer close(g.term)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.report(g, g.progress())
+			case <-g.quit:
+				return
+			}
+		}
+	}()
+	return g
+}
+
+// end marks a generation finished, reporting it a last time.
+func (t *progressTracker) end(g *generation) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	delete(t.active, g)
+	t.lock.Unlock()
+
+	close(g.quit)
+	<-g.term
+
+	progress := g.progress()
+	progress.Percentage, progress.ETA, progress.Done = 100, 0, true
+	t.report(g, progress)
+}
+
+// report queues a progress report of a generation for delivery, replacing any
+// stale one not delivered yet.
+func (t *progressTracker) report(g *generation, progress Progress) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.queued[g]; !ok {
+		t.order = append(t.order, g)
+	}
+	t.queued[g] = progress
+
+	if !t.sending {
+		t.sending = true
+		go t.deliver()
+	}
+}
+
+// deliver sends the queued progress reports to the subscribers until none are
+// left.
+func (t *progressTracker) deliver() {
+	for {
+		t.lock.Lock()
+		if len(t.order) == 0 {
+			t.sending = false
+			t.lock.Unlock()
+			return
+		}
+		g := t.order[0]
+		t.order = t.order[1:]
+		progress := t.queued[g]
+		delete(t.queued, g)
+		t.lock.Unlock()
+
+		t.feed.Send(progress)
+	}
+}
+
+// list returns the progress of the running generations, ordered by epoch with
+// the caches first.
+func (t *progressTracker) list() []Progress {
+	progress := []Progress{}
+	if t == nil {
+		return progress
+	}
+	t.lock.Lock()
+	for g := range t.active {
+		progress = append(progress, g.progress())
+	}
+	t.lock.Unlock()
+
+	sort.Slice(progress, func(i, j int) bool {
+		if progress[i].Epoch != progress[j].Epoch {
+			return progress[i].Epoch < progress[j].Epoch
+		}
+		return progress[i].Kind < progress[j].Kind
+	})
+	return progress
+}
+
+// subscribe registers a subscription for the progress reports.
+func (t *progressTracker) subscribe(ch chan<- Progress) event.Subscription {
+	return t.feed.Subscribe(ch)
+}
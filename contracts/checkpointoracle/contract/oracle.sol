@@ -0,0 +1,142 @@
+pragma solidity ^0.4.24;
+
+/**
+ * @title CheckpointOracle
+ * @dev Stores the latest light client checkpoint of a chain, along with the
+ * signatures of the trusted signers that approved it. A checkpoint can only be
+ * published if it's signed by at least a threshold of the admins.
+ */
+contract CheckpointOracle {
+    /*
+        Events
+    */
+
+    // NewCheckpoint is emitted when a new checkpoint is published.
+    event NewCheckpoint(uint64 indexed index, bytes32 sectionHead, bytes32 chtRoot, bytes32 bloomRoot);
+
+    /*
+        Public Functions
+    */
+    constructor(address[] _adminlist, uint _threshold) public {
+        require(_threshold > 0 && _threshold <= _adminlist.length);
+
+        for (uint i = 0; i < _adminlist.length; i++) {
+            admins[_adminlist[i]] = true;
+            adminList.push(_adminlist[i]);
+        }
+        threshold = _threshold;
+    }
+
+    /**
+     * @dev Get latest stable checkpoint information.
+     * @return section index
+     * @return section head hash
+     * @return canonical hash trie root
+     * @return bloom trie root
+     * @return block height associated with checkpoint
+     */
+    function GetLatestCheckpoint()
+    view
+    public
+    returns(uint64, bytes32, bytes32, bytes32, uint) {
+        return (sectionIndex, sectionHead, chtRoot, bloomRoot, height);
+    }
+
+    /**
+     * @dev Get the signatures approving the latest checkpoint.
+     */
+    function GetSignatures()
+    view
+    public
+    returns(uint8[], bytes32[], bytes32[]) {
+        return (sigV, sigR, sigS);
+    }
+
+    /**
+     * @dev Get all admin addresses.
+     */
+    function GetAllAdmin()
+    view
+    public
+    returns(address[]) {
+        return adminList;
+    }
+
+    /**
+     * @dev Publish a new checkpoint signed by at least threshold admins. The
+     * signed hash is keccak256(0x19, 0x00, oracle, index, checkpoint hash) where
+     * the checkpoint hash is keccak256(index, sectionHead, chtRoot, bloomRoot).
+     */
+    function SetCheckpoint(
+        uint64 _sectionIndex,
+        bytes32 _sectionHead,
+        bytes32 _chtRoot,
+        bytes32 _bloomRoot,
+        uint8[] _v,
+        bytes32[] _r,
+        bytes32[] _s
+    )
+    public
+    returns (bool)
+    {
+        // Ensure the sender is authorized.
+        require(admins[msg.sender]);
+
+        // Ensure checkpoints are only ever moved forward.
+        if (_sectionIndex <= sectionIndex && height != 0) {
+            return false;
+        }
+        // Ensure the signatures are well formed.
+        require(_v.length == _r.length && _v.length == _s.length);
+        require(_v.length >= threshold);
+
+        bytes32 hash = keccak256(abi.encodePacked(_sectionIndex, _sectionHead, _chtRoot, _bloomRoot));
+        bytes32 signedHash = keccak256(abi.encodePacked(byte(0x19), byte(0), this, _sectionIndex, hash));
+
+        address lastVoter = address(0);
+        for (uint i = 0; i < _v.length; i++) {
+            address signer = ecrecover(signedHash, _v[i], _r[i], _s[i]);
+            require(admins[signer]);
+
+            // Signatures must be ordered by signer to reject duplicates cheaply.
+            require(uint256(signer) > uint256(lastVoter));
+            lastVoter = signer;
+        }
+        sectionIndex = _sectionIndex;
+        sectionHead = _sectionHead;
+        chtRoot = _chtRoot;
+        bloomRoot = _bloomRoot;
+        height = block.number;
+
+        sigV = _v;
+        sigR = _r;
+        sigS = _s;
+
+        emit NewCheckpoint(_sectionIndex, _sectionHead, _chtRoot, _bloomRoot);
+        return true;
+    }
+
+    /*
+        Fields
+    */
+    // A map of admin users who have the permission to publish checkpoints.
+    mapping(address => bool) admins;
+
+    // A list of admin users so that we can obtain all admin users.
+    address[] adminList;
+
+    // Latest stored checkpoint.
+    uint64 sectionIndex;
+    bytes32 sectionHead;
+    bytes32 chtRoot;
+    bytes32 bloomRoot;
+    uint height;
+
+    // Signatures approving the latest checkpoint.
+    uint8[] sigV;
+    bytes32[] sigR;
+    bytes32[] sigS;
+
+    // The minimum number of admin signatures required to publish a checkpoint.
+    uint threshold;
+}
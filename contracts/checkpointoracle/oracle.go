@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package checkpointoracle is an on-chain light client checkpoint oracle.
+package checkpointoracle
+
+//go:generate abigen --sol contract/oracle.sol --pkg contract --out contract/oracle.go
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	followRecheck = 10 * time.Minute // Interval at which to look for new checkpoints in the oracle
+	followTimeout = 30 * time.Second // Maximum time allowed for retrieving the checkpoint from the oracle
+)
+
+var (
+	errNoCheckpoint        = errors.New("no checkpoint published")
+	errMalformedSignatures = errors.New("malformed checkpoint signatures")
+)
+
+// CheckpointOracle is a Go wrapper around an on-chain checkpoint oracle contract,
+// which only accepts checkpoints signed by a threshold of locally trusted keys.
+type CheckpointOracle struct {
+	config   *params.CheckpointOracleConfig
+	contract *contract.CheckpointOracle
+}
+
+// NewCheckpointOracle binds checkpoint contract and returns a registrar instance.
+func NewCheckpointOracle(config *params.CheckpointOracleConfig, backend bind.ContractBackend) (*CheckpointOracle, error) {
+	c, err := contract.NewCheckpointOracle(config.Address, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{config: config, contract: c}, nil
+}
+
+// Contract returns the underlying contract instance.
+func (oracle *CheckpointOracle) Contract() *contract.CheckpointOracle {
+	return oracle.contract
+}
+
+// LatestCheckpoint retrieves the latest checkpoint published in the oracle and
+// verifies that it was signed by enough of the locally trusted signers.
+func (oracle *CheckpointOracle) LatestCheckpoint(opts *bind.CallOpts) (*params.TrustedCheckpoint, error) {
+	index, head, cht, bloom, height, err := oracle.contract.GetLatestCheckpoint(opts)
+	if err != nil {
+		return nil, err
+	}
+	if height.Sign() == 0 {
+		return nil, errNoCheckpoint
+	}
+	checkpoint := &params.TrustedCheckpoint{
+		Name:         "oracle",
+		SectionIndex: index,
+		SectionHead:  head,
+		CHTRoot:      cht,
+		BloomRoot:    bloom,
+	}
+	v, r, s, err := oracle.contract.GetSignatures(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := oracle.VerifySigners(checkpoint, v, r, s); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Follow periodically retrieves the latest checkpoint from the oracle until quit
+// is closed, passing every newly published one to the given callback.
+func (oracle *CheckpointOracle) Follow(callback func(*params.TrustedCheckpoint), quit <-chan bool) {
+	ticker := time.NewTicker(followRecheck)
+	defer ticker.Stop()
+
+	var latest *params.TrustedCheckpoint
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), followTimeout)
+		checkpoint, err := oracle.LatestCheckpoint(&bind.CallOpts{Context: ctx})
+		cancel()
+
+		switch {
+		case err != nil:
+			log.Debug("Failed to retrieve oracle checkpoint", "err", err)
+		case latest == nil || checkpoint.SectionIndex > latest.SectionIndex:
+			latest = checkpoint
+			callback(checkpoint)
+		}
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// VerifySigners recovers the signer addresses of a checkpoint from the given
+// signatures and ensures that at least the configured threshold of them are
+// trusted.
+func (oracle *CheckpointOracle) VerifySigners(checkpoint *params.TrustedCheckpoint, v []uint8, r, s [][32]byte) error {
+	if len(v) != len(r) || len(v) != len(s) {
+		return errMalformedSignatures
+	}
+	trusted := make(map[common.Address]bool)
+	for _, signer := range oracle.config.Signers {
+		trusted[signer] = true
+	}
+	hash := SignatureHash(oracle.config.Address, checkpoint)
+
+	signers := make(map[common.Address]bool)
+	for i := range v {
+		sig := make([]byte, 65)
+		copy(sig, r[i][:])
+		copy(sig[32:], s[i][:])
+		sig[64] = v[i] - 27
+
+		pubkey, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			return err
+		}
+		if signer := crypto.PubkeyToAddress(*pubkey); trusted[signer] {
+			signers[signer] = true
+		}
+	}
+	if uint64(len(signers)) < oracle.config.Threshold {
+		return fmt.Errorf("not enough trusted signers: have %d, want %d", len(signers), oracle.config.Threshold)
+	}
+	return nil
+}
+
+// SignatureHash returns the hash the oracle signers need to sign to approve a
+// checkpoint, following the EIP-191 "intended validator" scheme:
+//
+//	keccak256(0x19 || 0x00 || oracle || index || checkpoint hash)
+func SignatureHash(oracle common.Address, checkpoint *params.TrustedCheckpoint) []byte {
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], checkpoint.SectionIndex)
+
+	hash := checkpoint.Hash()
+	return crypto.Keccak256([]byte{0x19, 0x00}, oracle[:], index[:], hash[:])
+}
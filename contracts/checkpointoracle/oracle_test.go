@@ -0,0 +1,91 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package checkpointoracle
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that checkpoint signatures are only accepted if signed by at least the
+// threshold of trusted signers.
+func TestVerifySigners(t *testing.T) {
+	var (
+		keys    []*ecdsa.PrivateKey
+		signers []common.Address
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	outsider, _ := crypto.GenerateKey()
+
+	oracle := &CheckpointOracle{config: &params.CheckpointOracleConfig{
+		Address:   common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+		Signers:   signers,
+		Threshold: 2,
+	}}
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: 1,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	sign := func(keys ...*ecdsa.PrivateKey) ([]uint8, [][32]byte, [][32]byte) {
+		var (
+			v    []uint8
+			r, s [][32]byte
+		)
+		for _, key := range keys {
+			sig, err := crypto.Sign(SignatureHash(oracle.config.Address, checkpoint), key)
+			if err != nil {
+				t.Fatalf("failed to sign checkpoint: %v", err)
+			}
+			v = append(v, sig[64]+27)
+			r = append(r, common.BytesToHash(sig[:32]))
+			s = append(s, common.BytesToHash(sig[32:64]))
+		}
+		return v, r, s
+	}
+	tests := []struct {
+		keys []*ecdsa.PrivateKey
+		ok   bool
+	}{
+		{[]*ecdsa.PrivateKey{keys[0], keys[1]}, true},
+		{[]*ecdsa.PrivateKey{keys[0], keys[1], keys[2]}, true},
+		{[]*ecdsa.PrivateKey{keys[0]}, false},
+		{[]*ecdsa.PrivateKey{keys[0], keys[0]}, false},
+		{[]*ecdsa.PrivateKey{keys[0], outsider}, false},
+	}
+	for i, tt := range tests {
+		v, r, s := sign(tt.keys...)
+		if err := oracle.VerifySigners(checkpoint, v, r, s); (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+	// Ensure signatures over a different checkpoint are rejected
+	v, r, s := sign(keys[0], keys[1])
+	checkpoint.SectionIndex++
+	if err := oracle.VerifySigners(checkpoint, v, r, s); err == nil {
+		t.Errorf("signatures over a different checkpoint accepted")
+	}
+}
@@ -118,11 +118,12 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 	return nil
 }
 
-// CalcGasLimit computes the gas limit of the next block after parent.
+// CalcGasLimit computes the gas limit of the next block after parent. It aims
+// to keep the baseline gas between the provided floor and ceiling.
 // This is miner strategy, not consensus protocol.
 // CalcGasLimit 함수는 부모 다음 블록의 가스 한도를 계산한다
 // 이것은 마이닝 정책이지 합의 프로토콜이 아니다
-func CalcGasLimit(parent *types.Block) uint64 {
+func CalcGasLimit(parent *types.Block, gasFloor, gasCeil uint64) uint64 {
 	// contrib = (parentGasUsed * 3 / 2) / 1024
 	contrib := (parent.GasUsed() + parent.GasUsed()/2) / params.GasLimitBoundDivisor
 
@@ -144,13 +145,18 @@ func CalcGasLimit(parent *types.Block) uint64 {
 	if limit < params.MinGasLimit {
 		limit = params.MinGasLimit
 	}
-	// however, if we're now below the target (TargetGasLimit) we increase the
-	// limit as much as we can (parentGasLimit / 1024 -1)
+	// however, if we're now outside of the allowed range (gasFloor, gasCeil) we
+	// hone the limit towards it as much as we can (parentGasLimit / 1024 -1)
 	// 만약 타겟값보다 아래에 있다면, 가능한한 많이로 증가시킨다
-	if limit < params.TargetGasLimit {
+	if limit < gasFloor {
 		limit = parent.GasLimit() + decay
-		if limit > params.TargetGasLimit {
-			limit = params.TargetGasLimit
+		if limit > gasFloor {
+			limit = gasFloor
+		}
+	} else if limit > gasCeil {
+		limit = parent.GasLimit() - decay
+		if limit < gasCeil {
+			limit = gasCeil
 		}
 	}
 	return limit
@@ -197,3 +197,38 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 		t.Errorf("verification count too large: have %d, want below %d", verified, 2*threads)
 	}
 }
+
+// Tests that the gas limit of mined blocks is steered into the floor and ceiling
+// range, moving within it based on the gas used by the parent.
+func TestCalcGasLimit(t *testing.T) {
+	block := func(limit, used uint64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{GasLimit: limit, GasUsed: used})
+	}
+	var (
+		floor = uint64(4000000)
+		ceil  = uint64(8000000)
+		decay = func(limit uint64) uint64 { return limit/params.GasLimitBoundDivisor - 1 }
+	)
+	tests := []struct {
+		parent *types.Block
+		want   uint64
+	}{
+		// Below the floor, raise by the maximum allowed step
+		{block(3000000, 0), 3000000 + decay(3000000)},
+		// Just below the floor, don't overshoot it
+		{block(floor-1, 0), floor},
+		// Above the ceiling, lower by the maximum allowed step
+		{block(9000000, 9000000), 9000000 - decay(9000000)},
+		// Just above the ceiling, don't overshoot it
+		{block(ceil+1, ceil+1), ceil},
+		// Within the range, empty blocks decay towards the floor
+		{block(6000000, 0), 6000000 - decay(6000000)},
+		// Within the range, full blocks grow towards the ceiling
+		{block(6000000, 6000000), 6000000 - decay(6000000) + 6000000*3/2/params.GasLimitBoundDivisor},
+	}
+	for i, tt := range tests {
+		if have := CalcGasLimit(tt.parent, floor, ceil); have != tt.want {
+			t.Errorf("test %d: gas limit mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
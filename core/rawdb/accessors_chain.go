@@ -129,6 +129,27 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// ReadFastSyncStatus retrieves the serialized progress of an interrupted fast
+// sync to allow resuming it after a restart.
+func ReadFastSyncStatus(db DatabaseReader) []byte {
+	data, _ := db.Get(fastSyncStatusKey)
+	return data
+}
+
+// WriteFastSyncStatus stores the serialized progress of a running fast sync.
+func WriteFastSyncStatus(db DatabaseWriter, status []byte) {
+	if err := db.Put(fastSyncStatusKey, status); err != nil {
+		log.Crit("Failed to store fast sync status", "err", err)
+	}
+}
+
+// DeleteFastSyncStatus removes the fast sync progress once the sync completed.
+func DeleteFastSyncStatus(db DatabaseDeleter) {
+	if err := db.Delete(fastSyncStatusKey); err != nil {
+		log.Crit("Failed to delete fast sync status", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
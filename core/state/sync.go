@@ -40,3 +40,20 @@ func NewStateSync(root common.Hash, database trie.DatabaseReader) *trie.TrieSync
 	syncer = trie.NewTrieSync(root, database, callback)
 	return syncer
 }
+
+// NewStateHeal creates a state trie healing scheduler, retrieving the entries
+// missing from a partially imported state, even below locally known trie nodes.
+func NewStateHeal(root common.Hash, database trie.DatabaseReader) *trie.TrieSync {
+	syncer := trie.NewTrieHeal(database)
+	callback := func(leaf []byte, parent common.Hash) error {
+		var obj Account
+		if err := rlp.Decode(bytes.NewReader(leaf), &obj); err != nil {
+			return err
+		}
+		syncer.AddSubTrie(obj.Root, 64, parent, nil)
+		syncer.AddRawEntry(common.BytesToHash(obj.CodeHash), 64, parent)
+		return nil
+	}
+	syncer.AddSubTrie(root, 0, common.Hash{}, callback)
+	return syncer
+}
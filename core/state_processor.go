@@ -89,8 +89,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	// 블록을 최종화하고 합의 엔진 특성에 따른 엑스트라 데이터를 적용한다 (block rewards같은것)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
-
+	if _, err := p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts); err != nil {
+		return nil, nil, 0, err
+	}
 	return receipts, allLogs, *usedGas, nil
 }
 
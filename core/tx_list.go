@@ -378,6 +378,16 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 	return removed, invalids
 }
 
+// Slots returns the number of data slots occupied by the transactions in the
+// list, see numSlots.
+func (l *txList) Slots() int {
+	slots := 0
+	for _, tx := range l.txs.items {
+		slots += numSlots(tx)
+	}
+	return slots
+}
+
 // Cap places a hard limit on the number of items, returning all transactions
 // exceeding that limit.
 // itrem 수량의 한도를 설정하고 초과하는 모든 트렌젠션을 반환해버린다
@@ -481,9 +491,9 @@ func (h *priceHeap) Pop() interface{} {
 // contents in a price-incrementing way.
 // txPricedList는 트렌젝션 풀의 내용이 가격이 증가하는 형태로 동작하게 하기 위한 가격 정렬된 힙이다
 type txPricedList struct {
-	all    *map[common.Hash]*types.Transaction // Pointer to the map of all transactions
-	items  *priceHeap                          // Heap of prices of all the stored transactions
-	stales int                                 // Number of stale price points to (re-heap trigger)
+	all    *txLookup  // Pointer to the lookup of all transactions
+	items  *priceHeap // Heap of prices of all the stored transactions
+	stales int        // Number of stale price points to (re-heap trigger)
 	// 모든 트렌젝션의 맵에 대한 포인터
 	// 모든 저장된 트렌젝션의 가격 힙
 	// 오래된 가격을 가리키는 수
@@ -491,7 +501,7 @@ type txPricedList struct {
 
 // newTxPricedList creates a new price-sorted transaction heap.
 // newTxPricedList함수는 새롭게 가격정렬된 트렌젝션 힙을 생성한다
-func newTxPricedList(all *map[common.Hash]*types.Transaction) *txPricedList {
+func newTxPricedList(all *txLookup) *txPricedList {
 	return &txPricedList{
 		all:   all,
 		items: new(priceHeap),
@@ -519,12 +529,13 @@ func (l *txPricedList) Removed() {
 	}
 	// Seems we've reached a critical number of stale transactions, reheap
 	// 오래된 트렌젝션의 숫자가 임계수에 도달한것으로 보이니, reheap한다
-	reheap := make(priceHeap, 0, len(*l.all))
+	reheap := make(priceHeap, 0, l.all.Count())
 
 	l.stales, l.items = 0, &reheap
-	for _, tx := range *l.all {
+	l.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
 		*l.items = append(*l.items, tx)
-	}
+		return true
+	})
 	heap.Init(l.items)
 }
 
@@ -543,7 +554,7 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 		// Discard stale transactions if found during cleanup
 		// 클린업 하는동안 찾아진 오래된 트렌젝션들을 제거한다
 		tx := heap.Pop(l.items).(*types.Transaction)
-		if _, ok := (*l.all)[tx.Hash()]; !ok {
+		if l.all.Get(tx.Hash()) == nil {
 			l.stales--
 			continue
 		}
@@ -580,7 +591,7 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 	// 힙이 시작될때 찾아진 오래된 가격의 포인트들을 폐기한다
 	for len(*l.items) > 0 {
 		head := []*types.Transaction(*l.items)[0]
-		if _, ok := (*l.all)[head.Hash()]; !ok {
+		if l.all.Get(head.Hash()) == nil {
 			l.stales--
 			heap.Pop(l.items)
 			continue
@@ -598,21 +609,22 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
 }
 
-// Discard finds a number of most underpriced transactions, removes them from the
-// priced list and returns them for further removal from the entire pool.
+// Discard finds the most underpriced transactions occupying at least the given
+// number of slots, removes them from the priced list and returns them for further
+// removal from the entire pool.
 // Discard 함수는 가장 저렴한 트렌젝션의 갯수를 찾아서 가격 리스트에서 제거하고
 // 전체 풀에서 제거하기 위해 반환한다
-func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions {
-	drop := make(types.Transactions, 0, count) // Remote underpriced transactions to drop
+func (l *txPricedList) Discard(slots int, local *accountSet) types.Transactions {
+	drop := make(types.Transactions, 0, slots) // Remote underpriced transactions to drop
 	// 원격에 존재하는 드롭할 낮은 가격의 트렌젝션들
 	save := make(types.Transactions, 0, 64)    // Local underpriced transactions to keep
 	// 로컬에 존재하는 보유할 낮은 가격의 트렌젝션들
 
-	for len(*l.items) > 0 && count > 0 {
+	for len(*l.items) > 0 && slots > 0 {
 		// Discard stale transactions if found during cleanup
 		// 클린업 하는동안 찾아진 오래된 트렌젝션들을 제거한다
 		tx := heap.Pop(l.items).(*types.Transaction)
-		if _, ok := (*l.all)[tx.Hash()]; !ok {
+		if l.all.Get(tx.Hash()) == nil {
 			l.stales--
 			continue
 		}
@@ -622,7 +634,7 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 			save = append(save, tx)
 		} else {
 			drop = append(drop, tx)
-			count--
+			slots -= numSlots(tx)
 		}
 	}
 	for _, tx := range save {
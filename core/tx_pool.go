@@ -90,6 +90,25 @@ var (
 	ErrOversizedData = errors.New("oversized data")
 )
 
+const (
+	// txSlotSize is used to calculate how many data slots a single transaction
+	// takes up based on its size (one per started slot size). The slots are used
+	// as DoS protection, ensuring that validating a new transaction remains a
+	// constant operation (in reality O(maxslots), where max slots are 4 currently).
+	//
+	// The slot is a quarter of the maximum transaction size rather than the full
+	// 32KB: with the size capped at 32KB, full sized slots would charge every
+	// transaction a single slot, and large transactions could still use up to
+	// 4 times the memory of small ones under the same limits.
+	txSlotSize = 8 * 1024
+
+	// txMaxSize is the maximum size a single transaction can have. This field has
+	// non-trivial consequences: larger transactions are significantly harder and
+	// more expensive to propagate; larger transactions also take more resources
+	// to validate whether they fit into the pool or not.
+	txMaxSize = 4 * txSlotSize // 32KB
+)
+
 var (
 	evictionInterval    = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval = 8 * time.Second // Time interval to report transaction pool stats
@@ -247,11 +266,11 @@ type TxPool struct {
 	journal *txJournal  // Journal of local transaction to back up to disk
 	// 로컬트렌젝션을 디스크 백업할 저널
 
-	pending map[common.Address]*txList         // All currently processable transactions
-	queue   map[common.Address]*txList         // Queued but non-processable transactions
-	beats   map[common.Address]time.Time       // Last heartbeat from each known account
-	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
-	priced  *txPricedList                      // All transactions sorted by price
+	pending map[common.Address]*txList   // All currently processable transactions
+	queue   map[common.Address]*txList   // Queued but non-processable transactions
+	beats   map[common.Address]time.Time // Last heartbeat from each known account
+	all     *txLookup                    // All transactions to allow lookups
+	priced  *txPricedList                // All transactions sorted by price
 	// 현재까지 처리된 트렌젝션들
 	// 큐잉되었으나 처리불가능한 트렌젝션들
 	// 각 계정의 마지막 하트비트
@@ -281,12 +300,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
-		all:         make(map[common.Hash]*types.Transaction),
+		all:         newTxLookup(),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
-	pool.priced = newTxPricedList(&pool.all)
+	pool.priced = newTxPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -596,6 +615,23 @@ func (pool *TxPool) stats() (int, int) {
 	return pending, queued
 }
 
+// Slots retrieves the number of data slots occupied by the pending and the queued
+// (non-executable) transactions, which the pool limits are enforced on.
+func (pool *TxPool) Slots() (int, int) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	pending := 0
+	for _, list := range pool.pending {
+		pending += list.Slots()
+	}
+	queued := 0
+	for _, list := range pool.queue {
+		queued += list.Slots()
+	}
+	return pending, queued
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
 // Contents 함수는 트렌젝션풀의 데이터 컨텐츠를 반환하고, 
@@ -654,10 +690,10 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx함수는 트렌젝션이 합의룰에 따라 유효한지 체크하고
 // 가격과 크기가 로컬노드에 부합한지 확인한다
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
-	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
+	// Reject transactions over defined size to prevent DOS attacks
 	// 휴리스틱하게 정해진 한도로 32KB가 넘는 트렌젝션은 
 	// DOS attck을 방지하기 위해 거절한다
-	if tx.Size() > 32*1024 {
+	if tx.Size() > txMaxSize {
 		return ErrOversizedData
 	}
 	// Transactions can't be negative. This may never happen using RLP decoded
@@ -724,7 +760,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	// If the transaction is already known, discard it
 	// 이미 알려진 트렌젝션이라면 처리하지 않음
 	hash := tx.Hash()
-	if pool.all[hash] != nil {
+	if pool.all.Get(hash) != nil {
 		log.Trace("Discarding already known transaction", "hash", hash)
 		return false, fmt.Errorf("known transaction: %x", hash)
 	}
@@ -737,7 +773,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	}
 	// If the transaction pool is full, discard underpriced transactions
 	// 트렌젝션 풀이 가득찾다면 낮은 가격의 트렌젝션을 버린다
-	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
 		// 새트렌젝션이 낮은가겨이라면 수락하지 않음
 		if !local && pool.priced.Underpriced(tx, pool.locals) {
@@ -747,7 +783,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		}
 		// New transaction is better than our worse ones, make room for it
 		// 새 트렌젝션이 기존것보다 좋을경우 공간을 만든다
-		drop := pool.priced.Discard(len(pool.all)-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
+		drop := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), pool.locals)
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
@@ -768,11 +804,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		// New transaction is better, replace old one
 		// 새 트렌젝션이 더 좋으므로 기존것을 대체
 		if old != nil {
-			delete(pool.all, old.Hash())
+			pool.all.Remove(old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
 		}
-		pool.all[tx.Hash()] = tx
+		pool.all.Add(tx)
 		pool.priced.Put(tx)
 		pool.journalTx(from, tx)
 
@@ -824,12 +860,12 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	// Discard any previous transaction and mark this
 	// 기존 트렌젝션을 배제하고 마킹한다
 	if old != nil {
-		delete(pool.all, old.Hash())
+		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
 	}
-	if pool.all[hash] == nil {
-		pool.all[hash] = tx
+	if pool.all.Get(hash) == nil {
+		pool.all.Add(tx)
 		pool.priced.Put(tx)
 	}
 	return old != nil, nil
@@ -868,7 +904,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	if !inserted {
 		// An older transaction was better, discard this
 		// 기존의 것이 더 좋으니 무시
-		delete(pool.all, hash)
+		pool.all.Remove(hash)
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
@@ -877,15 +913,15 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	// Otherwise discard any previous transaction and mark this
 	// 아니라면 기존의 트렌젝션을 제외하고 마킹한다
 	if old != nil {
-		delete(pool.all, old.Hash())
+		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	// 테스트를 위한 워크 어라운드
-	if pool.all[hash] == nil {
-		pool.all[hash] = tx
+	if pool.all.Get(hash) == nil {
+		pool.all.Add(tx)
 		pool.priced.Put(tx)
 	}
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
@@ -1003,7 +1039,7 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 
 	status := make([]TxStatus, len(hashes))
 	for i, hash := range hashes {
-		if tx := pool.all[hash]; tx != nil {
+		if tx := pool.all.Get(hash); tx != nil {
 			from, _ := types.Sender(pool.signer, tx) // already validated
 			// 이미 검증됨
 			if pool.pending[from] != nil && pool.pending[from].txs.items[tx.Nonce()] != nil {
@@ -1023,7 +1059,16 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
-	return pool.all[hash]
+	return pool.all.Get(hash)
+}
+
+// Arrival returns the time a transaction was added to the pool, or the zero time
+// if it is not contained in the pool.
+func (pool *TxPool) Arrival(hash common.Hash) time.Time {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.all.Seen(hash)
 }
 
 // removeTx removes a single transaction from the queue, moving all subsequent
@@ -1033,8 +1078,8 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 	// Fetch the transaction we wish to delete
 	// 삭제를 원하는 트렌젝션을 가져옴
-	tx, ok := pool.all[hash]
-	if !ok {
+	tx := pool.all.Get(hash)
+	if tx == nil {
 		return
 	}
 	addr, _ := types.Sender(pool.signer, tx) // already validated during insertion
@@ -1042,7 +1087,7 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 
 	// Remove it from the list of known transactions
 	// 알려진 트렌젝션 리스트에서 제거
-	delete(pool.all, hash)
+	pool.all.Remove(hash)
 	if outofbound {
 		pool.priced.Removed()
 	}
@@ -1110,7 +1155,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range list.Forward(pool.currentState.GetNonce(addr)) {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
-			delete(pool.all, hash)
+			pool.all.Remove(hash)
 			pool.priced.Removed()
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1119,7 +1164,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
-			delete(pool.all, hash)
+			pool.all.Remove(hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
 		}
@@ -1137,7 +1182,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		if !pool.locals.contains(addr) {
 			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
 				hash := tx.Hash()
-				delete(pool.all, hash)
+				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
@@ -1158,10 +1203,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 	// 대기한도가 넘어서면, 허용수치로 맞춘다
 	pending := uint64(0)
 	for _, list := range pool.pending {
-		pending += uint64(list.Len())
+		pending += uint64(list.Slots())
 	}
 	if pending > pool.config.GlobalSlots {
-		pendingBeforeCap := pending
 		// Assemble a spam order to penalize large transactors first
 		// 큰 트렌젝터를 먼저 처벌하기 위한 스팸오더를 작성한다
 		spammers := prque.New()
@@ -1197,7 +1241,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							// Drop the transaction from the global pools too
 							// 전체풀에서도 트렌젝션을 제거한다
 							hash := tx.Hash()
-							delete(pool.all, hash)
+							pool.all.Remove(hash)
 							pool.priced.Removed()
 
 							// Update the account nonce to the dropped transaction
@@ -1206,8 +1250,10 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 								pool.pendingState.SetNonce(offenders[i], nonce)
 							}
 							log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+
+							pending -= uint64(numSlots(tx))
+							pendingRateLimitCounter.Inc(1)
 						}
-						pending--
 					}
 				}
 			}
@@ -1222,7 +1268,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						// Drop the transaction from the global pools too
 						// 전체풀에서 트렌젝션을 제거한다
 						hash := tx.Hash()
-						delete(pool.all, hash)
+						pool.all.Remove(hash)
 						pool.priced.Removed()
 
 						// Update the account nonce to the dropped transaction
@@ -1231,18 +1277,19 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							pool.pendingState.SetNonce(addr, nonce)
 						}
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+
+						pending -= uint64(numSlots(tx))
+						pendingRateLimitCounter.Inc(1)
 					}
-					pending--
 				}
 			}
 		}
-		pendingRateLimitCounter.Inc(int64(pendingBeforeCap - pending))
 	}
 	// If we've queued more transactions than the hard limit, drop oldest ones
 	// 하드 리밋보다 많은 트렌젝션이 큐잉되면 오래된것부터 제거한다
 	queued := uint64(0)
 	for _, list := range pool.queue {
-		queued += uint64(list.Len())
+		queued += uint64(list.Slots())
 	}
 	if queued > pool.config.GlobalQueue {
 		// Sort all accounts with queued transactions by heartbeat
@@ -1265,12 +1312,13 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 
 			// Drop all transactions if they are less than the overflow
 			// overflow가 안날때까지 모든 트렌젝션을 드롭한다
-			if size := uint64(list.Len()); size <= drop {
-				for _, tx := range list.Flatten() {
+			if size := uint64(list.Slots()); size <= drop {
+				txs := list.Flatten()
+				for _, tx := range txs {
 					pool.removeTx(tx.Hash(), true)
 				}
 				drop -= size
-				queuedRateLimitCounter.Inc(int64(size))
+				queuedRateLimitCounter.Inc(int64(len(txs)))
 				continue
 			}
 			// Otherwise drop only last few transactions
@@ -1278,7 +1326,11 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash(), true)
-				drop--
+				if slots := uint64(numSlots(txs[i])); slots < drop {
+					drop -= slots
+				} else {
+					drop = 0
+				}
 				queuedRateLimitCounter.Inc(1)
 			}
 		}
@@ -1301,7 +1353,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range list.Forward(nonce) {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
-			delete(pool.all, hash)
+			pool.all.Remove(hash)
 			pool.priced.Removed()
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1310,7 +1362,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
-			delete(pool.all, hash)
+			pool.all.Remove(hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
 		}
@@ -1391,3 +1443,78 @@ func (as *accountSet) containsTx(tx *types.Transaction) bool {
 func (as *accountSet) add(addr common.Address) {
 	as.accounts[addr] = struct{}{}
 }
+
+// txLookup is used internally by TxPool to track transactions while allowing
+// lookup by hash, keeping count of the data slots they occupy and the time they
+// arrived at.
+//
+// Note, the lookup is not thread safe, it is protected by the pool lock.
+type txLookup struct {
+	all   map[common.Hash]*types.Transaction
+	seen  map[common.Hash]time.Time
+	slots int
+}
+
+// newTxLookup returns a new txLookup structure.
+func newTxLookup() *txLookup {
+	return &txLookup{
+		all:  make(map[common.Hash]*types.Transaction),
+		seen: make(map[common.Hash]time.Time),
+	}
+}
+
+// Range calls f on each key and value present in the map.
+func (t *txLookup) Range(f func(hash common.Hash, tx *types.Transaction) bool) {
+	for key, value := range t.all {
+		if !f(key, value) {
+			break
+		}
+	}
+}
+
+// Get returns a transaction if it exists in the lookup, or nil if not found.
+func (t *txLookup) Get(hash common.Hash) *types.Transaction {
+	return t.all[hash]
+}
+
+// Seen returns the time a transaction was added to the lookup, or the zero time
+// if it's not found.
+func (t *txLookup) Seen(hash common.Hash) time.Time {
+	return t.seen[hash]
+}
+
+// Count returns the current number of transactions in the lookup.
+func (t *txLookup) Count() int {
+	return len(t.all)
+}
+
+// Slots returns the current number of data slots used in the lookup.
+func (t *txLookup) Slots() int {
+	return t.slots
+}
+
+// Add adds a transaction to the lookup.
+func (t *txLookup) Add(tx *types.Transaction) {
+	hash := tx.Hash()
+	if old := t.all[hash]; old != nil {
+		t.slots -= numSlots(old)
+	} else {
+		t.seen[hash] = time.Now()
+	}
+	t.slots += numSlots(tx)
+	t.all[hash] = tx
+}
+
+// Remove removes a transaction from the lookup.
+func (t *txLookup) Remove(hash common.Hash) {
+	if tx := t.all[hash]; tx != nil {
+		t.slots -= numSlots(tx)
+		delete(t.all, hash)
+		delete(t.seen, hash)
+	}
+}
+
+// numSlots calculates the number of slots needed for a single transaction.
+func numSlots(tx *types.Transaction) int {
+	return int((tx.Size() + txSlotSize - 1) / txSlotSize)
+}
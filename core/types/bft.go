@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// BFTExtraVanity is the fixed number of extra-data prefix bytes of the blocks
+	// sealed by the BFT consensus engine, reserved for validator vanity.
+	BFTExtraVanity = 32
+)
+
+var (
+	// BFTMixDigest is the fixed mix digest identifying the blocks sealed by the
+	// BFT consensus engine.
+	BFTMixDigest = crypto.Keccak256Hash([]byte("byzantine fault tolerant proof-of-authority"))
+
+	// ErrInvalidBFTExtra is returned if the extra-data of a BFT block can't be
+	// decoded.
+	ErrInvalidBFTExtra = errors.New("invalid bft extra-data")
+)
+
+// BFTExtra is the BFT specific part of the header extra-data, following the
+// vanity prefix.
+type BFTExtra struct {
+	Validators    []common.Address // Validator set on checkpoint blocks
+	Seal          []byte           // Signature of the proposer
+	CommittedSeal [][]byte         // Signatures of the validators committing the block
+}
+
+// ExtractBFTExtra extracts the BFT specific fields from the extra-data of a header.
+func ExtractBFTExtra(h *Header) (*BFTExtra, error) {
+	if len(h.Extra) < BFTExtraVanity {
+		return nil, ErrInvalidBFTExtra
+	}
+	extra := new(BFTExtra)
+	if err := rlp.DecodeBytes(h.Extra[BFTExtraVanity:], extra); err != nil {
+		return nil, ErrInvalidBFTExtra
+	}
+	return extra, nil
+}
+
+// EncodeBFTExtra assembles the extra-data of a header from the vanity and the BFT
+// specific fields.
+func EncodeBFTExtra(vanity []byte, extra *BFTExtra) ([]byte, error) {
+	blob, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		return nil, err
+	}
+	if len(vanity) < BFTExtraVanity {
+		vanity = append(vanity, bytes.Repeat([]byte{0x00}, BFTExtraVanity-len(vanity))...)
+	}
+	return append(append([]byte{}, vanity[:BFTExtraVanity]...), blob...), nil
+}
+
+// BFTFilteredHeader returns a copy of the header with the committed seals, and
+// if requested the proposer seal too, removed from the extra-data.
+func BFTFilteredHeader(h *Header, keepSeal bool) *Header {
+	cpy := CopyHeader(h)
+
+	extra, err := ExtractBFTExtra(h)
+	if err != nil {
+		return cpy
+	}
+	if !keepSeal {
+		extra.Seal = []byte{}
+	}
+	extra.CommittedSeal = [][]byte{}
+
+	cpy.Extra, _ = EncodeBFTExtra(h.Extra[:BFTExtraVanity], extra)
+	return cpy
+}
+
+// bftHash returns the hash of a BFT header without its committed seals. Every
+// validator assembles the committed block from the seals it collected itself,
+// so the seals are left out to keep the hash identical on all of them.
+func bftHash(h *Header) common.Hash {
+	extra, err := ExtractBFTExtra(h)
+	if err != nil || len(extra.CommittedSeal) == 0 {
+		return rlpHash(h)
+	}
+	return rlpHash(BFTFilteredHeader(h, true))
+}
@@ -104,9 +104,12 @@ type headerMarshaling struct {
 }
 
 // Hash returns the block hash of the header, which is simply the keccak256 hash of its
-// RLP encoding.
+// RLP encoding. The committed seals of BFT blocks are not part of the hash.
 // 해쉬함수는 RLP encoding된 헤더의 keccak256 블록해시를 반환한다
 func (h *Header) Hash() common.Hash {
+	if h.MixDigest == BFTMixDigest {
+		return bftHash(h)
+	}
 	return rlpHash(h)
 }
 
@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -76,7 +77,7 @@ type PublicMinerAPI struct {
 
 // NewPublicMinerAPI create a new PublicMinerAPI instance.
 func NewPublicMinerAPI(e *Ethereum) *PublicMinerAPI {
-	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine())
+	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine(), e.config.MinerNotify)
 	e.Miner().Register(agent)
 
 	return &PublicMinerAPI{e, agent}
@@ -195,6 +196,22 @@ func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	return true
 }
 
+// SetGasLimit sets the range the gas limit of the mined blocks is kept in, moving
+// towards the floor while the blocks are mostly empty and towards the ceiling
+// while they are full.
+func (api *PrivateMinerAPI) SetGasLimit(floor, ceil hexutil.Uint64) (bool, error) {
+	if err := api.e.Miner().SetGasLimit(uint64(floor), uint64(ceil)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetRecommitInterval updates the minimum interval of recreating the sealing
+// work with the newly arrived transactions, in milliseconds.
+func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
+	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
+}
+
 // SetEtherbase sets the etherbase of the miner
 func (api *PrivateMinerAPI) SetEtherbase(etherbase common.Address) bool {
 	api.e.SetEtherbase(etherbase)
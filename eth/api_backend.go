@@ -186,6 +186,10 @@ func (b *EthAPIBackend) Stats() (pending int, queued int) {
 	return b.eth.txPool.Stats()
 }
 
+func (b *EthAPIBackend) TxPoolSlots() (pending int, queued int) {
+	return b.eth.txPool.Slots()
+}
+
 func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	return b.eth.TxPool().Content()
 }
@@ -27,9 +27,11 @@ import (
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -37,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -56,6 +59,7 @@ type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
+	APIs() []rpc.API
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 }
 
@@ -80,6 +84,7 @@ type Ethereum struct {
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
+	signer         *external.Signer // External signer to seal clique blocks with (nil = local accounts)
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	//블록을 받아들일때 동작함
@@ -143,6 +148,16 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 	log.Info("Initialising Ethereum protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
+	if config.ExternalSigner != "" {
+		if chainConfig.Clique == nil {
+			return nil, errors.New("external signer is only supported for clique sealing")
+		}
+		if eth.signer, err = external.NewSigner(config.ExternalSigner); err != nil {
+			return nil, fmt.Errorf("failed to connect to external signer: %v", err)
+		}
+		log.Info("Sealing clique blocks with external signer", "endpoint", config.ExternalSigner)
+	}
+
 	if !config.SkipBcVersionCheck {
 		bcVersion := rawdb.ReadDatabaseVersion(chainDb)
 		if bcVersion != core.BlockChainVersion && bcVersion != 0 {
@@ -173,6 +188,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	// 인덱서시작
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if engine, ok := eth.engine.(*clique.Clique); ok {
+		events := make(chan core.ChainEvent, 16)
+		go eth.cliqueLivenessLoop(engine, events, eth.blockchain.SubscribeChainEvent(events))
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
@@ -184,14 +204,46 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	// Qos 튜너는 산발적으로 피어들의 지연속도를 모아 예측시간을 업데이트 한다
 	// statefetcher는 피어 일동의 active state 동기화 및 요청 수락을 관리한다
 	// 해쉬 어나운스먼트를 베이스로 블록을 검색하는 블록패쳐를 만든다
-	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
+	checkpoint := config.Checkpoint
+	if checkpoint == nil {
+		checkpoint = params.TrustedCheckpoints[genesisHash]
+	}
+	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, checkpoint); err != nil {
 		return nil, err
 	}
 
 	// 이함수는 다운로더 이벤트를 트랙킹한다. (한번짜리)
 	// 체인이 sync되었는지, 실패했는지 확인한다.
+	if config.MinerGasFloor < params.MinGasLimit {
+		log.Warn("Sanitizing invalid miner gas floor", "provided", config.MinerGasFloor, "updated", DefaultConfig.MinerGasFloor)
+		config.MinerGasFloor = DefaultConfig.MinerGasFloor
+	}
+	if config.MinerGasCeil < config.MinerGasFloor {
+		ceil := DefaultConfig.MinerGasCeil
+		if ceil < config.MinerGasFloor {
+			ceil = config.MinerGasFloor
+		}
+		log.Warn("Sanitizing invalid miner gas ceiling", "provided", config.MinerGasCeil, "updated", ceil)
+		config.MinerGasCeil = ceil
+	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
+	eth.miner.SetRecommitInterval(config.MinerRecommit)
+	if err := eth.miner.SetGasLimit(config.MinerGasFloor, config.MinerGasCeil); err != nil {
+		return nil, err
+	}
+	if config.MinerTxOrder != "" {
+		if err := eth.miner.SetTxOrdering(config.MinerTxOrder); err != nil {
+			return nil, err
+		}
+	}
+	if config.MinerStratum != "" {
+		agent, err := miner.NewStratumAgent(eth.blockchain, eth.engine, config.MinerStratum, config.MinerStratumDiff)
+		if err != nil {
+			return nil, err
+		}
+		eth.miner.Register(agent)
+	}
 
 	eth.APIBackend = &EthAPIBackend{eth, nil}
 	gpoParams := config.GPO
@@ -246,6 +298,9 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *ethash.Config, chai
 	if chainConfig.Clique != nil {
 		return clique.New(chainConfig.Clique, db)
 	}
+	if chainConfig.BFT != nil {
+		return bft.New(chainConfig.BFT, db)
+	}
 	// Otherwise assume proof-of-work
 	switch {
 	case config.PowMode == ethash.ModeFake:
@@ -279,6 +334,11 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the APIs of the light server if it's running
+	if s.lesServer != nil {
+		apis = append(apis, s.lesServer.APIs()...)
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -391,12 +451,27 @@ func (s *Ethereum) StartMining(local bool) error {
 	}
 	//PoA일단 패스
 	if clique, ok := s.engine.(*clique.Clique); ok {
+		if s.signer != nil {
+			clique.Authorize(eb, s.signer.SignData)
+		} else {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("signer missing: %v", err)
+			}
+			clique.Authorize(eb, func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+				return wallet.SignHash(account, crypto.Keccak256(data))
+			})
+		}
+	}
+	if engine, ok := s.engine.(*bft.BFT); ok {
 		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
 		if wallet == nil || err != nil {
 			log.Error("Etherbase account unavailable locally", "err", err)
-			return fmt.Errorf("signer missing: %v", err)
+			return fmt.Errorf("validator missing: %v", err)
 		}
-		clique.Authorize(eb, wallet.SignHash)
+		engine.Authorize(eb, wallet.SignHash)
+		engine.Start(s.blockchain, s.validateBlock, s.commitBlock)
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -409,7 +484,59 @@ func (s *Ethereum) StartMining(local bool) error {
 	return nil
 }
 
-func (s *Ethereum) StopMining()         { s.miner.Stop() }
+// cliqueLivenessLoop feeds the blocks inserted into the canonical chain to the
+// clique engine to track the liveness of its signers, until the chain stops.
+func (s *Ethereum) cliqueLivenessLoop(engine *clique.Clique, events chan core.ChainEvent, sub event.Subscription) {
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-events:
+			engine.InsertedBlock(s.blockchain, ev.Block.Header())
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// validateBlock fully verifies a block proposed to the BFT validators, executing
+// its transactions on top of the parent state, before the validator agrees on it.
+func (s *Ethereum) validateBlock(block *types.Block) error {
+	parent := s.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if err := s.blockchain.Validator().ValidateBody(block); err != nil {
+		return err
+	}
+	statedb, err := s.blockchain.StateAt(parent.Root())
+	if err != nil {
+		return err
+	}
+	receipts, _, usedGas, err := s.blockchain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		return err
+	}
+	return s.blockchain.Validator().ValidateState(block, parent, statedb, receipts, usedGas)
+}
+
+// commitBlock imports a block agreed on by the BFT validators and announces it
+// to the network like a locally mined one.
+func (s *Ethereum) commitBlock(block *types.Block) error {
+	if _, err := s.blockchain.InsertChain(types.Blocks{block}); err != nil {
+		return err
+	}
+	s.eventMux.Post(core.NewMinedBlockEvent{Block: block})
+	return nil
+}
+
+func (s *Ethereum) StopMining() {
+	if engine, ok := s.engine.(*bft.BFT); ok {
+		engine.Stop()
+	}
+	s.miner.Stop()
+}
+
 func (s *Ethereum) IsMining() bool      { return s.miner.Mining() }
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
@@ -424,13 +551,24 @@ func (s *Ethereum) EthVersion() int                    { return int(s.protocolMa
 func (s *Ethereum) NetVersion() uint64                 { return s.networkId }
 func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// SetPeerScorer replaces the policy used to score the behaviour of remote peers,
+// influencing which peers are kept, dropped and dialed. A nil scorer restores
+// the default policy.
+func (s *Ethereum) SetPeerScorer(scorer PeerScorer) {
+	s.protocolManager.SetPeerScorer(scorer)
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
+	protos := append([]p2p.Protocol{}, s.protocolManager.SubProtocols...)
+	if engine, ok := s.engine.(*bft.BFT); ok {
+		protos = append(protos, engine.Protocols()...)
+	}
 	if s.lesServer == nil {
-		return s.protocolManager.SubProtocols
+		return protos
 	}
-	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+	return append(protos, s.lesServer.Protocols()...)
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -462,6 +600,9 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
 	s.bloomIndexer.Close()
+	if engine, ok := s.engine.(*bft.BFT); ok {
+		engine.Stop()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -471,6 +612,9 @@ func (s *Ethereum) Stop() error {
 	s.miner.Stop()
 	s.eventMux.Stop()
 
+	if s.signer != nil {
+		s.signer.Close()
+	}
 	s.chainDb.Close()
 	close(s.shutdownChan)
 
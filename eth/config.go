@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -53,6 +54,11 @@ var DefaultConfig = Config{
 	TrieCache:     256,
 	TrieTimeout:   5 * time.Minute,
 	GasPrice:      big.NewInt(18 * params.Shannon),
+	MinerGasFloor: params.GenesisGasLimit,
+	MinerGasCeil:  8000000,
+	MinerRecommit: miner.DefaultRecommitInterval,
+
+	MinerStratumDiff: miner.DefaultStratumDifficulty,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -90,8 +96,16 @@ type Config struct {
 	NoPruning bool
 
 	// Light client options
-	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
-	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
+	LightServ  int    `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
+	LightPeers int    `toml:",omitempty"` // Maximum number of LES client peers
+	LightState uint64 `toml:",omitempty"` // Number of recent blocks whose state is served to LES clients (0 = all available)
+
+	// Checkpoint options
+	Checkpoint       *params.TrustedCheckpoint      `toml:",omitempty"` // Trusted checkpoint to sync from (nil = built-in checkpoint of the network)
+	CheckpointOracle *params.CheckpointOracleConfig `toml:",omitempty"` // Oracle contract to retrieve newer signed checkpoints from
+
+	// Ultra light client options
+	ULC *ULCConfig `toml:",omitempty"`
 
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
@@ -101,10 +115,23 @@ type Config struct {
 	TrieTimeout        time.Duration
 
 	// Mining-related options
-	Etherbase    common.Address `toml:",omitempty"`
-	MinerThreads int            `toml:",omitempty"`
-	ExtraData    []byte         `toml:",omitempty"`
-	GasPrice     *big.Int
+	Etherbase     common.Address `toml:",omitempty"`
+	MinerThreads  int            `toml:",omitempty"`
+	ExtraData     []byte         `toml:",omitempty"`
+	GasPrice      *big.Int
+	MinerTxOrder  string        `toml:",omitempty"` // Transaction ordering policy of the mined blocks
+	MinerGasFloor uint64        // Target gas limit of the mined blocks when they are empty
+	MinerGasCeil  uint64        // Target gas limit of the mined blocks when they are full
+	MinerRecommit time.Duration // Minimum interval of recreating the sealing work with new transactions
+	MinerNotify   []string      `toml:",omitempty"` // HTTP URLs to push the new work packages of remote miners to
+
+	// External clef signer to seal clique blocks with, an HTTP or websocket URL or
+	// the path of an IPC socket (local accounts if empty)
+	ExternalSigner string `toml:",omitempty"`
+
+	// Stratum mining server options
+	MinerStratum     string  `toml:",omitempty"` // Listening address of the stratum server (disabled if empty)
+	MinerStratumDiff float64 // Initial share difficulty of the stratum workers
 
 	// Ethash options
 	Ethash ethash.Config
@@ -122,6 +149,18 @@ type Config struct {
 	DocRoot string `toml:"-"`
 }
 
+// DefaultULCMinTrustedFraction is the percentage of trusted servers that need to
+// announce a head before an ultra light client accepts it, unless configured.
+const DefaultULCMinTrustedFraction = 75
+
+// ULCConfig configures the ultra light client mode, in which the client skips
+// verifying headers and accepts new heads once enough trusted servers have
+// announced them.
+type ULCConfig struct {
+	TrustedServers     []string `toml:",omitempty"` // Enode URLs of the trusted LES servers
+	MinTrustedFraction int      `toml:",omitempty"` // Minimum percentage of trusted servers announcing a head
+}
+
 type configMarshaling struct {
 	ExtraData hexutil.Bytes
 }
@@ -72,6 +72,7 @@ var (
 	errBusy                    = errors.New("busy")
 	errUnknownPeer             = errors.New("peer is unknown or unhealthy")
 	errBadPeer                 = errors.New("action from bad peer ignored")
+	errUnsyncedPeer            = errors.New("unsynced peer")
 	errStallingPeer            = errors.New("peer is stalling")
 	errNoPeers                 = errors.New("no peers to keep download active")
 	errTimeout                 = errors.New("timeout")
@@ -101,6 +102,11 @@ type Downloader struct {
 	peers   *peerSet // Set of active peers from which download can proceed
 	stateDB ethdb.Database
 
+	checkpoint     uint64       // Checkpoint block number to enforce head against (e.g. fast sync)
+	checkpointHash common.Hash  // Hash of the checkpoint block to enforce the chain against
+	checkpointLock sync.RWMutex // Lock protecting the checkpoint, updated by oracles
+	genesis        uint64       // Genesis block number to limit sync to (e.g. light client CHT)
+
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
@@ -110,11 +116,15 @@ type Downloader struct {
 	syncStatsState       stateSyncStats
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
+	status     *syncStatus  // Fast sync progress persisted to resume after restarts
+	statusLock sync.RWMutex // Lock protecting the persisted sync progress
+
 	lightchain LightChain
 	blockchain BlockChain
 
 	// Callbacks
-	dropPeer peerDropFn // Drops a peer for misbehaving
+	dropPeer   peerDropFn   // Drops a peer for misbehaving
+	peerReport peerReportFn // Reports the quality of a peer's responses
 
 	// Status
 	synchroniseMock func(id string, hash common.Hash) error // Replacement for synchronise during testing
@@ -135,6 +145,13 @@ type Downloader struct {
 	trackStateReq  chan *stateReq
 	stateCh        chan dataPack // [eth/63] Channel receiving inbound node state data
 
+	// for snap sync
+	snapPeers map[string]SnapPeer // Set of peers speaking the snapshot protocol
+	snapSync  *rangeSync          // Currently running state range sync, if any
+	snapTasks []*accountTask      // Account range progress of the snap sync
+	snapRoot  common.Hash         // State root the account range progress belongs to
+	snapLock  sync.RWMutex        // Lock protecting the snap peers and active range sync
+
 	// Cancellation and termination
 	cancelPeer string         // Identifier of the peer currently being used as the master (cancel on drop)
 	cancelCh   chan struct{}  // Channel to cancel mid-flight syncs
@@ -199,8 +216,16 @@ type BlockChain interface {
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
+// If a trusted checkpoint is given, peers not on the checkpointed chain are
+// rejected.
+//
+// Light clients start syncing at the checkpoint, with the light chain proving
+// its head header against the trusted CHT root. Fast sync still downloads and
+// verifies the header chain from genesis: a full node needs the blocks below
+// the checkpoint anyway, and eth peers can't prove the total difficulty of the
+// checkpoint. The checkpoint is enforced on the synced chain instead.
 // 해쉬나 블록을 원격피어로 부터 가져오는 다운로더를 만든다
-func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn) *Downloader {
+func New(checkpoint *params.TrustedCheckpoint, mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn, peerReport peerReportFn) *Downloader {
 	if lightchain == nil {
 		lightchain = chain
 	}
@@ -216,6 +241,7 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 		blockchain:     chain,
 		lightchain:     lightchain,
 		dropPeer:       dropPeer,
+		peerReport:     peerReport,
 		headerCh:       make(chan dataPack, 1),
 		bodyCh:         make(chan dataPack, 1),
 		receiptCh:      make(chan dataPack, 1),
@@ -229,6 +255,19 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 			processed: rawdb.ReadFastTrieProgress(stateDb),
 		},
 		trackStateReq: make(chan *stateReq),
+		snapPeers:     make(map[string]SnapPeer),
+	}
+	if checkpoint != nil {
+		dl.SetCheckpoint(checkpoint)
+	}
+	// Pick up the progress of any fast sync interrupted by a restart
+	if status := readSyncStatus(stateDb); status != nil {
+		log.Info("Resuming interrupted fast sync", "origin", status.Origin, "pivot", status.Pivot, "root", status.Root)
+		dl.status = status
+		dl.syncStatsChainOrigin, dl.syncStatsChainHeight = status.Origin, status.Height
+		if len(status.Tasks) > 0 {
+			dl.snapTasks, dl.snapRoot = status.Tasks, status.Root
+		}
 	}
 	// Qos 튜너는 산발적으로 피어들의 지연속도를 모아 예측시간을 업데이트 한다
 	go dl.qosTuner()
@@ -237,6 +276,26 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 	return dl
 }
 
+// SetCheckpoint updates the trusted checkpoint enforced on the synced chain, if
+// the given one is newer than the current.
+func (d *Downloader) SetCheckpoint(checkpoint *params.TrustedCheckpoint) {
+	d.checkpointLock.Lock()
+	defer d.checkpointLock.Unlock()
+
+	if number := checkpoint.HeadNumber(); number > d.checkpoint {
+		d.checkpoint, d.checkpointHash = number, checkpoint.SectionHead
+	}
+}
+
+// trustedCheckpoint returns the number and hash of the trusted checkpoint, or
+// zero if none was set.
+func (d *Downloader) trustedCheckpoint() (uint64, common.Hash) {
+	d.checkpointLock.RLock()
+	defer d.checkpointLock.RUnlock()
+
+	return d.checkpoint, d.checkpointHash
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -260,7 +319,7 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 	switch d.mode {
 	case FullSync:
 		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
+	case FastSync, SnapSync:
 		current = d.blockchain.CurrentFastBlock().NumberU64()
 	case LightSync:
 		current = d.lightchain.CurrentHeader().Number.Uint64()
@@ -271,6 +330,7 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
+		PivotBlock:    d.syncPivot(),
 	}
 }
 
@@ -298,6 +358,41 @@ func (d *Downloader) RegisterLightPeer(id string, version int, peer LightPeer) e
 	return d.RegisterPeer(id, version, &lightPeerWrapper{peer})
 }
 
+// RegisterSnapPeer injects a new snapshot protocol peer into the set of sources
+// state ranges can be retrieved from during snap sync.
+func (d *Downloader) RegisterSnapPeer(id string, peer SnapPeer) error {
+	d.snapLock.Lock()
+	defer d.snapLock.Unlock()
+
+	if _, ok := d.snapPeers[id]; ok {
+		return errAlreadyRegistered
+	}
+	log.Trace("Registering snap sync peer", "peer", id)
+	d.snapPeers[id] = peer
+	return nil
+}
+
+// UnregisterSnapPeer removes a snapshot protocol peer from the known list.
+func (d *Downloader) UnregisterSnapPeer(id string) error {
+	d.snapLock.Lock()
+	defer d.snapLock.Unlock()
+
+	if _, ok := d.snapPeers[id]; !ok {
+		return errNotRegistered
+	}
+	log.Trace("Unregistering snap sync peer", "peer", id)
+	delete(d.snapPeers, id)
+	return nil
+}
+
+// snapPeer retrieves the registered snapshot protocol peer with the given id.
+func (d *Downloader) snapPeer(id string) SnapPeer {
+	d.snapLock.RLock()
+	defer d.snapLock.RUnlock()
+
+	return d.snapPeers[id]
+}
+
 // UnregisterPeer remove a peer from the known list, preventing any action from
 // the specified peer. An effort is also made to return any pending fetches into
 // the queue.
@@ -332,10 +427,18 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	case nil:
 	case errBusy:
 
-	case errTimeout, errBadPeer, errStallingPeer,
+	case errTimeout, errBadPeer, errStallingPeer, errUnsyncedPeer,
 		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
 		errInvalidAncestor, errInvalidChain:
 		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
+		switch err {
+		case errTimeout:
+			// Timeouts are reported by the fetchers as they happen
+		case errBadPeer, errInvalidAncestor, errInvalidChain:
+			d.reportPeer(id, InvalidResponse)
+		default:
+			d.reportPeer(id, UselessResponse)
+		}
 		if d.dropPeer == nil {
 			// The dropPeer method is nil when `--copydb` is used for a local copy.
 			// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
@@ -454,41 +557,72 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	d.syncStatsChainHeight = height
 	d.syncStatsLock.Unlock()
 
-	// Ensure our origin point is below any fast sync pivot point
-	pivot := uint64(0)
-	if d.mode == FastSync {
+	// Ensure our origin point is below any fast sync pivot point, resuming with
+	// the pivot of an interrupted sync if it's still fresh
+	var (
+		pivot uint64
+		root  = latest.Root
+	)
+	if d.mode == FastSync || d.mode == SnapSync {
 		if height <= uint64(fsMinFullBlocks) {
 			origin = 0
 		} else {
 			pivot = height - uint64(fsMinFullBlocks)
+			if resumed, resumedRoot := d.resumablePivot(height); resumed != 0 {
+				p.log.Debug("Resuming fast sync pivot", "number", resumed, "root", resumedRoot)
+				pivot = resumed
+				if resumedRoot != (common.Hash{}) {
+					root = resumedRoot
+				}
+			}
 			if pivot <= origin {
 				origin = pivot - 1
 			}
+			d.syncStatsLock.RLock()
+			start := d.syncStatsChainOrigin
+			d.syncStatsLock.RUnlock()
+
+			d.updateSyncStatus(func(status *syncStatus) {
+				if status.Pivot != pivot {
+					status.Root = common.Hash{}
+				}
+				status.Origin, status.Height, status.Pivot = start, height, pivot
+			})
 		}
 	}
 	d.committed = 1
-	if d.mode == FastSync && pivot != 0 {
+	if (d.mode == FastSync || d.mode == SnapSync) && pivot != 0 {
 		d.committed = 0
 	}
+	// Continue an interrupted fast sync from its persisted download offsets
+	var (
+		from   = origin + 1
+		stored []*types.Header
+	)
+	if pivot != 0 {
+		if from, stored, err = d.resumeOffsets(p, origin); err != nil {
+			return err
+		}
+	}
 	// Initiate the sync using a concurrent header and content retrieval algorithm
-	d.queue.Prepare(origin+1, d.mode)
+	d.queue.Prepare(from, d.mode)
 	if d.syncInitHook != nil {
 		d.syncInitHook(origin, height)
 	}
 
 	fetchers := []func() error{
 		// 지정된 피어로부터 헤더를 수신함
-		func() error { return d.fetchHeaders(p, origin+1, pivot) }, // Headers are always retrieved
+		func() error { return d.fetchResumedHeaders(p, from, stored, pivot) }, // Headers are always retrieved
 		// 아무 피어로부터 블록바디를 수신함
-		func() error { return d.fetchBodies(origin + 1) },          // Bodies are retrieved during normal and fast sync
+		func() error { return d.fetchBodies(from) },          // Bodies are retrieved during normal and fast sync
 		// 아무 피어로부터 영수증을 수신함
-		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
+		func() error { return d.fetchReceipts(from) },        // Receipts are retrieved during fast sync
 		// 수신한 헤더를 프로세스 큐에 넣고 스케쥴링함 
 		// 스케쥴링이란 노드 내에서 벌어지는 일련의 일을 우선순위 큐로 관리하는 것을 말함
-		func() error { return d.processHeaders(origin+1, pivot, td) },
+		func() error { return d.processHeaders(from, pivot, td) },
 	}
-	if d.mode == FastSync {
-		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
+	if d.mode == FastSync || d.mode == SnapSync {
+		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest, pivot, root) })
 	} else if d.mode == FullSync {
 		fetchers = append(fetchers, d.processFullSyncContent)
 	}
@@ -594,11 +728,16 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 				return nil, errBadPeer
 			}
 			head := headers[0]
+			if checkpoint, _ := d.trustedCheckpoint(); d.mode != FullSync && head.Number.Uint64() < checkpoint {
+				p.log.Warn("Remote head below checkpoint", "number", head.Number, "hash", head.Hash(), "checkpoint", checkpoint)
+				return nil, errUnsyncedPeer
+			}
 			p.log.Debug("Remote head header identified", "number", head.Number, "hash", head.Hash())
 			return head, nil
 
 		case <-timeout:
 			p.log.Debug("Waiting for head header timed out", "elapsed", ttl)
+			d.reportPeer(p.id, ResponseTimeout)
 			return nil, errTimeout
 
 		case <-d.bodyCh:
@@ -608,6 +747,45 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
+// confirmHeader requests a single header from the remote peer by number, checking
+// whether it matches the one given.
+func (d *Downloader) confirmHeader(p *peerConnection, header *types.Header) (bool, error) {
+	p.log.Debug("Confirming local header", "number", header.Number, "hash", header.Hash())
+	go p.peer.RequestHeadersByNumber(header.Number.Uint64(), 1, 0, false)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return false, errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			// Make sure the peer actually gave something valid
+			headers := packet.(*headerPack).headers
+			if len(headers) > 1 {
+				p.log.Debug("Multiple headers for single request", "headers", len(headers))
+				return false, errBadPeer
+			}
+			return len(headers) == 1 && headers[0].Hash() == header.Hash(), nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for local header confirmation timed out", "elapsed", ttl)
+			d.reportPeer(p.id, ResponseTimeout)
+			return false, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -624,12 +802,31 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 
 	if d.mode == FullSync {
 		ceil = d.blockchain.CurrentBlock().NumberU64()
-	} else if d.mode == FastSync {
+	} else if d.mode == FastSync || d.mode == SnapSync {
 		ceil = d.blockchain.CurrentFastBlock().NumberU64()
 	}
 	if ceil >= MaxForkAncestry {
 		floor = int64(ceil - MaxForkAncestry)
 	}
+	// If we're doing a light sync, ensure the floor doesn't go below the CHT, as
+	// all headers before that point will be missing.
+	if d.mode == LightSync {
+		// If we don't know the current CHT position, find it
+		if d.genesis == 0 {
+			header := d.lightchain.CurrentHeader()
+			for header != nil {
+				d.genesis = header.Number.Uint64()
+				if floor >= int64(d.genesis)-1 {
+					break
+				}
+				header = d.lightchain.GetHeaderByHash(header.ParentHash)
+			}
+		}
+		// We already know the "genesis" block number, cap floor to that
+		if floor < int64(d.genesis)-1 {
+			floor = int64(d.genesis) - 1
+		}
+	}
 	p.log.Debug("Looking for common ancestor", "local", ceil, "remote", height)
 
 	// Request the topmost blocks to short circuit binary ancestor lookup
@@ -701,6 +898,7 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 
 		case <-timeout:
 			p.log.Debug("Waiting for head header timed out", "elapsed", ttl)
+			d.reportPeer(p.id, ResponseTimeout)
 			return 0, errTimeout
 
 		case <-d.bodyCh:
@@ -765,6 +963,7 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 
 			case <-timeout:
 				p.log.Debug("Waiting for search header timed out", "elapsed", ttl)
+				d.reportPeer(p.id, ResponseTimeout)
 				return 0, errTimeout
 
 			case <-d.bodyCh:
@@ -782,6 +981,25 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 	return start, nil
 }
 
+// fetchResumedHeaders feeds the headers already imported by an interrupted sync
+// to the header processor to schedule their content retrieval, and then keeps
+// retrieving the remaining headers from the remote peer.
+func (d *Downloader) fetchResumedHeaders(p *peerConnection, from uint64, stored []*types.Header, pivot uint64) error {
+	for len(stored) > 0 {
+		limit := MaxHeaderFetch
+		if limit > len(stored) {
+			limit = len(stored)
+		}
+		select {
+		case d.headerProcCh <- stored[:limit]:
+		case <-d.cancelCh:
+			return errCancelHeaderFetch
+		}
+		stored, from = stored[limit:], from+uint64(limit)
+	}
+	return d.fetchHeaders(p, from, pivot)
+}
+
 // fetchHeaders keeps retrieving headers concurrently from the number
 // requested, until no more are returned, potentially throttling on the way. To
 // facilitate concurrency but still protect against malicious nodes sending bad
@@ -893,6 +1111,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64)
 			getHeaders(from)
 
 		case <-timeout.C:
+			d.reportPeer(p.id, ResponseTimeout)
 			if d.dropPeer == nil {
 				// The dropPeer method is nil when `--copydb` is used for a local copy.
 				// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
@@ -1058,8 +1277,14 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 			if peer := d.peers.Peer(packet.PeerId()); peer != nil {
 				// Deliver the received chunk of data and check chain validity
 				accepted, err := deliver(packet)
-				if err == errInvalidChain {
+				switch {
+				case err == errInvalidChain:
+					d.reportPeer(peer.id, InvalidResponse)
 					return err
+				case err == nil && accepted > 0:
+					d.reportPeer(peer.id, UsefulResponse)
+				case err != errStaleDelivery:
+					d.reportPeer(peer.id, UselessResponse)
 				}
 				// Unless a peer delivered something completely else than requested (usually
 				// caused by a timed out request which came through in the end), set it to
@@ -1109,6 +1334,8 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 			// Check for fetch request timeouts and demote the responsible peers
 			for pid, fails := range expire() {
 				if peer := d.peers.Peer(pid); peer != nil {
+					d.reportPeer(pid, ResponseTimeout)
+
 					// If a lot of retrieval elements expired, we might have overestimated the remote peer or perhaps
 					// ourselves. Only reset to minimal throughput but don't drop just yet. If even the minimal times
 					// out that sync wise we need to get rid of the peer.
@@ -1216,6 +1443,14 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				lastBlock = d.blockchain.CurrentBlock().Number()
 			}
 			d.lightchain.Rollback(hashes)
+			if pivot != 0 && atomic.LoadInt32(&d.committed) == 0 {
+				next := d.lightchain.CurrentHeader().Number.Uint64() + 1
+				d.updateSyncStatus(func(status *syncStatus) {
+					if status.Headers > next {
+						status.Headers = next
+					}
+				})
+			}
 			curFastBlock, curBlock := common.Big0, common.Big0
 			if d.mode != LightSync {
 				curFastBlock = d.blockchain.CurrentFastBlock().Number()
@@ -1271,7 +1506,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// This check cannot be executed "as is" for full imports, since blocks may still be
 				// queued for processing when the header download completes. However, as long as the
 				// peer gave us something useful, we're already happy/progressed (above check).
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode == SnapSync || d.mode == LightSync {
 					head := d.lightchain.CurrentHeader()
 					if td.Cmp(d.lightchain.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
 						return errStallingPeer
@@ -1299,7 +1534,15 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				chunk := headers[:limit]
 
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode == SnapSync || d.mode == LightSync {
+					// Reject the chain outright if it contradicts the trusted checkpoint
+					checkpoint, checkpointHash := d.trustedCheckpoint()
+					if first, last := chunk[0].Number.Uint64(), chunk[len(chunk)-1].Number.Uint64(); checkpoint != 0 && first <= checkpoint && checkpoint <= last {
+						if header := chunk[checkpoint-first]; header.Hash() != checkpointHash {
+							log.Warn("Checkpoint header mismatch", "number", checkpoint, "have", header.Hash(), "want", checkpointHash)
+							return errInvalidChain
+						}
+					}
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(headers))
 					for _, header := range chunk {
@@ -1325,9 +1568,16 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 					if len(rollback) > fsHeaderSafetyNet {
 						rollback = append(rollback[:0], rollback[len(rollback)-fsHeaderSafetyNet:]...)
 					}
+					// Persist the header progress to resume from after a restart
+					if pivot != 0 && atomic.LoadInt32(&d.committed) == 0 {
+						next := chunk[len(chunk)-1].Number.Uint64() + 1
+						d.updateSyncStatus(func(status *syncStatus) {
+							status.Headers = next
+						})
+					}
 				}
 				// Unless we're doing light chains, schedule the headers for associated content retrieval
-				if d.mode == FullSync || d.mode == FastSync {
+				if d.mode == FullSync || d.mode == FastSync || d.mode == SnapSync {
 					// If we've reached the allowed number of pending headers, stall a bit
 					for d.queue.PendingBlocks() >= maxQueuedHeaders || d.queue.PendingReceipts() >= maxQueuedHeaders {
 						select {
@@ -1411,27 +1661,25 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 
 // processFastSyncContent takes fetch results from the queue and writes them to the
 // database. It also controls the synchronisation of state nodes of the pivot block.
+// The state download starts at the given root, which is either that of the head
+// block, or that of the pivot block if an interrupted sync is being resumed.
 // processFastSyncContent 함수는 큐로부터 fetch 결과들을 받아 DB에 쓴다
 // 또한 피봇블록의 상태노드의 동기를 컨트롤한다.
-func (d *Downloader) processFastSyncContent(latest *types.Header) error {
+func (d *Downloader) processFastSyncContent(latest *types.Header, pivot uint64, root common.Hash) error {
 	// Start syncing state of the reported head block. This should get us most of
 	// the state of the pivot block.
 	// 보고된 헤드블록의 상태를 동기화 하기 시작한다.
-	stateSync := d.syncState(latest.Root)
+	stateSync := d.syncState(root)
 	defer stateSync.Cancel()
 	go func() {
 		if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
 			d.queue.Close() // wake up WaitResults
 		}
 	}()
-	// Figure out the ideal pivot block. Note, that this goalpost may move if the
-	// sync takes long enough for the chain head to move significantly.
+	// The pivot block was picked when starting the sync. Note, that this goalpost
+	// may move if the sync takes long enough for the chain head to move significantly.
 	// 이상적인 피봇블록을 찾는다. 이 목적지는 만약 싱크가 오래걸려 체인 헤드가 
 	// 심각하게 멀어질경우 옮겨질수 있다.
-	pivot := uint64(0)
-	if height := latest.Number.Uint64(); height > uint64(fsMinFullBlocks) {
-		pivot = height - uint64(fsMinFullBlocks)
-	}
 	// To cater for moving pivot points, track the pivot block and subsequently
 	// accumulated download results separately.
 	var (
@@ -1468,6 +1716,10 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			if height := latest.Number.Uint64(); height > pivot+2*uint64(fsMinFullBlocks) {
 				log.Warn("Pivot became stale, moving", "old", pivot, "new", height-uint64(fsMinFullBlocks))
 				pivot = height - uint64(fsMinFullBlocks)
+
+				d.updateSyncStatus(func(status *syncStatus) {
+					status.Height, status.Pivot, status.Root = height, pivot, common.Hash{}
+				})
 			}
 		}
 		P, beforeP, afterP := splitAroundPivot(pivot, results)
@@ -1481,6 +1733,13 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 
 				stateSync = d.syncState(P.Header.Root)
 				defer stateSync.Cancel()
+
+				d.updateSyncStatus(func(status *syncStatus) {
+					if status.Root != P.Header.Root {
+						status.Tasks = nil
+					}
+					status.Pivot, status.Root = pivot, P.Header.Root
+				})
 				go func() {
 					if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
 						d.queue.Close() // wake up WaitResults
@@ -1556,6 +1815,11 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 		log.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
 		return errInvalidChain
 	}
+	// Bodies and receipts are imported together, persist both offsets
+	next := last.Number.Uint64() + 1
+	d.updateSyncStatus(func(status *syncStatus) {
+		status.Bodies, status.Receipts = next, next
+	})
 	return nil
 }
 
@@ -1569,6 +1833,10 @@ func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 		return err
 	}
 	atomic.StoreInt32(&d.committed, 1)
+
+	// The sync is done, nothing to resume anymore
+	d.clearSyncStatus()
+	d.snapTasks, d.snapRoot = nil, common.Hash{}
 	return nil
 }
 
@@ -1593,6 +1861,54 @@ func (d *Downloader) DeliverNodeData(id string, data [][]byte) (err error) {
 	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
 }
 
+// DeliverAccountRange injects a range of accounts received from a remote snap peer.
+func (d *Downloader) DeliverAccountRange(id string, reqid uint64, hashes []common.Hash, accounts [][]byte, proof [][]byte) error {
+	return d.deliverSnap(&accountRangePack{id, reqid, hashes, accounts, proof}, accountRangeInMeter, accountRangeDropMeter)
+}
+
+// DeliverStorageRanges injects a batch of storage ranges received from a remote
+// snap peer.
+func (d *Downloader) DeliverStorageRanges(id string, reqid uint64, hashes [][]common.Hash, slots [][][]byte, proof [][]byte) error {
+	return d.deliverSnap(&storageRangesPack{id, reqid, hashes, slots, proof}, storageRangeInMeter, storageRangeDropMeter)
+}
+
+// DeliverByteCodes injects a batch of contract bytecodes received from a remote
+// snap peer.
+func (d *Downloader) DeliverByteCodes(id string, reqid uint64, codes [][]byte) error {
+	return d.deliverSnap(&byteCodesPack{id, reqid, codes}, byteCodesInMeter, byteCodesDropMeter)
+}
+
+// deliverSnap injects a snapshot protocol response into the currently running
+// range sync, if any.
+func (d *Downloader) deliverSnap(packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
+	inMeter.Mark(int64(packet.Items()))
+	defer func() {
+		if err != nil {
+			dropMeter.Mark(int64(packet.Items()))
+		}
+	}()
+	d.snapLock.RLock()
+	rs := d.snapSync
+	d.snapLock.RUnlock()
+
+	if rs == nil {
+		return errNoSyncActive
+	}
+	select {
+	case rs.deliveries <- packet:
+		return nil
+	case <-rs.done:
+		return errNoSyncActive
+	}
+}
+
+// reportPeer forwards the verdict on a peer's response to the protocol handler.
+func (d *Downloader) reportPeer(id string, feedback PeerFeedback) {
+	if d.peerReport != nil {
+		d.peerReport(id, feedback)
+	}
+}
+
 // deliver injects a new batch of data received from a remote node.
 func (d *Downloader) deliver(id string, destCh chan dataPack, packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
 	// Update the delivery metrics for both good and failed deliveries
@@ -28,11 +28,14 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -75,8 +78,14 @@ type downloadTester struct {
 
 // newTester creates a new downloader test mocker.
 func newTester() *downloadTester {
+	return newTesterWithAlloc(core.GenesisAlloc{testAddress: {Balance: big.NewInt(1000000000)}})
+}
+
+// newTesterWithAlloc creates a new downloader test mocker, with the given genesis
+// allocation as the initial state of the chains.
+func newTesterWithAlloc(alloc core.GenesisAlloc) *downloadTester {
 	testdb := ethdb.NewMemDatabase()
-	genesis := core.GenesisBlockForTesting(testdb, testAddress, big.NewInt(1000000000))
+	genesis := (&core.Genesis{Alloc: alloc}).MustCommit(testdb)
 
 	tester := &downloadTester{
 		genesis:           genesis,
@@ -96,7 +105,7 @@ func newTester() *downloadTester {
 	tester.stateDb = ethdb.NewMemDatabase()
 	tester.stateDb.Put(genesis.Root().Bytes(), []byte{0x00})
 
-	tester.downloader = New(FullSync, tester.stateDb, new(event.TypeMux), tester, nil, tester.dropPeer)
+	tester.downloader = New(nil, FullSync, tester.stateDb, new(event.TypeMux), tester, nil, tester.dropPeer, nil)
 
 	return tester
 }
@@ -603,6 +612,120 @@ func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// downloadTesterSnapPeer is a snapshot protocol peer of the download tester,
+// serving state ranges from the peer database. After a given number of account
+// ranges it forgets about the state, interrupting the range sync.
+type downloadTesterSnapPeer struct {
+	dl    *downloadTester
+	id    string
+	limit int // Number of account ranges to serve before going stateless
+
+	served int // Number of account ranges served so far
+	lock   sync.Mutex
+}
+
+// stateless checks whether the snap peer already served all its account ranges.
+func (dlp *downloadTesterSnapPeer) stateless() bool {
+	dlp.lock.Lock()
+	defer dlp.lock.Unlock()
+
+	return dlp.served >= dlp.limit
+}
+
+// RequestAccountRange serves a batch of accounts starting at origin from the
+// peer database, along with the proofs of the range boundaries.
+func (dlp *downloadTesterSnapPeer) RequestAccountRange(id uint64, root common.Hash, origin, limit common.Hash, bytes uint64) error {
+	dlp.lock.Lock()
+	defer dlp.lock.Unlock()
+
+	var (
+		hashes   []common.Hash
+		accounts [][]byte
+		proof    [][]byte
+	)
+	if dlp.served < dlp.limit {
+		dlp.served++
+
+		tr, err := trie.New(root, trie.NewDatabase(dlp.dl.peerDb))
+		if err != nil {
+			return err
+		}
+		it := trie.NewIterator(tr.NodeIterator(origin[:]))
+		for it.Next() && len(hashes) < 16 {
+			hash := common.BytesToHash(it.Key)
+			hashes, accounts = append(hashes, hash), append(accounts, common.CopyBytes(it.Value))
+			if hash.Big().Cmp(limit.Big()) >= 0 {
+				break
+			}
+		}
+		nodes := ethdb.NewMemDatabase()
+		tr.Prove(origin[:], 0, nodes)
+		if len(hashes) > 0 {
+			tr.Prove(hashes[len(hashes)-1][:], 0, nodes)
+		}
+		for _, key := range nodes.Keys() {
+			blob, _ := nodes.Get(key)
+			proof = append(proof, blob)
+		}
+	}
+	go dlp.dl.downloader.DeliverAccountRange(dlp.id, id, hashes, accounts, proof)
+
+	return nil
+}
+
+// RequestStorageRanges serves the entire storage tries of the requested accounts
+// from the peer database.
+func (dlp *downloadTesterSnapPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error {
+	var (
+		hashes [][]common.Hash
+		slots  [][][]byte
+	)
+	if !dlp.stateless() {
+		triedb := trie.NewDatabase(dlp.dl.peerDb)
+		accTrie, err := trie.New(root, triedb)
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			var acc state.Account
+			if err := rlp.DecodeBytes(accTrie.Get(account[:]), &acc); err != nil {
+				return err
+			}
+			stTrie, err := trie.New(acc.Root, triedb)
+			if err != nil {
+				return err
+			}
+			var (
+				keys []common.Hash
+				vals [][]byte
+			)
+			it := trie.NewIterator(stTrie.NodeIterator(nil))
+			for it.Next() {
+				keys, vals = append(keys, common.BytesToHash(it.Key)), append(vals, common.CopyBytes(it.Value))
+			}
+			hashes, slots = append(hashes, keys), append(slots, vals)
+		}
+	}
+	go dlp.dl.downloader.DeliverStorageRanges(dlp.id, id, hashes, slots, nil)
+
+	return nil
+}
+
+// RequestByteCodes serves the requested contract codes from the peer database.
+func (dlp *downloadTesterSnapPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	var codes [][]byte
+	if !dlp.stateless() {
+		for _, hash := range hashes {
+			if code, err := dlp.dl.peerDb.Get(hash[:]); err == nil {
+				codes = append(codes, code)
+			}
+		}
+	}
+	go dlp.dl.downloader.DeliverByteCodes(dlp.id, id, codes)
+
+	return nil
+}
+
 // assertOwnChain checks if the local chain contains the correct number of items
 // of the various chain components.
 func assertOwnChain(t *testing.T, tester *downloadTester, length int) {
@@ -1753,3 +1876,268 @@ func testDeliverHeadersHang(t *testing.T, protocol int, mode SyncMode) {
 		tester.downloader.peers.peers["peer"].peer.(*floodingTestPeer).pend.Wait()
 	}
 }
+
+// Tests that a trusted checkpoint is enforced during header only syncs: peers
+// whose head is below it are rejected, as are chains contradicting it.
+func TestCheckpointEnforcement63Full(t *testing.T)  { testCheckpointEnforcement(t, 63, FullSync) }
+func TestCheckpointEnforcement63Fast(t *testing.T)  { testCheckpointEnforcement(t, 63, FastSync) }
+func TestCheckpointEnforcement64Full(t *testing.T)  { testCheckpointEnforcement(t, 64, FullSync) }
+func TestCheckpointEnforcement64Fast(t *testing.T)  { testCheckpointEnforcement(t, 64, FastSync) }
+func TestCheckpointEnforcement64Light(t *testing.T) { testCheckpointEnforcement(t, 64, LightSync) }
+
+func testCheckpointEnforcement(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	master := newTester()
+	defer master.terminate()
+
+	targetBlocks := 3*fsHeaderSafetyNet + 256 + fsMinFullBlocks
+	hashes, headers, blocks, receipts := master.makeChain(targetBlocks, 0, master.genesis, nil, false)
+	checkpoint := uint64(targetBlocks / 2)
+
+	tests := []struct {
+		number uint64      // Checkpoint block number to enforce
+		hash   common.Hash // Checkpoint block hash to enforce
+		err    error       // Error expected from header only syncs
+	}{
+		{uint64(targetBlocks + 1), common.Hash{}, errUnsyncedPeer},
+		{checkpoint, common.Hash{0x01}, errInvalidChain},
+		{checkpoint, hashes[len(hashes)-1-int(checkpoint)], nil},
+	}
+	for i, tt := range tests {
+		tester := newTester()
+		tester.peerDb = master.peerDb
+		tester.downloader.checkpoint, tester.downloader.checkpointHash = tt.number, tt.hash
+
+		tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+
+		err := tester.sync("peer", nil, mode)
+		if want := tt.err; mode == FullSync {
+			if err != nil {
+				t.Errorf("test %d: full sync failed: %v", i, err)
+			}
+		} else if err != want {
+			t.Errorf("test %d: sync error mismatch: have %v, want %v", i, err, want)
+		}
+		if err == nil {
+			assertOwnChain(t, tester, targetBlocks+1)
+		}
+		tester.terminate()
+	}
+}
+
+// Tests that an interrupted fast sync persists its progress and a restarted
+// downloader resumes it with the same pivot block, dropping the progress after
+// the pivot is committed.
+func TestFastSyncResume63(t *testing.T) { testFastSyncResume(t, 63) }
+func TestFastSyncResume64(t *testing.T) { testFastSyncResume(t, 64) }
+
+func testFastSyncResume(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := blockCacheItems - 15
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	// Simulate an interrupted sync that picked an older, but still fresh pivot
+	pivot := uint64(targetBlocks - fsMinFullBlocks - 16)
+	tester.downloader.updateSyncStatus(func(status *syncStatus) {
+		status.Height, status.Pivot = uint64(targetBlocks), pivot
+	})
+	tester.downloader.Terminate()
+	tester.downloader = New(nil, FullSync, tester.stateDb, new(event.TypeMux), tester, nil, tester.dropPeer, nil)
+	if have := tester.downloader.Progress().PivotBlock; have != pivot {
+		t.Fatalf("restored pivot mismatch: have %d, want %d", have, pivot)
+	}
+	// Synchronise and ensure the stored pivot's state was retrieved
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if hs := len(tester.ownHeaders); hs != targetBlocks+1 {
+		t.Fatalf("synchronised headers mismatch: have %v, want %v", hs, targetBlocks+1)
+	}
+	if rs := len(tester.ownReceipts); rs != int(pivot)+1 {
+		t.Fatalf("fast synced receipts mismatch: have %v, want %v", rs, pivot+1)
+	}
+	root := headers[hashes[len(hashes)-1-int(pivot)]].Root
+	if _, err := tester.stateDb.Get(root.Bytes()); err != nil {
+		t.Fatalf("resumed pivot state missing: %v", err)
+	}
+	if blob := rawdb.ReadFastSyncStatus(tester.stateDb); len(blob) != 0 {
+		t.Fatalf("sync status not cleared after completion")
+	}
+	if have := tester.downloader.Progress().PivotBlock; have != 0 {
+		t.Fatalf("pivot not reset after completion: have %d", have)
+	}
+}
+
+// Tests that a restarted fast sync resumes from the persisted download offsets,
+// reusing the headers imported before the interruption instead of retrieving
+// them again.
+func TestFastSyncResumeOffsets63(t *testing.T) { testFastSyncResumeOffsets(t, 63) }
+func TestFastSyncResumeOffsets64(t *testing.T) { testFastSyncResumeOffsets(t, 64) }
+
+func testFastSyncResumeOffsets(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := blockCacheItems - 15
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	// Simulate an interrupted sync that already imported a batch of headers
+	imported := 2*MaxHeaderFetch + 10
+	chain := make([]*types.Header, imported)
+	for i := range chain {
+		chain[i] = headers[hashes[len(hashes)-2-i]]
+	}
+	if _, err := tester.InsertHeaderChain(chain, 1); err != nil {
+		t.Fatalf("failed to import headers: %v", err)
+	}
+	pivot := uint64(targetBlocks - fsMinFullBlocks)
+	tester.downloader.updateSyncStatus(func(status *syncStatus) {
+		status.Height, status.Pivot = uint64(targetBlocks), pivot
+		status.Headers, status.Bodies, status.Receipts = uint64(imported)+1, 1, 1
+	})
+	tester.downloader.Terminate()
+	tester.downloader = New(nil, FullSync, tester.stateDb, new(event.TypeMux), tester, nil, tester.dropPeer, nil)
+
+	// Hide the imported headers from the peer, apart from the last one needed to
+	// confirm the local chain, so they can only be scheduled from the database
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+	tester.lock.Lock()
+	for i := 1; i < imported; i++ {
+		delete(tester.peerHeaders["peer"], hashes[len(hashes)-1-i])
+	}
+	tester.lock.Unlock()
+
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+}
+
+// Tests that the trusted checkpoint is only ever moved forward, so an oracle
+// can't roll it back to an older section.
+func TestCheckpointUpdate(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	older := &params.TrustedCheckpoint{SectionIndex: 1, SectionHead: common.Hash{0x01}}
+	newer := &params.TrustedCheckpoint{SectionIndex: 2, SectionHead: common.Hash{0x02}}
+
+	tester.downloader.SetCheckpoint(newer)
+	tester.downloader.SetCheckpoint(older)
+	if number, hash := tester.downloader.trustedCheckpoint(); number != newer.HeadNumber() || hash != newer.SectionHead {
+		t.Fatalf("checkpoint mismatch: have %d/%x, want %d/%x", number, hash, newer.HeadNumber(), newer.SectionHead)
+	}
+}
+
+// Tests that a snap sync interrupted in the middle of the account ranges still
+// ends up with the complete pivot state, including contract codes and storage,
+// by healing the partially imported state trie.
+func TestSnapSyncInterruptedRange63(t *testing.T) { testSnapSyncInterruptedRange(t, 63) }
+func TestSnapSyncInterruptedRange64(t *testing.T) { testSnapSyncInterruptedRange(t, 64) }
+
+func testSnapSyncInterruptedRange(t *testing.T, protocol int) {
+	// Create a genesis state with plenty of accounts and a few contracts, some of
+	// them sharing their code
+	alloc := core.GenesisAlloc{testAddress: {Balance: big.NewInt(1000000000)}}
+	for i := 0; i < 256; i++ {
+		alloc[common.BigToAddress(big.NewInt(int64(i+1)))] = core.GenesisAccount{Balance: big.NewInt(int64(i + 1))}
+	}
+	for i := 0; i < 16; i++ {
+		storage := make(map[common.Hash]common.Hash)
+		for j := 0; j < 8; j++ {
+			storage[common.BigToHash(big.NewInt(int64(j)))] = common.BigToHash(big.NewInt(int64(i*8 + j + 1)))
+		}
+		alloc[common.BigToAddress(big.NewInt(int64(1000+i)))] = core.GenesisAccount{
+			Balance: big.NewInt(1),
+			Code:    []byte{0x60, byte(i % 5), 0x60, 0x00, 0x55},
+			Storage: storage,
+		}
+	}
+	tester := newTesterWithAlloc(alloc)
+	defer tester.terminate()
+
+	timeout := snapPeerTimeout
+	snapPeerTimeout = 100 * time.Millisecond
+	defer func() { snapPeerTimeout = timeout }()
+
+	targetBlocks := 2 * fsMinFullBlocks
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+	snap := &downloadTesterSnapPeer{dl: tester, id: "peer", limit: 4}
+	if err := tester.downloader.RegisterSnapPeer("peer", snap); err != nil {
+		t.Fatalf("failed to register snap peer: %v", err)
+	}
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+
+	if !snap.stateless() {
+		t.Fatalf("range sync not interrupted: served %d/%d account ranges", snap.served, snap.limit)
+	}
+	// Ensure every node, storage slot and contract code of the pivot state is present
+	root := headers[hashes[fsMinFullBlocks]].Root
+	peerState, err := state.New(root, state.NewDatabase(tester.peerDb))
+	if err != nil {
+		t.Fatalf("failed to open peer state: %v", err)
+	}
+	for it := state.NewNodeIterator(peerState); it.Next(); {
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		if ok, _ := tester.stateDb.Has(it.Hash[:]); !ok {
+			t.Fatalf("state entry %x missing", it.Hash)
+		}
+	}
+	ownState, err := state.New(root, state.NewDatabase(tester.stateDb))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	it := state.NewNodeIterator(ownState)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("synced state incomplete: %v", it.Error)
+	}
+}
+
+// Tests that a storage range delivered empty stalls the snap peer and puts the
+// storage task back into the queue, instead of dropping it.
+func TestSnapSyncEmptyStorageRange(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	var feedback []PeerFeedback
+	tester.downloader.peerReport = func(id string, f PeerFeedback) { feedback = append(feedback, f) }
+
+	r := newRangeSync(tester.downloader, tester.genesis.Root())
+	task := &storageTask{account: common.Hash{0x01}, root: common.Hash{0x02}, next: common.Hash{0x03}}
+	r.stReqs[1] = &storageRequest{id: 1, peer: "peer", tasks: []*storageTask{task}, timer: time.NewTimer(time.Hour)}
+	r.busy["peer"] = struct{}{}
+
+	pack := &storageRangesPack{peerId: "peer", id: 1, hashes: [][]common.Hash{{}}, slots: [][][]byte{{}}, proof: [][]byte{{0x80}}}
+	if err := r.processStorage(pack); err != nil {
+		t.Fatalf("failed to process storage ranges: %v", err)
+	}
+	if len(r.storage) != 1 || r.storage[0] != task {
+		t.Fatalf("storage task not rescheduled: %v", r.storage)
+	}
+	if task.next != (common.Hash{0x03}) {
+		t.Errorf("storage task progress changed: have %x, want %x", task.next, common.Hash{0x03})
+	}
+	if _, ok := r.stateless["peer"]; !ok {
+		t.Errorf("stalled peer still assigned tasks")
+	}
+	if len(feedback) != 1 || feedback[0] != UselessResponse {
+		t.Errorf("peer feedback mismatch: have %v, want [%v]", feedback, UselessResponse)
+	}
+}
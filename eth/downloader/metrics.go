@@ -40,4 +40,11 @@ var (
 
 	stateInMeter   = metrics.NewRegisteredMeter("eth/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("eth/downloader/states/drop", nil)
+
+	accountRangeInMeter   = metrics.NewRegisteredMeter("eth/downloader/snap/accounts/in", nil)
+	accountRangeDropMeter = metrics.NewRegisteredMeter("eth/downloader/snap/accounts/drop", nil)
+	storageRangeInMeter   = metrics.NewRegisteredMeter("eth/downloader/snap/storage/in", nil)
+	storageRangeDropMeter = metrics.NewRegisteredMeter("eth/downloader/snap/storage/drop", nil)
+	byteCodesInMeter      = metrics.NewRegisteredMeter("eth/downloader/snap/codes/in", nil)
+	byteCodesDropMeter    = metrics.NewRegisteredMeter("eth/downloader/snap/codes/drop", nil)
 )
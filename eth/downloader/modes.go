@@ -25,10 +25,11 @@ const (
 	FullSync  SyncMode = iota // Synchronise the entire blockchain history from full blocks
 	FastSync                  // Quickly download the headers, full sync only at the chain head
 	LightSync                 // Download only the headers and terminate afterwards
+	SnapSync                  // Like fast sync, but retrieve the pivot state via contiguous ranges
 )
 
 func (mode SyncMode) IsValid() bool {
-	return mode >= FullSync && mode <= LightSync
+	return mode >= FullSync && mode <= SnapSync
 }
 
 // String implements the stringer interface.
@@ -40,6 +41,8 @@ func (mode SyncMode) String() string {
 		return "fast"
 	case LightSync:
 		return "light"
+	case SnapSync:
+		return "snap"
 	default:
 		return "unknown"
 	}
@@ -53,6 +56,8 @@ func (mode SyncMode) MarshalText() ([]byte, error) {
 		return []byte("fast"), nil
 	case LightSync:
 		return []byte("light"), nil
+	case SnapSync:
+		return []byte("snap"), nil
 	default:
 		return nil, fmt.Errorf("unknown sync mode %d", mode)
 	}
@@ -66,8 +71,10 @@ func (mode *SyncMode) UnmarshalText(text []byte) error {
 		*mode = FastSync
 	case "light":
 		*mode = LightSync
+	case "snap":
+		*mode = SnapSync
 	default:
-		return fmt.Errorf(`unknown sync mode %q, want "full", "fast" or "light"`, text)
+		return fmt.Errorf(`unknown sync mode %q, want "full", "fast", "light" or "snap"`, text)
 	}
 	return nil
 }
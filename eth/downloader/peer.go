@@ -90,6 +90,14 @@ type Peer interface {
 	RequestNodeData([]common.Hash) error
 }
 
+// SnapPeer encapsulates the methods required to synchronise state ranges with a
+// remote peer via the snapshot protocol.
+type SnapPeer interface {
+	RequestAccountRange(id uint64, root common.Hash, origin, limit common.Hash, bytes uint64) error
+	RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error
+	RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
@@ -0,0 +1,720 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	accountConcurrency = 16               // Number of chunks to split the account trie into to allow concurrent retrievals
+	maxStorageFetch    = 128              // Maximum number of storage tries to request in a single range query
+	maxCodeFetch       = 64               // Maximum number of contract codes to request in a single query
+	snapResponseLimit  = 512 * 1024       // Soft limit on the size of a single range response
+	snapRetryInterval  = time.Second      // Interval at which to retry assigning tasks to idle snap peers
+	snapPeerTimeout    = 10 * time.Second // Time to wait for the range sync to progress before falling back to healing
+
+	emptyCode = crypto.Keccak256Hash(nil) // Code hash of accounts without contract code
+)
+
+// accountTask represents the sync task for a chunk of the account trie. Tasks
+// are kept across restarts of the sync of the same state root, but start over
+// if the root changes, since the ranges of the old one prove nothing about it.
+type accountTask struct {
+	Next common.Hash // Next account to sync in this interval
+	Last common.Hash // Last account to sync in this interval
+
+	req *accountRequest // Pending request to fill this task, if any
+}
+
+// done returns whether the task has retrieved its entire account range.
+func (t *accountTask) done() bool {
+	return t.Next == (common.Hash{}) && t.Last == (common.Hash{})
+}
+
+// storageTask represents the sync task for a single storage trie (or the
+// remainder of it if a large contract was only partially delivered).
+type storageTask struct {
+	account common.Hash // Hash of the account owning the storage trie
+	root    common.Hash // Storage trie root hash to verify deliveries against
+	next    common.Hash // Next storage slot to sync in this storage trie
+}
+
+// accountRequest tracks a pending account range retrieval.
+type accountRequest struct {
+	id    uint64       // Request ID to match up the response with
+	peer  string       // Peer to which this request is assigned
+	task  *accountTask // Account task this request is filling
+	timer *time.Timer  // Timer to fire when the request times out
+}
+
+// storageRequest tracks a pending storage ranges retrieval.
+type storageRequest struct {
+	id    uint64         // Request ID to match up the response with
+	peer  string         // Peer to which this request is assigned
+	tasks []*storageTask // Storage tasks this request is filling
+	timer *time.Timer    // Timer to fire when the request times out
+}
+
+// bytecodeRequest tracks a pending contract bytecode retrieval.
+type bytecodeRequest struct {
+	id     uint64        // Request ID to match up the response with
+	peer   string        // Peer to which this request is assigned
+	hashes []common.Hash // Code hashes this request is retrieving
+	timer  *time.Timer   // Timer to fire when the request times out
+}
+
+// rangeSync retrieves a state trie through contiguous account and storage
+// ranges served by snap peers, along with the contract codes of the delivered
+// accounts. Every delivered range is verified against the state root via its
+// boundary proofs and the reconstructed trie nodes are written directly into
+// the database. The trie nodes on the boundaries of the ranges are incomplete,
+// so the imported state is always healed afterwards by a trie node sync walking
+// the local trie, which also fills any ranges or codes left undelivered (e.g.
+// due to uncooperative peers).
+type rangeSync struct {
+	d    *Downloader // Downloader instance to access and manage current peerset
+	root common.Hash // State root currently being synced
+
+	tasks    []*accountTask       // Account range tasks (kept by the downloader for the root)
+	storage  []*storageTask       // Storage tasks waiting to be assigned
+	roots    map[common.Hash]bool // Storage roots already scheduled to avoid duplicates
+	codes    []common.Hash        // Contract codes waiting to be assigned
+	codeSeen map[common.Hash]bool // Contract codes already scheduled to avoid duplicates
+	reqid    uint64               // Last request ID used
+	accReqs  map[uint64]*accountRequest
+	stReqs   map[uint64]*storageRequest
+	codeReqs map[uint64]*bytecodeRequest
+
+	busy      map[string]struct{} // Snap peers with an active request
+	stateless map[string]struct{} // Snap peers that don't have the requested state
+
+	deliveries chan dataPack // Delivery channel multiplexing peer responses
+	timeouts   chan dataPack // Channel to signal request timeouts
+	done       chan struct{} // Channel to signal termination of the range sync
+
+	accounts, slots, bytecodes, nodes uint64    // Statistics of the retrieved data
+	progressed                        time.Time // Time instance when useful data was last retrieved
+	logged                            time.Time // Time instance when progress was last reported
+}
+
+// newRangeSync creates a state range download scheduler, resuming any account
+// tasks left over by a previous range sync of the same root.
+func newRangeSync(d *Downloader, root common.Hash) *rangeSync {
+	if d.snapTasks == nil || d.snapRoot != root {
+		d.snapTasks, d.snapRoot = splitAccountRange(accountConcurrency), root
+	}
+	return &rangeSync{
+		d:          d,
+		root:       root,
+		tasks:      d.snapTasks,
+		roots:      make(map[common.Hash]bool),
+		codeSeen:   make(map[common.Hash]bool),
+		accReqs:    make(map[uint64]*accountRequest),
+		stReqs:     make(map[uint64]*storageRequest),
+		codeReqs:   make(map[uint64]*bytecodeRequest),
+		busy:       make(map[string]struct{}),
+		stateless:  make(map[string]struct{}),
+		deliveries: make(chan dataPack),
+		timeouts:   make(chan dataPack),
+		done:       make(chan struct{}),
+		progressed: time.Now(),
+		logged:     time.Now(),
+	}
+}
+
+// splitAccountRange divides the account hash space into n equal intervals.
+func splitAccountRange(n int) []*accountTask {
+	var (
+		tasks = make([]*accountTask, 0, n)
+		next  = new(big.Int)
+		step  = new(big.Int).Div(new(big.Int).Lsh(common.Big1, 256), big.NewInt(int64(n)))
+	)
+	for i := 0; i < n; i++ {
+		last := new(big.Int).Sub(new(big.Int).Add(next, step), common.Big1)
+		if i == n-1 {
+			last = new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 256), common.Big1)
+		}
+		tasks = append(tasks, &accountTask{
+			Next: common.BigToHash(next),
+			Last: common.BigToHash(last),
+		})
+		next = new(big.Int).Add(last, common.Big1)
+	}
+	return tasks
+}
+
+// incHash returns the hash following h, or false if h was the last one.
+func incHash(h common.Hash) (common.Hash, bool) {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			return h, true
+		}
+	}
+	return h, false
+}
+
+// syncRanges runs the range retrieval phase of a snap sync for the given state
+// root, returning once all ranges are filled, no snap peer can serve the root
+// any more or the sync is canceled.
+func (d *Downloader) syncRanges(root common.Hash, cancel chan struct{}) error {
+	if root == types.EmptyRootHash {
+		return nil
+	}
+	r := newRangeSync(d, root)
+
+	d.snapLock.Lock()
+	d.snapSync = r
+	d.snapLock.Unlock()
+
+	defer func() {
+		d.snapLock.Lock()
+		d.snapSync = nil
+		d.snapLock.Unlock()
+
+		close(r.done)
+		for _, req := range r.accReqs {
+			req.timer.Stop()
+			req.task.req = nil
+		}
+		for _, req := range r.stReqs {
+			req.timer.Stop()
+		}
+		for _, req := range r.codeReqs {
+			req.timer.Stop()
+		}
+	}()
+	return r.run(cancel)
+}
+
+// run is the main event loop of the range sync, assigning range retrievals to
+// idle snap peers and processing their responses.
+func (r *rangeSync) run(cancel chan struct{}) error {
+	log.Info("Starting state range sync", "root", r.root)
+
+	ticker := time.NewTicker(snapRetryInterval)
+	defer ticker.Stop()
+
+	for !r.finished() {
+		r.assignTasks()
+
+		// If no snap peer delivered anything for a while (none connected, none
+		// having the state or none responding), leave the rest to healing
+		if time.Since(r.progressed) > snapPeerTimeout {
+			log.Warn("State range sync stalled, switching to healing", "root", r.root)
+			return nil
+		}
+		select {
+		case <-cancel:
+			return errCancelStateFetch
+
+		case <-r.d.cancelCh:
+			return errCancelStateFetch
+
+		case <-ticker.C:
+			// Retry assignment in case new snap peers arrived
+
+		case pack := <-r.deliveries:
+			var err error
+			switch pack := pack.(type) {
+			case *accountRangePack:
+				err = r.processAccounts(pack)
+			case *storageRangesPack:
+				err = r.processStorage(pack)
+			case *byteCodesPack:
+				err = r.processByteCodes(pack)
+			}
+			if err != nil {
+				return err
+			}
+
+		case pack := <-r.timeouts:
+			r.revert(pack)
+		}
+		r.reportProgress(false)
+	}
+	r.reportProgress(true)
+	return nil
+}
+
+// finished returns whether all account and storage ranges, and contract codes
+// have been retrieved.
+func (r *rangeSync) finished() bool {
+	if len(r.storage) > 0 || len(r.stReqs) > 0 || len(r.accReqs) > 0 {
+		return false
+	}
+	if len(r.codes) > 0 || len(r.codeReqs) > 0 {
+		return false
+	}
+	for _, task := range r.tasks {
+		if !task.done() {
+			return false
+		}
+	}
+	return true
+}
+
+// idlePeers returns the snap peers that are currently neither busy nor known to
+// be missing the requested state.
+func (r *rangeSync) idlePeers() []string {
+	r.d.snapLock.RLock()
+	defer r.d.snapLock.RUnlock()
+
+	var idle []string
+	for id := range r.d.snapPeers {
+		if _, ok := r.busy[id]; ok {
+			continue
+		}
+		if _, ok := r.stateless[id]; ok {
+			continue
+		}
+		idle = append(idle, id)
+	}
+	return idle
+}
+
+// assignTasks attempts to assign new account or storage range, or contract code
+// retrievals to all idle snap peers.
+func (r *rangeSync) assignTasks() {
+	for _, id := range r.idlePeers() {
+		peer := r.d.snapPeer(id)
+		if peer == nil {
+			continue
+		}
+		// Prefer storage retrievals to keep the pending queue short
+		if tasks := r.nextStorageBatch(); len(tasks) > 0 {
+			r.reqid++
+			req := &storageRequest{id: r.reqid, peer: id, tasks: tasks}
+			accounts := make([]common.Hash, len(tasks))
+			for i, task := range tasks {
+				accounts[i] = task.account
+			}
+			var origin []byte
+			if tasks[0].next != (common.Hash{}) {
+				origin = tasks[0].next[:]
+			}
+			req.timer = r.track(&storageRangesPack{peerId: id, id: req.id})
+			r.stReqs[req.id] = req
+			r.busy[id] = struct{}{}
+
+			if err := peer.RequestStorageRanges(req.id, r.root, accounts, origin, nil, uint64(snapResponseLimit)); err != nil {
+				log.Debug("Failed to request storage ranges", "peer", id, "err", err)
+			}
+			continue
+		}
+		// Then the contract codes of the already delivered accounts
+		if hashes := r.nextCodeBatch(); len(hashes) > 0 {
+			r.reqid++
+			req := &bytecodeRequest{id: r.reqid, peer: id, hashes: hashes}
+			req.timer = r.track(&byteCodesPack{peerId: id, id: req.id})
+			r.codeReqs[req.id] = req
+			r.busy[id] = struct{}{}
+
+			if err := peer.RequestByteCodes(req.id, hashes, uint64(snapResponseLimit)); err != nil {
+				log.Debug("Failed to request byte codes", "peer", id, "err", err)
+			}
+			continue
+		}
+		// No storage to retrieve, find an account task with no pending request
+		var task *accountTask
+		for _, t := range r.tasks {
+			if t.req == nil && !t.done() {
+				task = t
+				break
+			}
+		}
+		if task == nil {
+			return
+		}
+		r.reqid++
+		req := &accountRequest{id: r.reqid, peer: id, task: task}
+		req.timer = r.track(&accountRangePack{peerId: id, id: req.id})
+		r.accReqs[req.id] = req
+		r.busy[id] = struct{}{}
+		task.req = req
+
+		if err := peer.RequestAccountRange(req.id, r.root, task.Next, task.Last, uint64(snapResponseLimit)); err != nil {
+			log.Debug("Failed to request account range", "peer", id, "err", err)
+		}
+	}
+}
+
+// nextStorageBatch pops the next batch of storage tasks to retrieve. A partially
+// retrieved storage trie is always requested on its own, since the origin of a
+// range query only applies to the first requested account.
+func (r *rangeSync) nextStorageBatch() []*storageTask {
+	if len(r.storage) == 0 {
+		return nil
+	}
+	if r.storage[0].next != (common.Hash{}) {
+		tasks := r.storage[:1]
+		r.storage = r.storage[1:]
+		return tasks
+	}
+	n := 0
+	for n < len(r.storage) && n < maxStorageFetch && r.storage[n].next == (common.Hash{}) {
+		n++
+	}
+	tasks := append([]*storageTask{}, r.storage[:n]...)
+	r.storage = r.storage[n:]
+	return tasks
+}
+
+// nextCodeBatch pops the next batch of contract codes to retrieve.
+func (r *rangeSync) nextCodeBatch() []common.Hash {
+	n := len(r.codes)
+	if n > maxCodeFetch {
+		n = maxCodeFetch
+	}
+	hashes := append([]common.Hash{}, r.codes[:n]...)
+	r.codes = r.codes[n:]
+	return hashes
+}
+
+// track starts a timeout timer for a pending request, delivering the given
+// empty pack to the timeout channel if the peer doesn't respond in time.
+func (r *rangeSync) track(timeout dataPack) *time.Timer {
+	return time.AfterFunc(r.d.requestTTL(), func() {
+		select {
+		case r.timeouts <- timeout:
+		case <-r.done:
+		}
+	})
+}
+
+// revert returns the tasks of a timed out request into the retrieval queue.
+func (r *rangeSync) revert(pack dataPack) {
+	switch pack := pack.(type) {
+	case *accountRangePack:
+		req := r.accReqs[pack.id]
+		if req == nil {
+			return
+		}
+		log.Debug("Account range request timed out", "peer", req.peer, "reqid", req.id)
+		r.d.reportPeer(req.peer, ResponseTimeout)
+		delete(r.accReqs, req.id)
+		delete(r.busy, req.peer)
+		req.task.req = nil
+
+	case *storageRangesPack:
+		req := r.stReqs[pack.id]
+		if req == nil {
+			return
+		}
+		log.Debug("Storage ranges request timed out", "peer", req.peer, "reqid", req.id)
+		r.d.reportPeer(req.peer, ResponseTimeout)
+		delete(r.stReqs, req.id)
+		delete(r.busy, req.peer)
+		r.storage = append(req.tasks, r.storage...)
+
+	case *byteCodesPack:
+		req := r.codeReqs[pack.id]
+		if req == nil {
+			return
+		}
+		log.Debug("Byte codes request timed out", "peer", req.peer, "reqid", req.id)
+		r.d.reportPeer(req.peer, ResponseTimeout)
+		delete(r.codeReqs, req.id)
+		delete(r.busy, req.peer)
+		r.codes = append(req.hashes, r.codes...)
+	}
+}
+
+// processAccounts verifies an account range response, persisting the proven
+// trie nodes and scheduling the storage tries of the delivered accounts.
+func (r *rangeSync) processAccounts(res *accountRangePack) error {
+	req := r.accReqs[res.id]
+	if req == nil || req.peer != res.peerId {
+		log.Debug("Unrequested account range", "peer", res.peerId, "reqid", res.id)
+		return nil
+	}
+	req.timer.Stop()
+	delete(r.accReqs, req.id)
+	delete(r.busy, req.peer)
+
+	task := req.task
+	task.req = nil
+
+	// An empty response without proofs means the peer doesn't have the state
+	if len(res.hashes) == 0 && len(res.proof) == 0 {
+		log.Debug("Peer has no state for root", "peer", req.peer, "root", r.root)
+		r.stateless[req.peer] = struct{}{}
+		return nil
+	}
+	keys := make([][]byte, len(res.hashes))
+	for i, hash := range res.hashes {
+		keys[i] = common.CopyBytes(hash[:])
+	}
+	nodes, more, err := trie.VerifyRangeProof(r.root, task.Next[:], keys, res.accounts, proofDatabase(res.proof))
+	if err != nil {
+		log.Warn("Invalid account range, dropping peer", "peer", req.peer, "err", err)
+		r.dropPeer(req.peer)
+		return nil
+	}
+	// Range proven, schedule the storage tries and codes that are not yet known
+	for i, blob := range res.accounts {
+		var account state.Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			log.Warn("Invalid account in range, dropping peer", "peer", req.peer, "hash", res.hashes[i], "err", err)
+			r.dropPeer(req.peer)
+			return nil
+		}
+		if code := common.BytesToHash(account.CodeHash); code != emptyCode && !r.codeSeen[code] {
+			if ok, _ := r.d.stateDB.Has(code[:]); !ok {
+				r.codeSeen[code] = true
+				r.codes = append(r.codes, code)
+			}
+		}
+		if account.Root == types.EmptyRootHash || r.roots[account.Root] {
+			continue
+		}
+		if ok, _ := r.d.stateDB.Has(account.Root[:]); ok {
+			continue
+		}
+		r.roots[account.Root] = true
+		r.storage = append(r.storage, &storageTask{account: res.hashes[i], root: account.Root})
+	}
+	if err := r.commit(nodes); err != nil {
+		return err
+	}
+	r.accounts += uint64(len(res.hashes))
+	r.nodes += uint64(nodes.Len())
+	r.progressed = time.Now()
+	r.d.reportPeer(req.peer, UsefulResponse)
+
+	// Advance the task, finishing it if the interval was exhausted
+	if len(res.hashes) == 0 || !more || bytes.Compare(res.hashes[len(res.hashes)-1][:], task.Last[:]) >= 0 {
+		task.Next, task.Last = common.Hash{}, common.Hash{}
+	} else if next, ok := incHash(res.hashes[len(res.hashes)-1]); !ok {
+		task.Next, task.Last = common.Hash{}, common.Hash{}
+	} else {
+		task.Next = next
+	}
+	// Persist the account progress to resume from it after a restart
+	tasks := copyAccountTasks(r.tasks)
+	r.d.updateSyncStatus(func(status *syncStatus) {
+		status.Tasks = tasks
+	})
+	return nil
+}
+
+// processStorage verifies a storage ranges response, persisting the proven trie
+// nodes and rescheduling any storage tries not (fully) delivered.
+func (r *rangeSync) processStorage(res *storageRangesPack) error {
+	req := r.stReqs[res.id]
+	if req == nil || req.peer != res.peerId {
+		log.Debug("Unrequested storage ranges", "peer", res.peerId, "reqid", res.id)
+		return nil
+	}
+	req.timer.Stop()
+	delete(r.stReqs, req.id)
+	delete(r.busy, req.peer)
+
+	if len(res.hashes) == 0 {
+		log.Debug("Peer has no storage for root", "peer", req.peer, "root", r.root)
+		r.stateless[req.peer] = struct{}{}
+		r.storage = append(req.tasks, r.storage...)
+		return nil
+	}
+	if len(res.hashes) > len(req.tasks) {
+		log.Warn("Too many storage ranges delivered, dropping peer", "peer", req.peer, "want", len(req.tasks), "have", len(res.hashes))
+		r.dropPeer(req.peer)
+		r.storage = append(req.tasks, r.storage...)
+		return nil
+	}
+	var pending []*storageTask
+	for i, hashes := range res.hashes {
+		task := req.tasks[i]
+
+		// Scheduled storage tries always have slots left, delivering none of them
+		// is a stall, not a proof of the range being empty
+		if len(hashes) == 0 {
+			log.Debug("Peer stalled on storage range", "peer", req.peer, "account", task.account)
+			r.stateless[req.peer] = struct{}{}
+			r.d.reportPeer(req.peer, UselessResponse)
+			r.storage = append(append(pending, req.tasks[i:]...), r.storage...)
+			return nil
+		}
+		keys := make([][]byte, len(hashes))
+		for j, hash := range hashes {
+			keys[j] = common.CopyBytes(hash[:])
+		}
+		// Only the last range may be partial and thus carry proofs
+		var proof trie.DatabaseReader
+		if i == len(res.hashes)-1 && len(res.proof) > 0 {
+			proof = proofDatabase(res.proof)
+		} else if task.next != (common.Hash{}) {
+			log.Warn("Unproven partial storage range, dropping peer", "peer", req.peer, "account", task.account)
+			r.dropPeer(req.peer)
+			r.storage = append(req.tasks[i:], r.storage...)
+			return nil
+		}
+		nodes, more, err := trie.VerifyRangeProof(task.root, task.next[:], keys, res.slots[i], proof)
+		if err != nil {
+			log.Warn("Invalid storage range, dropping peer", "peer", req.peer, "account", task.account, "err", err)
+			r.dropPeer(req.peer)
+			r.storage = append(req.tasks[i:], r.storage...)
+			return nil
+		}
+		if err := r.commit(nodes); err != nil {
+			return err
+		}
+		r.slots += uint64(len(hashes))
+		r.nodes += uint64(nodes.Len())
+		r.progressed = time.Now()
+
+		if more {
+			if next, ok := incHash(hashes[len(hashes)-1]); ok {
+				task.next = next
+				pending = append(pending, task)
+			}
+		}
+	}
+	r.d.reportPeer(req.peer, UsefulResponse)
+
+	// Reschedule partially filled and undelivered storage tries
+	pending = append(pending, req.tasks[len(res.hashes):]...)
+	r.storage = append(pending, r.storage...)
+	return nil
+}
+
+// processByteCodes verifies a contract bytecode response, persisting the codes
+// and rescheduling the ones not delivered.
+func (r *rangeSync) processByteCodes(res *byteCodesPack) error {
+	req := r.codeReqs[res.id]
+	if req == nil || req.peer != res.peerId {
+		log.Debug("Unrequested byte codes", "peer", res.peerId, "reqid", res.id)
+		return nil
+	}
+	req.timer.Stop()
+	delete(r.codeReqs, req.id)
+	delete(r.busy, req.peer)
+
+	if len(res.codes) == 0 {
+		log.Debug("Peer has no byte codes", "peer", req.peer, "root", r.root)
+		r.stateless[req.peer] = struct{}{}
+		r.codes = append(req.hashes, r.codes...)
+		return nil
+	}
+	// Match the delivered codes up with the requested hashes
+	pending := make(map[common.Hash]bool, len(req.hashes))
+	for _, hash := range req.hashes {
+		pending[hash] = true
+	}
+	codes := ethdb.NewMemDatabase()
+	for _, code := range res.codes {
+		hash := crypto.Keccak256Hash(code)
+		if !pending[hash] {
+			log.Warn("Unrequested byte code delivered, dropping peer", "peer", req.peer, "hash", hash)
+			r.dropPeer(req.peer)
+			r.codes = append(req.hashes, r.codes...)
+			return nil
+		}
+		delete(pending, hash)
+		codes.Put(hash[:], code)
+	}
+	if err := r.commit(codes); err != nil {
+		return err
+	}
+	r.bytecodes += uint64(codes.Len())
+	r.progressed = time.Now()
+	r.d.reportPeer(req.peer, UsefulResponse)
+
+	// Reschedule the codes not delivered
+	var missing []common.Hash
+	for _, hash := range req.hashes {
+		if pending[hash] {
+			missing = append(missing, hash)
+		}
+	}
+	r.codes = append(missing, r.codes...)
+	return nil
+}
+
+// commit writes a set of proven trie nodes or contract codes into the state
+// database.
+func (r *rangeSync) commit(nodes *ethdb.MemDatabase) error {
+	batch := r.d.stateDB.NewBatch()
+	for _, key := range nodes.Keys() {
+		blob, _ := nodes.Get(key)
+		if err := batch.Put(key, blob); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("DB write error: %v", err)
+	}
+
+	r.d.syncStatsLock.Lock()
+	r.d.syncStatsState.processed += uint64(nodes.Len())
+	processed := r.d.syncStatsState.processed
+	r.d.syncStatsLock.Unlock()
+
+	rawdb.WriteFastTrieProgress(r.d.stateDB, processed)
+	return nil
+}
+
+// dropPeer marks a snap peer as useless for this sync and disconnects it.
+func (r *rangeSync) dropPeer(id string) {
+	r.stateless[id] = struct{}{}
+	r.d.reportPeer(id, InvalidResponse)
+	if r.d.dropPeer != nil {
+		r.d.dropPeer(id)
+	}
+}
+
+// reportProgress logs the state of the range sync to the user, either when
+// forced or at most every few seconds.
+func (r *rangeSync) reportProgress(force bool) {
+	if !force && time.Since(r.logged) < 8*time.Second {
+		return
+	}
+	r.logged = time.Now()
+
+	done := 0
+	for _, task := range r.tasks {
+		if task.done() {
+			done++
+		}
+	}
+	log.Info("Imported state ranges", "root", r.root, "chunks", fmt.Sprintf("%d/%d", done, len(r.tasks)),
+		"accounts", r.accounts, "slots", r.slots, "codes", r.bytecodes, "nodes", r.nodes, "pending", len(r.storage)+len(r.codes))
+}
+
+// proofDatabase collects a list of proof nodes into a database keyed by the
+// node hashes, as required by the trie proof verification.
+func proofDatabase(proof [][]byte) *ethdb.MemDatabase {
+	db := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
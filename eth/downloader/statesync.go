@@ -214,7 +214,8 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 // stateSync schedules requests for downloading a particular state trie defined
 // by a given state root.
 type stateSync struct {
-	d *Downloader // Downloader instance to access and manage current peerset
+	d    *Downloader // Downloader instance to access and manage current peerset
+	root common.Hash // State root currently being synced
 
 	sched  *trie.TrieSync             // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
@@ -243,6 +244,7 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
 		d:       d,
+		root:    root,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
@@ -290,6 +292,15 @@ func (s *stateSync) loop() (err error) {
 		}
 	}()
 
+	// During snap sync, retrieve the bulk of the state via contiguous ranges
+	// first, then heal the imported state by walking it from the root, as the
+	// nodes on the range boundaries (and anything undelivered) are incomplete.
+	if s.d.mode == SnapSync {
+		if err = s.d.syncRanges(s.root, s.cancel); err != nil {
+			return err
+		}
+		s.sched = state.NewStateHeal(s.root, s.d.stateDB)
+	}
 	// Keep assigning new tasks until the sync completes or aborts
 	for s.sched.Pending() > 0 {
 		if err = s.commit(false); err != nil {
@@ -310,6 +321,15 @@ func (s *stateSync) loop() (err error) {
 		case req := <-s.deliver:
 			// Response, disconnect or timeout triggered, drop the peer if stalling
 			log.Trace("Received node data response", "peer", req.peer.id, "count", len(req.response), "dropped", req.dropped, "timeout", !req.dropped && req.timedOut())
+			switch {
+			case req.dropped:
+			case req.timedOut():
+				s.d.reportPeer(req.peer.id, ResponseTimeout)
+			case len(req.response) > 0:
+				s.d.reportPeer(req.peer.id, UsefulResponse)
+			default:
+				s.d.reportPeer(req.peer.id, UselessResponse)
+			}
 			if len(req.items) <= 2 && !req.dropped && req.timedOut() {
 				// 2 items are the minimum requested, if even that times out, we've no use of
 				// this peer at the moment.
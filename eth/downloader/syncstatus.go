@@ -0,0 +1,174 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// syncStatus is the progress of a fast (or snap) sync persisted into the
+// database, allowing an interrupted sync to resume with the same pivot block
+// and without losing the header, block and state retrieval progress.
+type syncStatus struct {
+	Origin   uint64         // Block number where the sync started at
+	Height   uint64         // Highest block number known when the sync was last running
+	Pivot    uint64         // Pivot block whose state is being retrieved
+	Root     common.Hash    // State root of the pivot block, pending retrieval
+	Headers  uint64         // First block whose header wasn't yet imported
+	Bodies   uint64         // First block whose body wasn't yet imported
+	Receipts uint64         // First block whose receipts weren't yet imported
+	Tasks    []*accountTask // Account range progress of the snap sync, if any
+}
+
+// readSyncStatus loads the persisted sync progress from the database, returning
+// nil if no interrupted sync was found.
+func readSyncStatus(db rawdb.DatabaseReader) *syncStatus {
+	blob := rawdb.ReadFastSyncStatus(db)
+	if len(blob) == 0 {
+		return nil
+	}
+	status := new(syncStatus)
+	if err := rlp.DecodeBytes(blob, status); err != nil {
+		log.Warn("Failed to decode fast sync status, discarding", "err", err)
+		return nil
+	}
+	return status
+}
+
+// updateSyncStatus applies a modification to the tracked sync progress and
+// persists the result into the database.
+func (d *Downloader) updateSyncStatus(update func(status *syncStatus)) {
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+
+	if d.status == nil {
+		d.status = new(syncStatus)
+	}
+	update(d.status)
+
+	blob, err := rlp.EncodeToBytes(d.status)
+	if err != nil {
+		log.Error("Failed to encode fast sync status", "err", err)
+		return
+	}
+	rawdb.WriteFastSyncStatus(d.stateDB, blob)
+}
+
+// clearSyncStatus drops the tracked sync progress after the pivot block was
+// committed, so subsequent syncs don't resume a finished one.
+func (d *Downloader) clearSyncStatus() {
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+
+	d.status = nil
+	rawdb.DeleteFastSyncStatus(d.stateDB)
+}
+
+// resumablePivot returns the pivot block of an interrupted sync if it's still
+// fresh enough to be synced to given the current chain height, or 0 otherwise.
+func (d *Downloader) resumablePivot(height uint64) (uint64, common.Hash) {
+	d.statusLock.RLock()
+	defer d.statusLock.RUnlock()
+
+	if d.status == nil || d.status.Pivot == 0 {
+		return 0, common.Hash{}
+	}
+	if d.status.Pivot > height || d.status.Pivot+2*uint64(fsMinFullBlocks) < height {
+		return 0, common.Hash{}
+	}
+	return d.status.Pivot, d.status.Root
+}
+
+// syncPivot returns the pivot block of the running (or interrupted) fast sync.
+func (d *Downloader) syncPivot() uint64 {
+	d.statusLock.RLock()
+	defer d.statusLock.RUnlock()
+
+	if d.status == nil {
+		return 0
+	}
+	return d.status.Pivot
+}
+
+// resumeOffsets restores the download offsets of an interrupted sync, returning
+// the first block whose content (body and receipts) needs retrieving and the
+// headers already imported from that block onwards. The stored headers are only
+// reused if the remote peer has the last one too, otherwise the sync continues
+// from the common ancestor.
+func (d *Downloader) resumeOffsets(p *peerConnection, origin uint64) (uint64, []*types.Header, error) {
+	d.statusLock.RLock()
+	var headers, content uint64
+	if d.status != nil {
+		headers, content = d.status.Headers, d.status.Bodies
+		if d.status.Receipts < content {
+			content = d.status.Receipts
+		}
+	}
+	d.statusLock.RUnlock()
+
+	// Content may only be skipped up to the local fast block, headers up to the
+	// local head header
+	if fast := d.blockchain.CurrentFastBlock().NumberU64(); content > fast+1 {
+		content = fast + 1
+	}
+	if content <= origin {
+		content = origin + 1
+	}
+	head := d.lightchain.CurrentHeader()
+	if number := head.Number.Uint64(); headers > number+1 {
+		headers = number + 1
+	}
+	if headers <= content {
+		return origin + 1, nil, nil
+	}
+	// Collect the stored headers, making sure they link up to a local block
+	stored := make([]*types.Header, headers-content)
+	for header := head; header != nil && header.Number.Uint64() >= content; header = d.lightchain.GetHeaderByHash(header.ParentHash) {
+		if number := header.Number.Uint64(); number < headers {
+			stored[number-content] = header
+		}
+	}
+	if stored[0] == nil || d.blockchain.GetBlockByHash(stored[0].ParentHash) == nil {
+		return origin + 1, nil, nil
+	}
+	// Ensure the remote peer is on the same chain as the stored headers
+	last := stored[len(stored)-1]
+	known, err := d.confirmHeader(p, last)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !known {
+		p.log.Debug("Peer disagrees with stored headers", "number", last.Number, "hash", last.Hash())
+		return origin + 1, nil, nil
+	}
+	p.log.Debug("Resuming interrupted downloads", "content", content, "headers", headers)
+	return content, stored, nil
+}
+
+// copyAccountTasks creates a snapshot of the account range progress, dropping
+// any in-flight request associations.
+func copyAccountTasks(tasks []*accountTask) []*accountTask {
+	cpy := make([]*accountTask, len(tasks))
+	for i, task := range tasks {
+		cpy[i] = &accountTask{Next: task.Next, Last: task.Last}
+	}
+	return cpy
+}
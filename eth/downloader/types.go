@@ -19,12 +19,27 @@ package downloader
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // peerDropFn is a callback type for dropping a peer detected as malicious.
 type peerDropFn func(id string)
 
+// PeerFeedback is the downloader's verdict on how a peer answered a request.
+type PeerFeedback int
+
+const (
+	UsefulResponse  PeerFeedback = iota // Peer delivered data that was accepted
+	UselessResponse                     // Peer delivered nothing or nothing usable
+	ResponseTimeout                     // Peer failed to answer in time
+	InvalidResponse                     // Peer delivered invalid chain or state data
+)
+
+// peerReportFn is a callback type for reporting the verdict on a peer's
+// response, allowing the protocol handler to track the peer's reputation.
+type peerReportFn func(id string, feedback PeerFeedback)
+
 // dataPack is a data message returned by a peer for some query.
 type dataPack interface {
 	PeerId() string
@@ -77,3 +92,40 @@ type statePack struct {
 func (p *statePack) PeerId() string { return p.peerId }
 func (p *statePack) Items() int     { return len(p.states) }
 func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+
+// accountRangePack is a range of accounts returned by a snap peer.
+type accountRangePack struct {
+	peerId   string
+	id       uint64
+	hashes   []common.Hash
+	accounts [][]byte
+	proof    [][]byte
+}
+
+func (p *accountRangePack) PeerId() string { return p.peerId }
+func (p *accountRangePack) Items() int     { return len(p.hashes) }
+func (p *accountRangePack) Stats() string  { return fmt.Sprintf("%d:%d", len(p.hashes), len(p.proof)) }
+
+// storageRangesPack is a batch of storage ranges returned by a snap peer.
+type storageRangesPack struct {
+	peerId string
+	id     uint64
+	hashes [][]common.Hash
+	slots  [][][]byte
+	proof  [][]byte
+}
+
+func (p *storageRangesPack) PeerId() string { return p.peerId }
+func (p *storageRangesPack) Items() int     { return len(p.hashes) }
+func (p *storageRangesPack) Stats() string  { return fmt.Sprintf("%d:%d", len(p.hashes), len(p.proof)) }
+
+// byteCodesPack is a batch of contract bytecodes returned by a snap peer.
+type byteCodesPack struct {
+	peerId string
+	id     uint64
+	codes  [][]byte
+}
+
+func (p *byteCodesPack) PeerId() string { return p.peerId }
+func (p *byteCodesPack) Items() int     { return len(p.codes) }
+func (p *byteCodesPack) Stats() string  { return fmt.Sprintf("%d", len(p.codes)) }
@@ -4,6 +4,7 @@ package eth
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -11,6 +12,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -20,15 +22,27 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		LightServ               int                            `toml:",omitempty"`
+		LightPeers              int                            `toml:",omitempty"`
+		LightState              uint64                         `toml:",omitempty"`
+		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
+		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		ULC                     *ULCConfig                     `toml:",omitempty"`
+		SkipBcVersionCheck      bool                           `toml:"-"`
+		DatabaseHandles         int                            `toml:"-"`
 		DatabaseCache           int
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		MinerTxOrder            string `toml:",omitempty"`
+		MinerGasFloor           uint64
+		MinerGasCeil            uint64
+		MinerRecommit           time.Duration
+		MinerNotify             []string `toml:",omitempty"`
+		ExternalSigner          string   `toml:",omitempty"`
+		MinerStratum            string   `toml:",omitempty"`
+		MinerStratumDiff        float64
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
@@ -41,6 +55,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightState = c.LightState
+	enc.Checkpoint = c.Checkpoint
+	enc.CheckpointOracle = c.CheckpointOracle
+	enc.ULC = c.ULC
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
@@ -48,6 +66,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
+	enc.MinerTxOrder = c.MinerTxOrder
+	enc.MinerGasFloor = c.MinerGasFloor
+	enc.MinerGasCeil = c.MinerGasCeil
+	enc.MinerRecommit = c.MinerRecommit
+	enc.MinerNotify = c.MinerNotify
+	enc.ExternalSigner = c.ExternalSigner
+	enc.MinerStratum = c.MinerStratum
+	enc.MinerStratumDiff = c.MinerStratumDiff
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -61,15 +87,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		LightServ               *int                           `toml:",omitempty"`
+		LightPeers              *int                           `toml:",omitempty"`
+		LightState              *uint64                        `toml:",omitempty"`
+		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
+		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		ULC                     *ULCConfig                     `toml:",omitempty"`
+		SkipBcVersionCheck      *bool                          `toml:"-"`
+		DatabaseHandles         *int                           `toml:"-"`
 		DatabaseCache           *int
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		MinerTxOrder            *string `toml:",omitempty"`
+		MinerGasFloor           *uint64
+		MinerGasCeil            *uint64
+		MinerRecommit           *time.Duration
+		MinerNotify             []string `toml:",omitempty"`
+		ExternalSigner          *string  `toml:",omitempty"`
+		MinerStratum            *string  `toml:",omitempty"`
+		MinerStratumDiff        *float64
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
@@ -95,6 +133,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightState != nil {
+		c.LightState = *dec.LightState
+	}
+	if dec.Checkpoint != nil {
+		c.Checkpoint = dec.Checkpoint
+	}
+	if dec.CheckpointOracle != nil {
+		c.CheckpointOracle = dec.CheckpointOracle
+	}
+	if dec.ULC != nil {
+		c.ULC = dec.ULC
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -116,6 +166,30 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.MinerTxOrder != nil {
+		c.MinerTxOrder = *dec.MinerTxOrder
+	}
+	if dec.MinerGasFloor != nil {
+		c.MinerGasFloor = *dec.MinerGasFloor
+	}
+	if dec.MinerGasCeil != nil {
+		c.MinerGasCeil = *dec.MinerGasCeil
+	}
+	if dec.MinerRecommit != nil {
+		c.MinerRecommit = *dec.MinerRecommit
+	}
+	if dec.MinerNotify != nil {
+		c.MinerNotify = dec.MinerNotify
+	}
+	if dec.ExternalSigner != nil {
+		c.ExternalSigner = *dec.ExternalSigner
+	}
+	if dec.MinerStratum != nil {
+		c.MinerStratum = *dec.MinerStratum
+	}
+	if dec.MinerStratumDiff != nil {
+		c.MinerStratumDiff = *dec.MinerStratumDiff
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
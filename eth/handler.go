@@ -67,6 +67,7 @@ type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  uint32 // Flag whether fast sync should retrieve the state via snap ranges
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
@@ -77,6 +78,10 @@ type ProtocolManager struct {
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
+	snapPeers  *snapPeerSet
+
+	scorer     PeerScorer   // Policy converting peer behaviours into reputation changes
+	scorerLock sync.RWMutex // Protects the peer scorer from concurrent replacement
 
 	SubProtocols []p2p.Protocol
 
@@ -104,7 +109,7 @@ type ProtocolManager struct {
 // Qos 튜너는 산발적으로 피어들의 지연속도를 모아 예측시간을 업데이트 한다
 // statefetcher는 피어 일동의 active state 동기화 및 요청 수락을 관리한다
 // 해쉬 어나운스먼트를 베이스로 블록을 검색하는 블록패쳐를 만든다
-func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, networkId uint64, mux *event.TypeMux, txpool txPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database) (*ProtocolManager, error) {
+func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, networkId uint64, mux *event.TypeMux, txpool txPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database, checkpoint *params.TrustedCheckpoint) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		networkId:   networkId,
@@ -113,26 +118,31 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockchain:  blockchain,
 		chainconfig: config,
 		peers:       newPeerSet(),
+		snapPeers:   newSnapPeerSet(),
+		scorer:      DefaultPeerScorer,
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	}
 	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
+	if (mode == downloader.FastSync || mode == downloader.SnapSync) && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	if mode == downloader.FastSync || mode == downloader.SnapSync {
 		manager.fastSync = uint32(1)
 	}
+	if mode == downloader.SnapSync {
+		manager.snapSync = uint32(1)
+	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	// 서브 프로토콜을 초기화 한다
 	// 피어별로 사용하는 프로토콜 버전이 다를 수 있으므로 각 버전에 대해 초기화 해준다
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < eth63 {
+		if (mode == downloader.FastSync || mode == downloader.SnapSync) && version < eth63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -166,12 +176,16 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	if len(manager.SubProtocols) == 0 {
 		return nil, errIncompatibleConfig
 	}
+	// Every node serves state ranges, regardless of its own sync mode
+	for i, version := range SnapProtocolVersions {
+		manager.SubProtocols = append(manager.SubProtocols, manager.makeSnapProtocol(version, SnapProtocolLengths[i]))
+	}
 	// Construct the different synchronisation mechanisms
 	// 해쉬나 블록을 원격피어로 부터 가져오는 다운로더를 만든다
 	// Qos 튜너는 산발적으로 피어들의 지연속도를 모아 예측시간을 업데이트 한다
 	// statefetcher는 피어 일동의 active state 동기화 및 요청 수락을 관리한다
 	// FullSync, FastSync, LightSync
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(checkpoint, mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer, manager.reportDownload)
 
 	validator := func(header *types.Header) error {
 		return engine.VerifyHeader(blockchain, header, true)
@@ -189,7 +203,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return manager.blockchain.InsertChain(blocks)
 	}
 	// 해쉬 어나운스먼트를 베이스로 블록을 검색하는 블록패쳐를 만든다
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removeInvalidPeer)
 
 	return manager, nil
 }
@@ -257,6 +271,7 @@ func (pm *ProtocolManager) Stop() {
 	// sessions which are already established but not added to pm.peers yet
 	// will exit when they try to register.
 	pm.peers.Close()
+	pm.snapPeers.Close()
 
 	// Wait for all peer handler goroutines and the loops to come down.
 	pm.wg.Wait()
@@ -698,7 +713,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		for _, err := range pm.txpool.AddRemotes(txs) {
+			if invalidTransaction(err) {
+				pm.reportPeer(p.id, InvalidTransaction)
+				break
+			}
+		}
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
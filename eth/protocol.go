@@ -45,6 +45,22 @@ var ProtocolLengths = []uint64{17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
+// Constants to match up snapshot protocol versions and messages
+const (
+	snap1 = 1
+)
+
+// SnapProtocolName is the official short name of the snapshot satellite protocol
+// used during capability negotiation.
+var SnapProtocolName = "snap"
+
+// SnapProtocolVersions are the supported versions of the snap protocol (first is primary).
+var SnapProtocolVersions = []uint{snap1}
+
+// SnapProtocolLengths are the number of implemented message corresponding to different
+// snap protocol versions.
+var SnapProtocolLengths = []uint64{6}
+
 // eth protocol message codes
 const (
 	// Protocol messages belonging to eth/62
@@ -64,6 +80,17 @@ const (
 	ReceiptsMsg    = 0x10
 )
 
+// snap protocol message codes
+const (
+	// Protocol messages belonging to snap/1
+	GetAccountRangeMsg  = 0x00
+	AccountRangeMsg     = 0x01
+	GetStorageRangesMsg = 0x02
+	StorageRangesMsg    = 0x03
+	GetByteCodesMsg     = 0x04
+	ByteCodesMsg        = 0x05
+)
+
 type errCode int
 
 const (
@@ -181,3 +208,61 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// getAccountRangeData represents an account range query.
+type getAccountRangeData struct {
+	ID     uint64      // Request ID to match up responses with
+	Root   common.Hash // Root hash of the account trie to serve
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// accountRangeData is the network packet for an account range response.
+type accountRangeData struct {
+	ID       uint64         // ID of the request this is a response for
+	Accounts []*accountData // List of consecutive accounts from the trie
+	Proof    [][]byte       // List of trie nodes proving the account range
+}
+
+// accountData represents a single account in an account range response.
+type accountData struct {
+	Hash common.Hash  // Hash of the account
+	Body rlp.RawValue // Account body in consensus RLP format
+}
+
+// getStorageRangesData represents a storage slot range query.
+type getStorageRangesData struct {
+	ID       uint64        // Request ID to match up responses with
+	Root     common.Hash   // Root hash of the account trie to serve
+	Accounts []common.Hash // Account hashes of the storage tries to serve
+	Origin   []byte        // Hash of the first storage slot to retrieve (large contract mode)
+	Limit    []byte        // Hash of the last storage slot to retrieve (large contract mode)
+	Bytes    uint64        // Soft limit at which to stop returning data
+}
+
+// storageRangesData is the network packet for a storage range response.
+type storageRangesData struct {
+	ID    uint64           // ID of the request this is a response for
+	Slots [][]*storageData // Lists of consecutive storage slots for the requested accounts
+	Proof [][]byte         // Merkle proofs for the *last* slot range, if it's incomplete
+}
+
+// storageData represents a single storage slot in a storage range response.
+type storageData struct {
+	Hash common.Hash // Hash of the storage slot
+	Body []byte      // Data content of the slot
+}
+
+// getByteCodesData represents a contract bytecode query.
+type getByteCodesData struct {
+	ID     uint64        // Request ID to match up responses with
+	Hashes []common.Hash // Code hashes to retrieve the code for
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// byteCodesData is the network packet for a contract bytecode response.
+type byteCodesData struct {
+	ID    uint64   // ID of the request this is a response for
+	Codes [][]byte // Requested contract bytecodes in request order, unknown ones skipped
+}
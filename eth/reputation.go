@@ -0,0 +1,148 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// PeerEvent is a behaviour of a remote peer influencing its reputation.
+type PeerEvent int
+
+const (
+	UsefulDelivery     PeerEvent = iota // Peer delivered requested data that was accepted
+	UselessDelivery                     // Peer delivered nothing or nothing usable
+	RequestTimeout                      // Peer failed to answer a request in time
+	InvalidData                         // Peer sent invalid blocks or state data
+	InvalidTransaction                  // Peer sent transactions that can never be valid
+)
+
+// String implements fmt.Stringer.
+func (ev PeerEvent) String() string {
+	switch ev {
+	case UsefulDelivery:
+		return "useful delivery"
+	case UselessDelivery:
+		return "useless delivery"
+	case RequestTimeout:
+		return "request timeout"
+	case InvalidData:
+		return "invalid data"
+	case InvalidTransaction:
+		return "invalid transaction"
+	default:
+		return "unknown event"
+	}
+}
+
+// PeerScorer converts the behaviours of remote peers into reputation changes.
+// Peers whose reputation drops below p2p.ReputationThreshold are disconnected
+// and not dialed again.
+type PeerScorer interface {
+	// Score returns the reputation change of a peer exhibiting the given event.
+	Score(event PeerEvent) int64
+}
+
+// DefaultPeerScorer is the peer scoring policy used unless a custom one is set:
+// useful deliveries slowly build reputation, stalling or useless peers quickly
+// lose it, while peers sending invalid data are dropped right away.
+var DefaultPeerScorer PeerScorer = defaultPeerScorer{}
+
+type defaultPeerScorer struct{}
+
+// Score implements PeerScorer.
+func (defaultPeerScorer) Score(event PeerEvent) int64 {
+	switch event {
+	case UsefulDelivery:
+		return 1
+	case UselessDelivery:
+		return -2
+	case RequestTimeout:
+		return -10
+	case InvalidData:
+		return -200
+	case InvalidTransaction:
+		return -20
+	default:
+		return 0
+	}
+}
+
+// SetPeerScorer replaces the policy used to score the behaviour of peers. A nil
+// scorer restores the default one.
+func (pm *ProtocolManager) SetPeerScorer(scorer PeerScorer) {
+	if scorer == nil {
+		scorer = DefaultPeerScorer
+	}
+	pm.scorerLock.Lock()
+	defer pm.scorerLock.Unlock()
+
+	pm.scorer = scorer
+}
+
+// reportPeer adjusts the reputation of a peer based on an observed behaviour.
+func (pm *ProtocolManager) reportPeer(id string, event PeerEvent) {
+	peer := pm.peers.Peer(id)
+	if peer == nil {
+		return
+	}
+	pm.scorerLock.RLock()
+	delta := pm.scorer.Score(event)
+	pm.scorerLock.RUnlock()
+
+	if delta != 0 {
+		score := peer.Peer.AdjustReputation(delta)
+		peer.Log().Trace("Adjusted peer reputation", "event", event, "delta", delta, "reputation", score)
+	}
+}
+
+// reportDownload adjusts the reputation of a peer based on the verdict of the
+// downloader on its response.
+func (pm *ProtocolManager) reportDownload(id string, feedback downloader.PeerFeedback) {
+	switch feedback {
+	case downloader.UsefulResponse:
+		pm.reportPeer(id, UsefulDelivery)
+	case downloader.UselessResponse:
+		pm.reportPeer(id, UselessDelivery)
+	case downloader.ResponseTimeout:
+		pm.reportPeer(id, RequestTimeout)
+	case downloader.InvalidResponse:
+		pm.reportPeer(id, InvalidData)
+	default:
+		log.Warn("Unknown download feedback", "peer", id, "feedback", feedback)
+	}
+}
+
+// removeInvalidPeer penalises a peer that propagated invalid blocks and drops it.
+func (pm *ProtocolManager) removeInvalidPeer(id string) {
+	pm.reportPeer(id, InvalidData)
+	pm.removePeer(id)
+}
+
+// invalidTransaction reports whether a transaction pool error means that the
+// transaction could never have been valid, as opposed to being only stale or
+// underpriced from the local node's point of view.
+func invalidTransaction(err error) bool {
+	switch err {
+	case core.ErrInvalidSender, core.ErrIntrinsicGas, core.ErrNegativeValue, core.ErrOversizedData:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/eth/downloader"
+)
+
+// flatPeerScorer is a peer scoring policy punishing every event equally.
+type flatPeerScorer int64
+
+func (s flatPeerScorer) Score(event PeerEvent) int64 { return int64(s) }
+
+// Tests that peer behaviours are converted into reputation changes by the
+// configured peer scorer.
+func TestPeerScoring(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	p, _ := newTestPeer("peer", eth63, pm, true)
+	defer p.close()
+
+	// Wait until the peer is registered after the handshake
+	for i := 0; pm.peers.Peer(p.id) == nil; i++ {
+		if i == 100 {
+			t.Fatalf("peer not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Report a few events through the default scorer
+	pm.reportPeer(p.id, UsefulDelivery)
+	pm.reportDownload(p.id, downloader.UsefulResponse)
+	pm.reportDownload(p.id, downloader.ResponseTimeout)
+
+	want := 2*DefaultPeerScorer.Score(UsefulDelivery) + DefaultPeerScorer.Score(RequestTimeout)
+	if have := p.Peer.Reputation(); have != want {
+		t.Fatalf("reputation mismatch: have %d, want %d", have, want)
+	}
+	// Plug in a custom scorer and ensure it's used
+	pm.SetPeerScorer(flatPeerScorer(-3))
+	pm.reportPeer(p.id, UsefulDelivery)
+
+	if have := p.Peer.Reputation(); have != want-3 {
+		t.Fatalf("custom reputation mismatch: have %d, want %d", have, want-3)
+	}
+	// Reports about unknown peers should be ignored
+	pm.reportPeer("unknown", InvalidData)
+}
@@ -0,0 +1,462 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	maxStorageServe = 1024 // Maximum number of storage tries to serve in a single range query
+	maxCodeServe    = 1024 // Maximum number of contract codes to serve in a single query
+)
+
+// SnapPeerInfo represents a short summary of the snapshot sub-protocol metadata
+// known about a connected peer.
+type SnapPeerInfo struct {
+	Version int `json:"version"` // Snapshot protocol version negotiated
+}
+
+// snapPeer is a remote peer speaking the snapshot satellite protocol, used to
+// serve and retrieve contiguous state ranges.
+type snapPeer struct {
+	id string
+
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	version int // Protocol version negotiated
+}
+
+// newSnapPeer creates a wrapper for a network connection and negotiated protocol
+// version.
+func newSnapPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *snapPeer {
+	id := p.ID()
+
+	return &snapPeer{
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		id:      fmt.Sprintf("%x", id[:8]),
+	}
+}
+
+// Info gathers and returns a collection of metadata known about a peer.
+func (p *snapPeer) Info() *SnapPeerInfo {
+	return &SnapPeerInfo{Version: p.version}
+}
+
+// RequestAccountRange fetches a batch of accounts rooted in a specific account
+// trie, starting with the origin.
+func (p *snapPeer) RequestAccountRange(id uint64, root common.Hash, origin, limit common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching range of accounts", "reqid", id, "root", root, "origin", origin, "limit", limit, "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetAccountRangeMsg, &getAccountRangeData{
+		ID:     id,
+		Root:   root,
+		Origin: origin,
+		Limit:  limit,
+		Bytes:  bytes,
+	})
+}
+
+// RequestStorageRanges fetches a batch of storage slots belonging to one or more
+// accounts. If slots from only one account is requested, an origin marker may
+// also be used to retrieve from there.
+func (p *snapPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error {
+	p.Log().Debug("Fetching ranges of storage slots", "reqid", id, "root", root, "accounts", len(accounts), "origin", common.Bytes2Hex(origin), "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetStorageRangesMsg, &getStorageRangesData{
+		ID:       id,
+		Root:     root,
+		Accounts: accounts,
+		Origin:   origin,
+		Limit:    limit,
+		Bytes:    bytes,
+	})
+}
+
+// RequestByteCodes fetches a batch of contract bytecodes by their code hashes.
+func (p *snapPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching set of byte codes", "reqid", id, "hashes", len(hashes), "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetByteCodesMsg, &getByteCodesData{
+		ID:     id,
+		Hashes: hashes,
+		Bytes:  bytes,
+	})
+}
+
+// snapPeerSet represents the collection of active peers currently participating
+// in the snapshot sub-protocol.
+type snapPeerSet struct {
+	peers  map[string]*snapPeer
+	lock   sync.RWMutex
+	closed bool
+}
+
+// newSnapPeerSet creates a new peer set to track the active snap participants.
+func newSnapPeerSet() *snapPeerSet {
+	return &snapPeerSet{
+		peers: make(map[string]*snapPeer),
+	}
+}
+
+// Register injects a new peer into the working set, or returns an error if the
+// peer is already known.
+func (ps *snapPeerSet) Register(p *snapPeer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errClosed
+	}
+	if _, ok := ps.peers[p.id]; ok {
+		return errAlreadyRegistered
+	}
+	ps.peers[p.id] = p
+	return nil
+}
+
+// Unregister removes a remote peer from the active set.
+func (ps *snapPeerSet) Unregister(id string) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[id]; !ok {
+		return errNotRegistered
+	}
+	delete(ps.peers, id)
+	return nil
+}
+
+// Peer retrieves the registered peer with the given id.
+func (ps *snapPeerSet) Peer(id string) *snapPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return ps.peers[id]
+}
+
+// Close disconnects all peers.
+// No new peers can be registered after Close has returned.
+func (ps *snapPeerSet) Close() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for _, p := range ps.peers {
+		p.Disconnect(p2p.DiscQuitting)
+	}
+	ps.closed = true
+}
+
+// makeSnapProtocol creates the snapshot satellite protocol of the given version.
+func (pm *ProtocolManager) makeSnapProtocol(version uint, length uint64) p2p.Protocol {
+	return p2p.Protocol{
+		Name:    SnapProtocolName,
+		Version: version,
+		Length:  length,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			pm.wg.Add(1)
+			defer pm.wg.Done()
+
+			return pm.handleSnap(newSnapPeer(int(version), p, rw))
+		},
+		PeerInfo: func(id discover.NodeID) interface{} {
+			if p := pm.snapPeers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
+				return p.Info()
+			}
+			return nil
+		},
+	}
+}
+
+// handleSnap is the callback invoked to manage the life cycle of a snap peer.
+// When this function terminates, the peer is disconnected.
+func (pm *ProtocolManager) handleSnap(p *snapPeer) error {
+	p.Log().Debug("Snapshot peer connected", "name", p.Name())
+
+	if err := pm.snapPeers.Register(p); err != nil {
+		p.Log().Debug("Snapshot peer registration failed", "err", err)
+		return err
+	}
+	defer pm.removeSnapPeer(p.id)
+
+	if err := pm.downloader.RegisterSnapPeer(p.id, p); err != nil {
+		return err
+	}
+	for {
+		if err := pm.handleSnapMsg(p); err != nil {
+			p.Log().Debug("Snapshot message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// removeSnapPeer unregisters a snap peer from the downloader and the peer set.
+func (pm *ProtocolManager) removeSnapPeer(id string) {
+	pm.downloader.UnregisterSnapPeer(id)
+	pm.snapPeers.Unregister(id)
+}
+
+// handleSnapMsg is invoked whenever an inbound snapshot protocol message is
+// received from a remote peer. The remote connection is torn down upon
+// returning any error.
+func (pm *ProtocolManager) handleSnapMsg(p *snapPeer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req getAccountRangeData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, AccountRangeMsg, pm.serveAccountRange(&req))
+
+	case AccountRangeMsg:
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		hashes := make([]common.Hash, len(res.Accounts))
+		accounts := make([][]byte, len(res.Accounts))
+		for i, acc := range res.Accounts {
+			hashes[i], accounts[i] = acc.Hash, acc.Body
+		}
+		if err := pm.downloader.DeliverAccountRange(p.id, res.ID, hashes, accounts, res.Proof); err != nil {
+			p.Log().Debug("Failed to deliver account range", "err", err)
+		}
+
+	case GetStorageRangesMsg:
+		var req getStorageRangesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, StorageRangesMsg, pm.serveStorageRanges(&req))
+
+	case StorageRangesMsg:
+		var res storageRangesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		hashes := make([][]common.Hash, len(res.Slots))
+		slots := make([][][]byte, len(res.Slots))
+		for i, list := range res.Slots {
+			hashes[i] = make([]common.Hash, len(list))
+			slots[i] = make([][]byte, len(list))
+			for j, slot := range list {
+				hashes[i][j], slots[i][j] = slot.Hash, slot.Body
+			}
+		}
+		if err := pm.downloader.DeliverStorageRanges(p.id, res.ID, hashes, slots, res.Proof); err != nil {
+			p.Log().Debug("Failed to deliver storage ranges", "err", err)
+		}
+
+	case GetByteCodesMsg:
+		var req getByteCodesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p2p.Send(p.rw, ByteCodesMsg, pm.serveByteCodes(&req))
+
+	case ByteCodesMsg:
+		var res byteCodesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverByteCodes(p.id, res.ID, res.Codes); err != nil {
+			p.Log().Debug("Failed to deliver byte codes", "err", err)
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// serveAccountRange collects the accounts of the requested range from the
+// local state, along with the proofs of the range boundaries. If the state is
+// not available, an empty response without proofs is returned.
+func (pm *ProtocolManager) serveAccountRange(req *getAccountRangeData) *accountRangeData {
+	res := &accountRangeData{ID: req.ID}
+
+	tr, err := trie.New(req.Root, pm.blockchain.StateCache().TrieDB())
+	if err != nil {
+		return res
+	}
+	limit := req.Bytes
+	if limit > softResponseLimit {
+		limit = softResponseLimit
+	}
+	var (
+		size uint64
+		last common.Hash
+	)
+	it := trie.NewIterator(tr.NodeIterator(req.Origin[:]))
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		res.Accounts = append(res.Accounts, &accountData{Hash: hash, Body: common.CopyBytes(it.Value)})
+		last = hash
+
+		size += uint64(common.HashLength + len(it.Value))
+		if bytes.Compare(hash[:], req.Limit[:]) >= 0 || size >= limit {
+			break
+		}
+	}
+	if it.Err != nil {
+		return &accountRangeData{ID: req.ID}
+	}
+	// Generate the Merkle proofs for the first and last account
+	proof := ethdb.NewMemDatabase()
+	if err := tr.Prove(req.Origin[:], 0, proof); err != nil {
+		return &accountRangeData{ID: req.ID}
+	}
+	if len(res.Accounts) > 0 {
+		if err := tr.Prove(last[:], 0, proof); err != nil {
+			return &accountRangeData{ID: req.ID}
+		}
+	}
+	res.Proof = proofList(proof)
+	return res
+}
+
+// serveStorageRanges collects the storage slots of the requested accounts from
+// the local state. Every storage trie is served entirely, apart from the last
+// one which may be cut short by the response size limit, in which case proofs
+// are attached for its boundaries. The origin and limit only apply to the first
+// requested account.
+func (pm *ProtocolManager) serveStorageRanges(req *getStorageRangesData) *storageRangesData {
+	res := &storageRangesData{ID: req.ID}
+
+	triedb := pm.blockchain.StateCache().TrieDB()
+	accTrie, err := trie.New(req.Root, triedb)
+	if err != nil {
+		return res
+	}
+	limit := req.Bytes
+	if limit > softResponseLimit {
+		limit = softResponseLimit
+	}
+	var size uint64
+	for i, account := range req.Accounts {
+		if size >= limit || i >= maxStorageServe {
+			break
+		}
+		blob, err := accTrie.TryGet(account[:])
+		if err != nil || blob == nil {
+			break
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			break
+		}
+		stTrie, err := trie.New(acc.Root, triedb)
+		if err != nil {
+			break
+		}
+		// Retrieve the requested portion of the storage trie
+		origin, end := common.Hash{}, common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		if i == 0 {
+			origin = common.BytesToHash(req.Origin)
+			if len(req.Limit) > 0 {
+				end = common.BytesToHash(req.Limit)
+			}
+		}
+		var (
+			slots   []*storageData
+			partial = origin != (common.Hash{})
+		)
+		it := trie.NewIterator(stTrie.NodeIterator(origin[:]))
+		for it.Next() {
+			hash := common.BytesToHash(it.Key)
+			slots = append(slots, &storageData{Hash: hash, Body: common.CopyBytes(it.Value)})
+
+			size += uint64(common.HashLength + len(it.Value))
+			if bytes.Compare(hash[:], end[:]) >= 0 || size >= limit {
+				partial = true
+				break
+			}
+		}
+		if it.Err != nil {
+			break
+		}
+		res.Slots = append(res.Slots, slots)
+
+		// If the storage trie was only partially served, prove the boundaries
+		// and stop, since only the last range can be partial
+		if partial {
+			proof := ethdb.NewMemDatabase()
+			if err := stTrie.Prove(origin[:], 0, proof); err != nil {
+				return &storageRangesData{ID: req.ID}
+			}
+			if len(slots) > 0 {
+				if err := stTrie.Prove(slots[len(slots)-1].Hash[:], 0, proof); err != nil {
+					return &storageRangesData{ID: req.ID}
+				}
+			}
+			res.Proof = proofList(proof)
+			break
+		}
+	}
+	return res
+}
+
+// serveByteCodes collects the requested contract bytecodes from the local state,
+// skipping the unknown ones.
+func (pm *ProtocolManager) serveByteCodes(req *getByteCodesData) *byteCodesData {
+	res := &byteCodesData{ID: req.ID}
+
+	limit := req.Bytes
+	if limit > softResponseLimit {
+		limit = softResponseLimit
+	}
+	var size uint64
+	for _, hash := range req.Hashes {
+		if size >= limit || len(res.Codes) >= maxCodeServe {
+			break
+		}
+		if code, err := pm.blockchain.TrieNode(hash); err == nil {
+			res.Codes = append(res.Codes, code)
+			size += uint64(len(code))
+		}
+	}
+	return res
+}
+
+// proofList flattens a proof database into the list of its trie nodes.
+func proofList(proof *ethdb.MemDatabase) [][]byte {
+	var nodes [][]byte
+	for _, key := range proof.Keys() {
+		blob, _ := proof.Get(key)
+		nodes = append(nodes, blob)
+	}
+	return nodes
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that account ranges are served along with boundary proofs that can be
+// verified against the requested state root.
+func TestServeAccountRange(t *testing.T) {
+	// Create a chain funding a handful of fresh accounts
+	generator := func(i int, block *core.BlockGen) {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), addr, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+		block.AddTx(tx)
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 16, generator, nil)
+	defer pm.Stop()
+
+	root := pm.blockchain.CurrentBlock().Root()
+	limit := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+	// Request the entire account range and ensure everything is delivered
+	res := pm.serveAccountRange(&getAccountRangeData{ID: 1, Root: root, Limit: limit, Bytes: softResponseLimit})
+	if res.ID != 1 {
+		t.Fatalf("request id mismatch: have %d, want %d", res.ID, 1)
+	}
+	tr, _ := trie.New(root, pm.blockchain.StateCache().TrieDB())
+	accounts := 0
+	for it := trie.NewIterator(tr.NodeIterator(nil)); it.Next(); {
+		accounts++
+	}
+	if len(res.Accounts) != accounts {
+		t.Fatalf("account count mismatch: have %d, want %d", len(res.Accounts), accounts)
+	}
+	if more := verifyAccountRange(t, root, common.Hash{}, res); more {
+		t.Fatalf("full range reported further accounts")
+	}
+	// Request a size capped range and ensure it's a valid partial response
+	res = pm.serveAccountRange(&getAccountRangeData{ID: 2, Root: root, Limit: limit, Bytes: 1})
+	if len(res.Accounts) != 1 {
+		t.Fatalf("capped account count mismatch: have %d, want %d", len(res.Accounts), 1)
+	}
+	if more := verifyAccountRange(t, root, common.Hash{}, res); !more {
+		t.Fatalf("partial range reported no further accounts")
+	}
+	// Continue from the middle of the range
+	origin := res.Accounts[0].Hash
+	res = pm.serveAccountRange(&getAccountRangeData{ID: 3, Root: root, Origin: origin, Limit: limit, Bytes: softResponseLimit})
+	if len(res.Accounts) != accounts {
+		t.Fatalf("continued account count mismatch: have %d, want %d", len(res.Accounts), accounts)
+	}
+	verifyAccountRange(t, root, origin, res)
+
+	// Request an unknown state and ensure an empty response is returned
+	res = pm.serveAccountRange(&getAccountRangeData{ID: 4, Root: common.Hash{0x01}, Limit: limit, Bytes: softResponseLimit})
+	if len(res.Accounts) != 0 || len(res.Proof) != 0 {
+		t.Fatalf("unknown state served: %d accounts, %d proof nodes", len(res.Accounts), len(res.Proof))
+	}
+}
+
+// verifyAccountRange checks a served account range against the state root,
+// returning whether there are further accounts after it.
+func verifyAccountRange(t *testing.T, root common.Hash, origin common.Hash, res *accountRangeData) bool {
+	proof := ethdb.NewMemDatabase()
+	for _, node := range res.Proof {
+		proof.Put(crypto.Keccak256(node), node)
+	}
+	keys := make([][]byte, len(res.Accounts))
+	vals := make([][]byte, len(res.Accounts))
+	for i, acc := range res.Accounts {
+		keys[i], vals[i] = common.CopyBytes(acc.Hash[:]), acc.Body
+	}
+	_, more, err := trie.VerifyRangeProof(root, origin[:], keys, vals, proof)
+	if err != nil {
+		t.Fatalf("failed to verify served range: %v", err)
+	}
+	return more
+}
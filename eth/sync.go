@@ -188,6 +188,9 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		// Fast sync was explicitly requested, and explicitly granted
 		// fast sync가 명백히 요구되었고, 명백히 허가됨
 		mode = downloader.FastSync
+		if atomic.LoadUint32(&pm.snapSync) == 1 {
+			mode = downloader.SnapSync
+		}
 	} else if currentBlock.NumberU64() == 0 && pm.blockchain.CurrentFastBlock().NumberU64() > 0 {
 		// The database seems empty as the current block is the genesis. Yet the fast
 		// block is ahead, so fast sync was enabled for this node at a certain point.
@@ -203,7 +206,7 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		mode = downloader.FastSync
 	}
 
-	if mode == downloader.FastSync {
+	if mode == downloader.FastSync || mode == downloader.SnapSync {
 		// Make sure the peer's total difficulty we are synchronizing is higher.
 		// 우리가 동기화할 피어의 총난이도가 더 높은지 확인한다 
 		if pm.blockchain.GetTdByHash(pm.blockchain.CurrentFastBlock().Hash()).Cmp(pTd) >= 0 {
@@ -219,6 +222,7 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		log.Info("Fast sync complete, auto disabling")
 		atomic.StoreUint32(&pm.fastSync, 0)
+		atomic.StoreUint32(&pm.snapSync, 0)
 	}
 	atomic.StoreUint32(&pm.acceptTxs, 1) // Mark initial sync done
 	if head := pm.blockchain.CurrentBlock(); head.NumberU64() > 0 {
@@ -276,6 +276,7 @@ type rpcProgress struct {
 	HighestBlock  hexutil.Uint64
 	PulledStates  hexutil.Uint64
 	KnownStates   hexutil.Uint64
+	PivotBlock    hexutil.Uint64
 }
 
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
@@ -300,6 +301,7 @@ func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, err
 		HighestBlock:  uint64(progress.HighestBlock),
 		PulledStates:  uint64(progress.PulledStates),
 		KnownStates:   uint64(progress.KnownStates),
+		PivotBlock:    uint64(progress.PivotBlock),
 	}, nil
 }
 
@@ -309,6 +311,59 @@ func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 	return ec.c.EthSubscribe(ctx, ch, "newHeads")
 }
 
+// TransactionStatus is a change of the inclusion status of a transaction sent
+// through a light client. The block position, the receipt and its Merkle path in
+// the receipt trie of the block are only set for included transactions.
+type TransactionStatus struct {
+	Hash        common.Hash
+	Status      string // "pending", "included", "reorged", "lost" or "dropped"
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint
+	Receipt     *types.Receipt
+	ReceiptPath [][]byte
+}
+
+type rpcTxStatus struct {
+	Hash        common.Hash     `json:"transactionHash"`
+	Status      string          `json:"status"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	Index       hexutil.Uint    `json:"transactionIndex"`
+	Receipt     *types.Receipt  `json:"receipt"`
+	ReceiptPath []hexutil.Bytes `json:"receiptPath"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *TransactionStatus) UnmarshalJSON(msg []byte) error {
+	var dec rpcTxStatus
+	if err := json.Unmarshal(msg, &dec); err != nil {
+		return err
+	}
+	*s = TransactionStatus{
+		Hash:        dec.Hash,
+		Status:      dec.Status,
+		BlockHash:   dec.BlockHash,
+		BlockNumber: uint64(dec.BlockNumber),
+		Index:       uint(dec.Index),
+		Receipt:     dec.Receipt,
+	}
+	for _, node := range dec.ReceiptPath {
+		s.ReceiptPath = append(s.ReceiptPath, node)
+	}
+	return nil
+}
+
+// SubscribeTransactionStatus subscribes to the inclusion status changes of the
+// given transactions sent through a light client, or of all of them if no hashes
+// are given.
+func (ec *Client) SubscribeTransactionStatus(ctx context.Context, hashes []common.Hash, ch chan<- *TransactionStatus) (ethereum.Subscription, error) {
+	if hashes == nil {
+		hashes = []common.Hash{}
+	}
+	return ec.c.EthSubscribe(ctx, ch, "transactionStatus", hashes)
+}
+
 // State Access
 
 // NetworkID returns the network ID (also known as the chain ID) for this chain.
@@ -128,6 +128,7 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+	PivotBlock    uint64 // Pivot block whose state is being synced (fast sync only)
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
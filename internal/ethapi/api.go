@@ -101,6 +101,7 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		"pivotBlock":    hexutil.Uint64(progress.PivotBlock),
 	}, nil
 }
 
@@ -141,12 +142,16 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
+// Status returns the number of pending and queued transaction in the pool, and
+// the number of data slots they occupy, which the pool limits are enforced on.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
+	pendingSlots, queueSlots := s.b.TxPoolSlots()
 	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(pending),
-		"queued":  hexutil.Uint(queue),
+		"pending":      hexutil.Uint(pending),
+		"queued":       hexutil.Uint(queue),
+		"pendingSlots": hexutil.Uint(pendingSlots),
+		"queuedSlots":  hexutil.Uint(queueSlots),
 	}
 }
 
@@ -1278,6 +1283,40 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an ECDSA signature for EIP-712 typed structured data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// The account associated with addr must be unlocked, and the domain of the typed
+// data, if bound to a chain, must be bound to the chain of the node.
+//
+// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-712.md
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, typedData TypedData) (hexutil.Bytes, error) {
+	sighash, _, err := typedData.SigningHash()
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := typedData.ChainId()
+	if err != nil {
+		return nil, err
+	}
+	if config := s.b.ChainConfig(); chainId != nil && config.ChainId != nil && chainId.Cmp(config.ChainId) != 0 {
+		return nil, fmt.Errorf("typed data bound to chain %v, node on chain %v", chainId, config.ChainId)
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the requested hash with the wallet
+	signature, err := wallet.SignHash(account, sighash)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
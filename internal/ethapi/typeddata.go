@@ -0,0 +1,516 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// domainType is the name of the type describing the domain of typed data.
+const domainType = "EIP712Domain"
+
+// domainFields are the fields a domain may contain, along with their types.
+var domainFields = map[string]string{
+	"name":              "string",
+	"version":           "string",
+	"chainId":           "uint256",
+	"verifyingContract": "address",
+	"salt":              "bytes32",
+}
+
+// typeNameRegexp matches the valid names of struct types and their fields.
+var typeNameRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+// TypedData is a typed structured data object as defined by EIP-712, hashed and
+// signed in place of an opaque blob of bytes so users know what they are signing.
+//
+// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-712.md
+type TypedData struct {
+	Types       Types                  `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      map[string]interface{} `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// Types are the struct types of typed data, by name.
+type Types map[string][]Type
+
+// Type is a single field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// NameValueType is a field of typed data in a human readable form, shown to the
+// user when approving a signature. The value of a struct field is the list of its
+// own fields and the value of an array is the list of its elements.
+type NameValueType struct {
+	Name  string      `json:"name"`
+	Typ   string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// UnmarshalJSON parses typed data, keeping the numbers of the domain and message
+// as json.Number to avoid losing the precision of 256 bit integers.
+func (typedData *TypedData) UnmarshalJSON(input []byte) error {
+	type typedDataJSON TypedData
+
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+
+	var data typedDataJSON
+	if err := dec.Decode(&data); err != nil {
+		return err
+	}
+	*typedData = TypedData(data)
+	return nil
+}
+
+// Validate checks that the types of typed data are well formed and that both the
+// domain and the message are instances of them.
+func (typedData *TypedData) Validate() error {
+	if _, ok := typedData.Types[domainType]; !ok {
+		return fmt.Errorf("missing %s type", domainType)
+	}
+	for name, fields := range typedData.Types {
+		if !typeNameRegexp.MatchString(name) || isAtomicType(name) || isDynamicType(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		known := make(map[string]bool)
+		for _, field := range fields {
+			if !typeNameRegexp.MatchString(field.Name) {
+				return fmt.Errorf("type %s: invalid field name %q", name, field.Name)
+			}
+			if known[field.Name] {
+				return fmt.Errorf("type %s: duplicate field %q", name, field.Name)
+			}
+			known[field.Name] = true
+
+			base, err := baseType(field.Type)
+			if err != nil {
+				return fmt.Errorf("type %s: field %s: %v", name, field.Name, err)
+			}
+			if _, ok := typedData.Types[base]; !ok && !isAtomicType(base) && !isDynamicType(base) {
+				return fmt.Errorf("type %s: field %s: unknown type %q", name, field.Name, field.Type)
+			}
+		}
+	}
+	for _, field := range typedData.Types[domainType] {
+		if typ, ok := domainFields[field.Name]; !ok || typ != field.Type {
+			return fmt.Errorf("invalid %s field %s %s", domainType, field.Type, field.Name)
+		}
+	}
+	if typedData.PrimaryType == domainType {
+		return fmt.Errorf("primary type can't be %s", domainType)
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok {
+		return fmt.Errorf("unknown primary type %q", typedData.PrimaryType)
+	}
+	return nil
+}
+
+// ChainId returns the chain id the typed data is bound to by its domain, or nil
+// if the domain doesn't specify one.
+func (typedData *TypedData) ChainId() (*big.Int, error) {
+	value, ok := typedData.Domain["chainId"]
+	if !ok {
+		return nil, nil
+	}
+	return parseInteger(value)
+}
+
+// SigningHash validates typed data and calculates the hash to sign for it:
+//   keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+// where the domain separator is hashStruct(domain). It also returns the data the
+// hash was calculated of.
+func (typedData *TypedData) SigningHash() ([]byte, []byte, error) {
+	if err := typedData.Validate(); err != nil {
+		return nil, nil, err
+	}
+	domainSeparator, err := typedData.HashStruct(domainType, typedData.Domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawData := append([]byte("\x19\x01"), append(domainSeparator, messageHash...)...)
+	return crypto.Keccak256(rawData), rawData, nil
+}
+
+// HashStruct calculates hashStruct(data) = keccak256(typeHash ‖ encodeData(data))
+// of a struct of the given type.
+func (typedData *TypedData) HashStruct(primaryType string, data map[string]interface{}) ([]byte, error) {
+	encoded, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// TypeHash calculates the hash of the encoding of a struct type.
+func (typedData *TypedData) TypeHash(primaryType string) []byte {
+	return crypto.Keccak256([]byte(typedData.EncodeType(primaryType)))
+}
+
+// EncodeType encodes a struct type as its name followed by its fields, appending
+// the encodings of the struct types it references, sorted by name, e.g.:
+//   Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (typedData *TypedData) EncodeType(primaryType string) string {
+	deps := typedData.dependencies(primaryType, make(map[string]bool))
+	sort.Strings(deps)
+
+	var encoded bytes.Buffer
+	for _, typ := range append([]string{primaryType}, deps...) {
+		fields := make([]string, len(typedData.Types[typ]))
+		for i, field := range typedData.Types[typ] {
+			fields[i] = field.Type + " " + field.Name
+		}
+		encoded.WriteString(typ + "(" + strings.Join(fields, ",") + ")")
+	}
+	return encoded.String()
+}
+
+// dependencies returns the struct types referenced by a struct type, directly or
+// indirectly, excluding the type itself.
+func (typedData *TypedData) dependencies(primaryType string, found map[string]bool) []string {
+	found[primaryType] = true
+
+	var deps []string
+	for _, field := range typedData.Types[primaryType] {
+		base, _ := baseType(field.Type)
+		if _, ok := typedData.Types[base]; !ok || found[base] {
+			continue
+		}
+		deps = append(deps, base)
+		deps = append(deps, typedData.dependencies(base, found)...)
+	}
+	return deps
+}
+
+// EncodeData encodes a struct of the given type as the hash of its type followed
+// by each of its fields encoded into 32 bytes.
+func (typedData *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := typedData.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", primaryType)
+	}
+	if len(data) != len(fields) {
+		return nil, fmt.Errorf("%s: have %d fields, want %d", primaryType, len(data), len(fields))
+	}
+	encoded := typedData.TypeHash(primaryType)
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing field %s", primaryType, field.Name)
+		}
+		enc, err := typedData.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: field %s: %v", primaryType, field.Name, err)
+		}
+		encoded = append(encoded, enc...)
+	}
+	return encoded, nil
+}
+
+// encodeValue encodes a single value of the given type into 32 bytes. Structs,
+// arrays and dynamic values are encoded as the hash of their contents.
+func (typedData *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	base, length, err := parseArrayType(typ)
+	if err != nil {
+		return nil, err
+	}
+	if length >= 0 {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		if length > 0 && len(items) != length {
+			return nil, fmt.Errorf("invalid %s length %d", typ, len(items))
+		}
+		var encoded []byte
+		for _, item := range items {
+			enc, err := typedData.encodeValue(base, item)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, enc...)
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		return typedData.HashStruct(typ, data)
+	}
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		if flag {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address value %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+	}
+	if !isAtomicType(typ) {
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	if strings.HasPrefix(typ, "bytes") {
+		size, _ := strconv.Atoi(typ[len("bytes"):])
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) != size {
+			return nil, fmt.Errorf("invalid %s length %d", typ, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	if strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint") {
+		number, err := parseInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		signed := strings.HasPrefix(typ, "int")
+		size, _ := strconv.Atoi(strings.TrimPrefix(typ, "u")[len("int"):])
+
+		min, max := new(big.Int), new(big.Int).Lsh(common.Big1, uint(size))
+		if signed {
+			max.Rsh(max, 1)
+			min.Neg(max)
+		}
+		if number.Cmp(min) < 0 || number.Cmp(max) >= 0 {
+			return nil, fmt.Errorf("%s value %v out of range", typ, number)
+		}
+		// Negative values are encoded in two's complement
+		return math.PaddedBigBytes(math.U256(new(big.Int).Set(number)), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// Format returns the domain and the message of typed data in a human readable
+// form, as the fields of the domain and primary types.
+func (typedData *TypedData) Format() []*NameValueType {
+	return []*NameValueType{
+		{Name: "domain", Typ: domainType, Value: typedData.formatData(domainType, typedData.Domain)},
+		{Name: "message", Typ: typedData.PrimaryType, Value: typedData.formatData(typedData.PrimaryType, typedData.Message)},
+	}
+}
+
+// formatData formats the fields of a struct of the given type.
+func (typedData *TypedData) formatData(primaryType string, data map[string]interface{}) []*NameValueType {
+	var output []*NameValueType
+	for _, field := range typedData.Types[primaryType] {
+		output = append(output, &NameValueType{
+			Name:  field.Name,
+			Typ:   field.Type,
+			Value: typedData.formatValue(field.Type, data[field.Name]),
+		})
+	}
+	return output
+}
+
+// formatValue formats a single value of the given type, recursing into structs
+// and arrays.
+func (typedData *TypedData) formatValue(typ string, value interface{}) interface{} {
+	if base, length, err := parseArrayType(typ); err == nil && length >= 0 {
+		items, _ := value.([]interface{})
+		output := make([]interface{}, len(items))
+		for i, item := range items {
+			output[i] = typedData.formatValue(base, item)
+		}
+		return output
+	}
+	if _, ok := typedData.Types[typ]; ok {
+		data, _ := value.(map[string]interface{})
+		return typedData.formatData(typ, data)
+	}
+	switch {
+	case typ == "address":
+		if str, ok := value.(string); ok && common.IsHexAddress(str) {
+			return common.HexToAddress(str).Hex()
+		}
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		if number, err := parseInteger(value); err == nil {
+			return number.String()
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// Pprint returns the field in an indented, human readable form.
+func (nvt *NameValueType) Pprint(depth int) string {
+	var output bytes.Buffer
+	output.WriteString(strings.Repeat("  ", depth))
+
+	switch value := nvt.Value.(type) {
+	case []*NameValueType:
+		output.WriteString(fmt.Sprintf("%s [%s]\n", nvt.Name, nvt.Typ))
+		for _, field := range value {
+			output.WriteString(field.Pprint(depth + 1))
+		}
+	case []interface{}:
+		output.WriteString(fmt.Sprintf("%s [%s]\n", nvt.Name, nvt.Typ))
+		base, _, _ := parseArrayType(nvt.Typ)
+		for i, item := range value {
+			output.WriteString((&NameValueType{Name: strconv.Itoa(i), Typ: base, Value: item}).Pprint(depth + 1))
+		}
+	default:
+		output.WriteString(fmt.Sprintf("%s [%s]: %v\n", nvt.Name, nvt.Typ, value))
+	}
+	return output.String()
+}
+
+// parseArrayType splits a type into the type of its elements and its length,
+// which is 0 for dynamic arrays and -1 if the type is not an array.
+func parseArrayType(typ string) (string, int, error) {
+	if !strings.HasSuffix(typ, "]") {
+		return typ, -1, nil
+	}
+	open := strings.LastIndex(typ, "[")
+	if open <= 0 {
+		return "", 0, fmt.Errorf("invalid array type %q", typ)
+	}
+	if open == len(typ)-2 {
+		return typ[:open], 0, nil
+	}
+	length, err := strconv.Atoi(typ[open+1 : len(typ)-1])
+	if err != nil || length <= 0 {
+		return "", 0, fmt.Errorf("invalid array type %q", typ)
+	}
+	return typ[:open], length, nil
+}
+
+// baseType strips all the array dimensions of a type.
+func baseType(typ string) (string, error) {
+	for {
+		base, length, err := parseArrayType(typ)
+		if err != nil || length < 0 {
+			return base, err
+		}
+		typ = base
+	}
+}
+
+// isAtomicType returns whether a type is encoded in place, e.g. uint256, bytes32,
+// bool or address.
+func isAtomicType(typ string) bool {
+	if typ == "bool" || typ == "address" {
+		return true
+	}
+	for _, prefix := range []string{"uint", "int", "bytes"} {
+		if !strings.HasPrefix(typ, prefix) {
+			continue
+		}
+		size, err := strconv.Atoi(typ[len(prefix):])
+		if err != nil || strconv.Itoa(size) != typ[len(prefix):] {
+			return false
+		}
+		if prefix == "bytes" {
+			return size >= 1 && size <= 32
+		}
+		return size >= 8 && size <= 256 && size%8 == 0
+	}
+	return false
+}
+
+// isDynamicType returns whether a type is encoded as the hash of its contents.
+func isDynamicType(typ string) bool {
+	return typ == "string" || typ == "bytes"
+}
+
+// parseInteger parses an integer value of typed data, given as a JSON number or
+// as a decimal or hexadecimal string, optionally negative. The magnitude of the
+// value is limited to 256 bits, its range is checked by the caller.
+func parseInteger(value interface{}) (*big.Int, error) {
+	var str string
+	switch value := value.(type) {
+	case json.Number:
+		str = string(value)
+	case string:
+		str = value
+	case float64:
+		number, accuracy := big.NewFloat(value).Int(nil)
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("invalid integer value %v", value)
+		}
+		return number, nil
+	case *big.Int:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("invalid integer value %v", value)
+	}
+	negative := strings.HasPrefix(str, "-")
+	magnitude := strings.TrimPrefix(str, "-")
+
+	number, ok := math.ParseBig256(magnitude)
+	if magnitude == "" || !ok || number.Sign() < 0 {
+		return nil, fmt.Errorf("invalid integer value %q", str)
+	}
+	if negative {
+		number.Neg(number)
+	}
+	return number, nil
+}
+
+// parseBytes parses a hex encoded byte value of typed data.
+func parseBytes(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, errors.New("bytes must be hex encoded")
+	}
+	return hexutil.Decode(str)
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mailTypedData is the example of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func newTypedData(t *testing.T, input string) TypedData {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(input), &typedData); err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	return typedData
+}
+
+// Tests that the encodings and hashes of the specification example are computed
+// correctly, and that signing the hash yields the expected signature.
+func TestTypedDataHash(t *testing.T) {
+	typedData := newTypedData(t, mailTypedData)
+
+	if have, want := typedData.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("type encoding mismatch: have %s, want %s", have, want)
+	}
+	if have, want := hexutil.Encode(typedData.TypeHash("Mail")), "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"; have != want {
+		t.Errorf("type hash mismatch: have %s, want %s", have, want)
+	}
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain)
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if have, want := hexutil.Encode(domainSeparator), "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; have != want {
+		t.Errorf("domain separator mismatch: have %s, want %s", have, want)
+	}
+	messageHash, err := typedData.HashStruct("Mail", typedData.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if have, want := hexutil.Encode(messageHash), "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"; have != want {
+		t.Errorf("message hash mismatch: have %s, want %s", have, want)
+	}
+	sighash, rawData, err := typedData.SigningHash()
+	if err != nil {
+		t.Fatalf("failed to calculate signing hash: %v", err)
+	}
+	if have, want := hexutil.Encode(sighash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; have != want {
+		t.Errorf("signing hash mismatch: have %s, want %s", have, want)
+	}
+	if !bytes.HasPrefix(rawData, []byte("\x19\x01")) || len(rawData) != 66 {
+		t.Errorf("signed data malformed: %x", rawData)
+	}
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if addr := crypto.PubkeyToAddress(key.PublicKey); addr != common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826") {
+		t.Fatalf("signer address mismatch: have %x", addr)
+	}
+	sig, err := crypto.Sign(sighash, key)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	want := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b9156201"
+	if have := hexutil.Encode(sig); have != want {
+		t.Errorf("signature mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that malformed typed data is rejected.
+func TestTypedDataValidation(t *testing.T) {
+	tests := []struct {
+		old, new string // Replacement to apply on the specification example
+		err      string
+	}{
+		{`"EIP712Domain"`, `"Domain"`, "missing EIP712Domain type"},
+		{`"primaryType": "Mail"`, `"primaryType": "Letter"`, "unknown primary type"},
+		{`"primaryType": "Mail"`, `"primaryType": "EIP712Domain"`, "primary type can't be"},
+		{`"type": "Person"}`, `"type": "Human"}`, "unknown type"},
+		{`"type": "address"}
+		]`, `"type": "uint7"}
+		]`, "unknown type"},
+		{`{"name": "version", "type": "string"}`, `{"name": "version", "type": "uint256"}`, "invalid EIP712Domain field"},
+		{`{"name": "name", "type": "string"},
+			{"name": "wallet"`, `{"name": "wallet", "type": "string"},
+			{"name": "wallet"`, "duplicate field"},
+		{`"Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"`, `"Cow", "wallet": "0xCD2a3d9F"`, "invalid address value"},
+		{`"Cow", "wallet"`, `"Cow", "pet": "dog", "wallet"`, "have 3 fields, want 2"},
+		{`"contents": "Hello, Bob!"`, `"content": "Hello, Bob!"`, "missing field contents"},
+		{`"chainId": 1`, `"chainId": -1`, "uint256 value -1 out of range"},
+		{`"chainId": 1`, `"chainId": "one"`, "invalid integer value"},
+	}
+	for i, tt := range tests {
+		if !strings.Contains(mailTypedData, tt.old) {
+			t.Fatalf("test %d: replaced text not found", i)
+		}
+		typedData := newTypedData(t, strings.Replace(mailTypedData, tt.old, tt.new, 1))
+		if _, _, err := typedData.SigningHash(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %s", i, err, tt.err)
+		}
+	}
+}
+
+// Tests the encoding of the atomic types and arrays.
+func TestTypedDataEncodeValue(t *testing.T) {
+	typedData := TypedData{Types: Types{}}
+
+	tests := []struct {
+		typ   string
+		value interface{}
+		want  string
+	}{
+		{"bool", true, "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{"uint8", json.Number("0xff"), "0x00000000000000000000000000000000000000000000000000000000000000ff"},
+		{"uint8", json.Number("256"), ""},
+		{"int8", json.Number("-128"), "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80"},
+		{"int8", json.Number("128"), ""},
+		{"int8", json.Number("-129"), ""},
+		{"int8", "-0x7f", "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff81"},
+		{"int8", "0x-7f", ""},
+		{"int8", "--1", ""},
+		{"int256", json.Number("-1"), "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"int256", "-0x8000000000000000000000000000000000000000000000000000000000000000", "0x8000000000000000000000000000000000000000000000000000000000000000"},
+		{"int256", "0x8000000000000000000000000000000000000000000000000000000000000000", ""},
+		{"uint8", json.Number("-1"), ""},
+		{"bytes4", "0x01020304", "0x0102030400000000000000000000000000000000000000000000000000000000"},
+		{"bytes4", "0x0102030405", ""},
+		{"bytes4", "0x010203", ""},
+		{"bytes1", "0x", ""},
+		{"bytes", "0x", "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"string", "", "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"uint256[2]", []interface{}{json.Number("1")}, ""},
+		{"uint256[][]", []interface{}{[]interface{}{}}, hexutil.Encode(crypto.Keccak256(crypto.Keccak256()))},
+	}
+	for i, tt := range tests {
+		enc, err := typedData.encodeValue(tt.typ, tt.value)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("test %d: %s %v: expected error, have %x", i, tt.typ, tt.value, enc)
+		case tt.want != "" && err != nil:
+			t.Errorf("test %d: %s %v: failed to encode: %v", i, tt.typ, tt.value, err)
+		case tt.want != "" && hexutil.Encode(enc) != tt.want:
+			t.Errorf("test %d: %s %v: encoding mismatch: have %x, want %s", i, tt.typ, tt.value, enc, tt.want)
+		}
+	}
+}
@@ -19,10 +19,13 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":      Admin_JS,
+	"bft":        BFT_JS,
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
 	"eth":        Eth_JS,
+	"ethash":     Ethash_JS,
+	"les":        LES_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -89,6 +92,12 @@ web3._extend({
 			call: 'clique_getSignersAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'status',
+			call: 'clique_status',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'propose',
 			call: 'clique_propose',
@@ -109,6 +118,67 @@ web3._extend({
 });
 `
 
+const BFT_JS = `
+web3._extend({
+	property: 'bft',
+	methods: [
+		new web3._extend.Method({
+			name: 'getSnapshot',
+			call: 'bft_getSnapshot',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getValidators',
+			call: 'bft_getValidators',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorsAtHash',
+			call: 'bft_getValidatorsAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'propose',
+			call: 'bft_propose',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'discard',
+			call: 'bft_discard',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'proposals',
+			getter: 'bft_proposals'
+		}),
+	]
+});
+`
+
+const Ethash_JS = `
+web3._extend({
+	property: 'ethash',
+	methods: [
+		new web3._extend.Method({
+			name: 'generateDAG',
+			call: 'ethash_generateDAG',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'progress',
+			getter: 'ethash_getProgress'
+		}),
+	]
+});
+`
+
 const Admin_JS = `
 web3._extend({
 	property: 'admin',
@@ -400,6 +470,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'eth_signTypedData',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'resend',
 			call: 'eth_resend',
@@ -449,6 +525,41 @@ web3._extend({
 });
 `
 
+const LES_JS = `
+web3._extend({
+	property: 'les',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'clientInfo',
+			call: 'les_clientInfo',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'setClientParams',
+			call: 'les_setClientParams',
+			params: 2
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'serverInfo',
+			getter: 'les_serverInfo'
+		}),
+		new web3._extend.Property({
+			name: 'costTable',
+			getter: 'les_costTable'
+		}),
+		new web3._extend.Property({
+			name: 'requestStats',
+			getter: 'les_requestStats'
+		}),
+	]
+});
+`
+
 const Miner_JS = `
 web3._extend({
 	property: 'miner',
@@ -480,6 +591,17 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'setGasLimit',
+			call: 'miner_setGasLimit',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'setRecommitInterval',
+			call: 'miner_setRecommitInterval',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
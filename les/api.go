@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// txStatusChanSize is the size of the channels listening to transaction status
+// events of RPC subscriptions.
+const txStatusChanSize = 256
+
+// PrivateLightServerAPI provides an API to manage the clients served by a light
+// server.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new API for the given light server.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// ServerInfo returns the capacity usage of the server.
+func (api *PrivateLightServerAPI) ServerInfo() map[string]interface{} {
+	total, used, priority, free, prio := api.server.clientPool.stats()
+	return map[string]interface{}{
+		"totalCapacity":      total,
+		"usedCapacity":       used,
+		"priorityCapacity":   priority,
+		"freeClientCapacity": api.server.defParams.MinRecharge,
+		"freeClients":        free,
+		"priorityClients":    prio,
+	}
+}
+
+// CostTable returns the request costs currently advertised to the clients.
+func (api *PrivateLightServerAPI) CostTable() map[string]map[string]uint64 {
+	table := make(map[string]map[string]uint64)
+	for _, e := range api.server.fcCostStats.advertisedList() {
+		table[requestNames[e.MsgCode]] = map[string]uint64{
+			"baseCost": e.BaseCost,
+			"reqCost":  e.ReqCost,
+		}
+	}
+	return table
+}
+
+// RequestStats returns the number of served requests and their measured serving
+// costs per request type.
+func (api *PrivateLightServerAPI) RequestStats() map[string]requestUsage {
+	return api.server.fcCostStats.usageStats()
+}
+
+// ClientInfo returns the state of the given clients, or of all priority clients
+// if no IDs are given.
+func (api *PrivateLightServerAPI) ClientInfo(ids []discover.NodeID) map[discover.NodeID]clientInfo {
+	if len(ids) == 0 {
+		ids = api.server.clientPool.priorityClients()
+	}
+	res := make(map[discover.NodeID]clientInfo, len(ids))
+	for _, id := range ids {
+		res[id] = api.server.clientPool.info(id)
+	}
+	return res
+}
+
+// SetClientParams sets the parameters of the given clients. The only supported
+// parameter is "capacity", the guaranteed minimum recharge rate of a priority
+// client; a capacity of zero turns the clients back into free ones. Connected
+// clients are disconnected if their capacity changes, to reconnect with their
+// new flow control parameters.
+func (api *PrivateLightServerAPI) SetClientParams(ids []discover.NodeID, params map[string]interface{}) error {
+	var capacity uint64
+	for name, value := range params {
+		switch name {
+		case "capacity":
+			v, ok := value.(float64)
+			if !ok || v < 0 || v != float64(uint64(v)) {
+				return fmt.Errorf("invalid capacity %v", value)
+			}
+			capacity = uint64(v)
+		default:
+			return fmt.Errorf("unknown client parameter %q", name)
+		}
+	}
+	if _, ok := params["capacity"]; !ok {
+		return nil
+	}
+	for _, id := range ids {
+		if err := api.server.clientPool.setCapacity(id, capacity); err != nil {
+			return fmt.Errorf("client %x: %v", id[:8], err)
+		}
+	}
+	return nil
+}
+
+// PublicTxStatusAPI provides subscriptions to the inclusion status of the
+// transactions sent by a light client.
+type PublicTxStatusAPI struct {
+	pool *light.TxPool
+}
+
+// NewPublicTxStatusAPI creates a new transaction status API for the given pool.
+func NewPublicTxStatusAPI(pool *light.TxPool) *PublicTxStatusAPI {
+	return &PublicTxStatusAPI{pool: pool}
+}
+
+// rpcTxStatus is the RPC representation of a transaction status change.
+type rpcTxStatus struct {
+	Hash        common.Hash     `json:"transactionHash"`
+	Status      string          `json:"status"`
+	BlockHash   *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	Index       *hexutil.Uint   `json:"transactionIndex,omitempty"`
+	Receipt     *types.Receipt  `json:"receipt,omitempty"`
+	ReceiptPath []hexutil.Bytes `json:"receiptPath,omitempty"`
+}
+
+// newRPCTxStatus converts a transaction status event into its RPC representation.
+func newRPCTxStatus(ev light.TxStatusEvent) *rpcTxStatus {
+	res := &rpcTxStatus{Hash: ev.Hash, Status: ev.Status.String()}
+	if ev.Status == light.TxTrackIncluded {
+		number, index := hexutil.Uint64(ev.BlockNumber), hexutil.Uint(ev.Index)
+		res.BlockHash, res.BlockNumber, res.Index = &ev.BlockHash, &number, &index
+		res.Receipt = ev.Receipt
+		for _, node := range ev.ReceiptPath {
+			res.ReceiptPath = append(res.ReceiptPath, hexutil.Bytes(node))
+		}
+	}
+	return res
+}
+
+// TransactionStatus creates a subscription that is notified whenever one of the
+// given local transactions, or any of them if none are given, becomes pending,
+// is included in a block, is rolled back by a reorg, is resent after the servers
+// lost it or is dropped. Inclusions are reported with the receipt of the
+// transaction and its Merkle path in the receipt trie of the block.
+func (api *PublicTxStatusAPI) TransactionStatus(ctx context.Context, hashes []common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	filter := make(map[common.Hash]bool, len(hashes))
+	for _, hash := range hashes {
+		filter[hash] = true
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan light.TxStatusEvent, txStatusChanSize)
+		sub := api.pool.SubscribeTxStatusEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if len(filter) == 0 || filter[ev.Hash] {
+					notifier.Notify(rpcSub.ID, newRPCTxStatus(ev))
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -103,11 +103,40 @@ func (b *LesApiBackend) GetTd(hash common.Hash) *big.Int {
 }
 
 func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	b.prefetchState(ctx, msg, header)
+
 	state.SetBalance(msg.From(), math.MaxBig256)
 	context := core.NewEVMContext(msg, header, b.eth.blockchain, nil)
 	return vm.NewEVM(context, state, b.eth.chainConfig, vmCfg), state.Error, nil
 }
 
+// prefetchState retrieves the state accessed by the message in a few batched
+// round trips, instead of one by one during the execution. The speculative
+// executions are limited to the gas limit of the header and are aborted when
+// the context is cancelled.
+func (b *LesApiBackend) prefetchState(ctx context.Context, msg core.Message, header *types.Header) {
+	if msg.Gas() > header.GasLimit {
+		msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), header.GasLimit, msg.GasPrice(), msg.Data(), false)
+	}
+	light.PrefetchState(ctx, header, b.eth.odr, func(statedb *state.StateDB) {
+		statedb.SetBalance(msg.From(), math.MaxBig256)
+		context := core.NewEVMContext(msg, header, b.eth.blockchain, nil)
+		evm := vm.NewEVM(context, statedb, b.eth.chainConfig, vm.Config{})
+
+		// Cancel the execution if the context is done before it finishes
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()))
+	})
+}
+
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	return b.eth.txPool.Add(ctx, signedTx)
 }
@@ -132,6 +161,10 @@ func (b *LesApiBackend) Stats() (pending int, queued int) {
 	return b.eth.txPool.Stats(), 0
 }
 
+func (b *LesApiBackend) TxPoolSlots() (pending int, queued int) {
+	return b.Stats()
+}
+
 func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	return b.eth.txPool.Content()
 }
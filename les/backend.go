@@ -45,6 +45,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/params"
 	rpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/golang-lru"
 )
 
 type LightEthereum struct {
@@ -63,10 +64,12 @@ type LightEthereum struct {
 	serverPool      *serverPool
 	reqDist         *requestDistributor
 	retriever       *retrieveManager
+	ulc             *ulc // nil unless running as an ultra light client
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
 
 	bloomRequests                              chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomBitsCache                             *lru.Cache                     // Cache of the retrieved bloom bit vectors
 	bloomIndexer, chtIndexer, bloomTrieIndexer *core.ChainIndexer
 
 	ApiBackend *LesApiBackend
@@ -128,6 +131,13 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	}
 	//txrelay.go참조
 	//피어들에게 notify할 릴레이 채널 생성
+	leth.bloomBitsCache, _ = lru.New(bloomBitsCacheSize)
+	if config.ULC != nil {
+		if leth.ulc, err = newULC(config.ULC); err != nil {
+			return nil, err
+		}
+		log.Info("Running as ultra light client", "servers", len(leth.ulc.servers), "fraction", leth.ulc.fraction)
+	}
 	leth.relay = NewLesTxRelay(peers, leth.reqDist)
 	//serverpool.go참조
 	//서버풀은 이미 알려졌거나 새롭게 발견된 라이트 서버 노드를 저장한다. 
@@ -141,7 +151,12 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	leth.odr = NewLesOdr(chainDb, leth.chtIndexer, leth.bloomTrieIndexer, leth.bloomIndexer, leth.retriever)
 	//light/lightchain.go
 	//라이트 체인을 받아온다.(내부적으로 체인을 검증함)
-	if leth.blockchain, err = light.NewLightChain(leth.odr, leth.chainConfig, leth.engine); err != nil {
+	// Ultra light clients leave the verification of the headers to their trusted servers
+	engine := leth.engine
+	if leth.ulc != nil {
+		engine = ulcEngine{engine}
+	}
+	if leth.blockchain, err = light.NewLightChain(leth.odr, leth.chainConfig, engine, config.Checkpoint); err != nil {
 		return nil, err
 	}
 	leth.bloomIndexer.Start(leth.blockchain)
@@ -158,7 +173,7 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	//hander.go
 	//이더리움 서브 프로토콜 메니져 생성
 	//이 매니져는 이더리움 네트워크와 호환 가능한 피어들을 관리한다 - quick sync 등등
-	if leth.protocolManager, err = NewProtocolManager(leth.chainConfig, true, ClientProtocolVersions, config.NetworkId, leth.eventMux, leth.engine, leth.peers, leth.blockchain, nil, chainDb, leth.odr, leth.relay, quitSync, &leth.wg); err != nil {
+	if leth.protocolManager, err = NewProtocolManager(leth.chainConfig, true, ClientProtocolVersions, config.NetworkId, leth.eventMux, leth.engine, leth.peers, leth.blockchain, nil, chainDb, leth.blockchain.Checkpoint(), leth.odr, leth.relay, leth.ulc, quitSync, &leth.wg); err != nil {
 		return nil, err
 	}
 	leth.ApiBackend = &LesApiBackend{leth, nil}
@@ -226,6 +241,11 @@ func (s *LightEthereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
 			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicTxStatusAPI(s.txPool),
+			Public:    true,
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -271,6 +291,11 @@ func (s *LightEthereum) Start(srvr *p2p.Server) error {
 	//서버풀 스타트
 	//이안에서 DiscV5 프로토콜을 통해 RLPx노드들을 찾는다
 	s.serverPool.start(srvr, lesTopic(s.blockchain.Genesis().Hash(), protocolVersion))
+	if s.ulc != nil {
+		for _, node := range s.ulc.servers {
+			srvr.AddPeer(node)
+		}
+	}
 	//hander.go
 	//프로토콜 매니저 스타트
 	//내부적으로 syncer가 돌면서 블럭과 해시를 동기화함
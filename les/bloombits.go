@@ -19,8 +19,12 @@ package les
 import (
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/bitutil"
+	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -39,6 +43,11 @@ const (
 	// bloomRetrievalWait is the maximum time to wait for enough bloom bit requests
 	// to accumulate request an entire batch (avoiding hysteresis).
 	bloomRetrievalWait = time.Microsecond * 100
+
+	// bloomBitsCacheSize is the number of decompressed bloom bit vectors to keep
+	// in memory, saving repeated database reads and network retrievals of the
+	// sections scanned by log filters.
+	bloomBitsCacheSize = 2048
 )
 
 // startBloomHandlers starts a batch of goroutines to accept bloom bit database
@@ -53,22 +62,57 @@ func (eth *LightEthereum) startBloomHandlers() {
 
 				case request := <-eth.bloomRequests:
 					task := <-request
-					task.Bitsets = make([][]byte, len(task.Sections))
-					compVectors, err := light.GetBloomBits(task.Context, eth.odr, task.Bit, task.Sections)
-					if err == nil {
-						for i := range task.Sections {
-							if blob, err := bitutil.DecompressBytes(compVectors[i], int(light.BloomTrieFrequency/8)); err == nil {
-								task.Bitsets[i] = blob
-							} else {
-								task.Error = err
-							}
-						}
-					} else {
-						task.Error = err
-					}
+					retrieveBloomBits(eth.odr, eth.bloomBitsCache, task)
 					request <- task
 				}
 			}
 		}()
 	}
 }
+
+// bloomBitsKey identifies a bloom bit vector in the cache.
+type bloomBitsKey struct {
+	bit         uint
+	section     uint64
+	sectionHead common.Hash
+}
+
+// retrieveBloomBits fills the bitsets of a bloom bit retrieval task. Vectors not
+// found in the cache are read from the database, or retrieved from the BloomTrie
+// of light servers if not available locally, and cached afterwards.
+func retrieveBloomBits(odr light.OdrBackend, cache *lru.Cache, task *bloombits.Retrieval) {
+	task.Bitsets = make([][]byte, len(task.Sections))
+
+	var (
+		keys    = make([]bloomBitsKey, len(task.Sections))
+		missing []uint64
+		indices []int
+	)
+	for i, section := range task.Sections {
+		head := rawdb.ReadCanonicalHash(odr.Database(), (section+1)*light.BloomTrieFrequency-1)
+		keys[i] = bloomBitsKey{bit: task.Bit, section: section, sectionHead: head}
+		if blob, ok := cache.Get(keys[i]); ok {
+			task.Bitsets[i] = blob.([]byte)
+			continue
+		}
+		missing = append(missing, section)
+		indices = append(indices, i)
+	}
+	if len(missing) == 0 {
+		return
+	}
+	compVectors, err := light.GetBloomBits(task.Context, odr, task.Bit, missing)
+	if err != nil {
+		task.Error = err
+		return
+	}
+	for i, idx := range indices {
+		blob, err := bitutil.DecompressBytes(compVectors[i], int(light.BloomTrieFrequency/8))
+		if err != nil {
+			task.Error = err
+			continue
+		}
+		task.Bitsets[idx] = blob
+		cache.Add(keys[idx], blob)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
+	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/hashicorp/golang-lru"
+)
+
+// Tests that retrieved bloom bit vectors are cached and served from memory on
+// subsequent retrievals.
+func TestBloomBitsCache(t *testing.T) {
+	cache, _ := lru.New(bloomBitsCacheSize)
+
+	// Store a few bloom bit vectors in the database of a light client
+	db := ethdb.NewMemDatabase()
+	vectors := make([][]byte, 2)
+	for i := range vectors {
+		vectors[i] = make([]byte, light.BloomTrieFrequency/8)
+		vectors[i][i] = 0x80
+		rawdb.WriteBloomBits(db, 5, uint64(i), common.Hash{}, bitutil.CompressBytes(vectors[i]))
+	}
+	task := &bloombits.Retrieval{Bit: 5, Sections: []uint64{0, 1}, Context: context.Background()}
+	retrieveBloomBits(NewLesOdr(db, nil, nil, nil, nil), cache, task)
+	if task.Error != nil {
+		t.Fatalf("failed to retrieve bloom bits: %v", task.Error)
+	}
+	for i, bitset := range task.Bitsets {
+		if !bytes.Equal(bitset, vectors[i]) {
+			t.Errorf("section %d: bitset mismatch", i)
+		}
+	}
+	// Retrieve the same vectors without the database, they should come from the cache
+	odr := NewLesOdr(ethdb.NewMemDatabase(), nil, nil, nil, nil)
+
+	task = &bloombits.Retrieval{Bit: 5, Sections: []uint64{1, 0}, Context: context.Background()}
+	retrieveBloomBits(odr, cache, task)
+	if task.Error != nil {
+		t.Fatalf("failed to retrieve cached bloom bits: %v", task.Error)
+	}
+	if !bytes.Equal(task.Bitsets[0], vectors[1]) || !bytes.Equal(task.Bitsets[1], vectors[0]) {
+		t.Errorf("cached bitset mismatch")
+	}
+	// Sections neither cached nor covered by a trusted BloomTrie must fail
+	task = &bloombits.Retrieval{Bit: 5, Sections: []uint64{0, 2}, Context: context.Background()}
+	if retrieveBloomBits(odr, cache, task); task.Error != light.ErrNoTrustedBloomTrie {
+		t.Errorf("uncached section error mismatch: have %v, want %v", task.Error, light.ErrNoTrustedBloomTrie)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SetContractBackend binds the configured checkpoint oracle to the given
+// contract backend and starts following it, adding any newer checkpoint signed
+// by enough trusted signers to the light chain. The next sync then starts from
+// the new checkpoint. It's a noop if no oracle has been configured.
+func (s *LightEthereum) SetContractBackend(backend bind.ContractBackend) {
+	if s.config.CheckpointOracle == nil {
+		return
+	}
+	oracle, err := checkpointoracle.NewCheckpointOracle(s.config.CheckpointOracle, backend)
+	if err != nil {
+		log.Error("Failed to bind checkpoint oracle", "address", s.config.CheckpointOracle.Address, "err", err)
+		return
+	}
+	log.Info("Following checkpoint oracle", "address", s.config.CheckpointOracle.Address, "signers", len(s.config.CheckpointOracle.Signers), "threshold", s.config.CheckpointOracle.Threshold)
+	go oracle.Follow(s.updateCheckpoint, s.shutdownChan)
+}
+
+// updateCheckpoint adds a checkpoint retrieved from the oracle to the light chain
+// and the downloader if it's newer than the one currently in use.
+func (s *LightEthereum) updateCheckpoint(checkpoint *params.TrustedCheckpoint) {
+	if current := s.blockchain.Checkpoint(); current != nil && current.SectionIndex >= checkpoint.SectionIndex {
+		return
+	}
+	s.blockchain.AddTrustedCheckpoint(checkpoint)
+	s.protocolManager.downloader.SetCheckpoint(checkpoint)
+}
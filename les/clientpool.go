@@ -0,0 +1,292 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/les/flowcontrol"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	errCapacityTooLow   = errors.New("capacity is lower than the free client capacity")
+	errCapacityOverflow = errors.New("total priority capacity exceeds the server capacity")
+)
+
+var clientPoolKey = []byte("_lesClientPool")
+
+// clientPool decides which light clients are served and with what flow control
+// parameters. The capacity of a client is its minimum recharge rate, its buffer
+// limit scaling proportionally.
+//
+// Priority clients are assigned a guaranteed capacity by the operator. The sum of
+// the priority capacities never exceeds the total capacity of the server, so a
+// priority client can always connect, kicking free clients if necessary. Free
+// clients share the leftover capacity, each receiving the default parameters.
+type clientPool struct {
+	db         ethdb.Database
+	freeParams flowcontrol.ServerParams // Flow control parameters of free clients
+	totalCap   uint64                   // Capacity shared by all connected clients
+
+	priority  map[discover.NodeID]uint64           // Capacities assigned to priority clients
+	connected map[discover.NodeID]*clientPoolEntry // Clients currently being served
+	usedCap   uint64                               // Capacity of the connected clients
+	lock      sync.Mutex
+}
+
+// clientPoolEntry is a client connected to the pool.
+type clientPoolEntry struct {
+	peer      *peer
+	capacity  uint64
+	priority  bool
+	trusted   bool
+	connected mclock.AbsTime
+}
+
+// clientPoolStoredEntry is the RLP encoding of a priority capacity assignment.
+type clientPoolStoredEntry struct {
+	ID       discover.NodeID
+	Capacity uint64
+}
+
+// newClientPool creates a client pool serving up to maxFreeClients clients with
+// the given default parameters, loading the priority assignments from db.
+func newClientPool(db ethdb.Database, freeParams flowcontrol.ServerParams, maxFreeClients int) *clientPool {
+	pool := &clientPool{
+		db:         db,
+		freeParams: freeParams,
+		totalCap:   freeParams.MinRecharge * uint64(maxFreeClients),
+		priority:   make(map[discover.NodeID]uint64),
+		connected:  make(map[discover.NodeID]*clientPoolEntry),
+	}
+	if db != nil {
+		var list []clientPoolStoredEntry
+		if data, err := db.Get(clientPoolKey); err == nil {
+			if err := rlp.DecodeBytes(data, &list); err != nil {
+				log.Error("Failed to decode light client pool", "err", err)
+			}
+		}
+		for _, e := range list {
+			pool.priority[e.ID] = e.Capacity
+		}
+	}
+	return pool
+}
+
+// params returns the flow control parameters of a client with the given capacity.
+func (pool *clientPool) params(capacity uint64) *flowcontrol.ServerParams {
+	return &flowcontrol.ServerParams{
+		BufLimit:    pool.freeParams.BufLimit / pool.freeParams.MinRecharge * capacity,
+		MinRecharge: capacity,
+	}
+}
+
+// connect admits a client into the pool and returns its flow control parameters.
+// Priority clients push out free clients if there isn't enough capacity left,
+// trusted free clients are admitted even if the server is full.
+func (pool *clientPool) connect(p *peer, trusted bool) (*flowcontrol.ServerParams, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	id := p.ID()
+	if _, ok := pool.connected[id]; ok {
+		return nil, p2p.DiscAlreadyConnected
+	}
+	entry := &clientPoolEntry{peer: p, trusted: trusted, connected: mclock.Now()}
+	if capacity, ok := pool.priority[id]; ok {
+		entry.capacity, entry.priority = capacity, true
+		if pool.usedCap+capacity > pool.totalCap {
+			pool.kickFree(pool.usedCap + capacity - pool.totalCap)
+		}
+	} else {
+		entry.capacity = pool.freeParams.MinRecharge
+		if pool.usedCap+entry.capacity > pool.totalCap && !trusted {
+			return nil, p2p.DiscTooManyPeers
+		}
+	}
+	pool.connected[id] = entry
+	pool.usedCap += entry.capacity
+
+	p.Log().Debug("Light client admitted", "priority", entry.priority, "capacity", entry.capacity)
+	return pool.params(entry.capacity), nil
+}
+
+// disconnect releases the capacity of a client, unless it was already released
+// by kicking it.
+func (pool *clientPool) disconnect(p *peer) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if entry := pool.connected[p.ID()]; entry != nil && entry.peer == p {
+		pool.drop(entry)
+	}
+}
+
+// drop removes a client from the pool and releases its capacity.
+func (pool *clientPool) drop(entry *clientPoolEntry) {
+	delete(pool.connected, entry.peer.ID())
+	pool.usedCap -= entry.capacity
+}
+
+// kickFree disconnects free clients until at least the requested capacity is
+// released. Untrusted clients are kicked first, the longest served ones before
+// the others so that free service rotates between clients. Peers are dropped in
+// the background, as their handlers need the pool lock to terminate.
+func (pool *clientPool) kickFree(capacity uint64) {
+	var free []*clientPoolEntry
+	for _, entry := range pool.connected {
+		if !entry.priority {
+			free = append(free, entry)
+		}
+	}
+	sort.Slice(free, func(i, j int) bool {
+		if free[i].trusted != free[j].trusted {
+			return !free[i].trusted
+		}
+		return free[i].connected < free[j].connected
+	})
+	var released uint64
+	for _, entry := range free {
+		if released >= capacity {
+			break
+		}
+		entry.peer.Log().Debug("Kicking free light client for priority client")
+		pool.drop(entry)
+		released += entry.capacity
+		go entry.peer.Peer.Disconnect(p2p.DiscTooManyPeers)
+	}
+}
+
+// setCapacity assigns a guaranteed capacity to a client, or turns it into a free
+// client if capacity is zero. A connected client whose capacity changes is
+// disconnected, as flow control parameters can only be set during the handshake.
+func (pool *clientPool) setCapacity(id discover.NodeID, capacity uint64) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if capacity != 0 && capacity < pool.freeParams.MinRecharge {
+		return errCapacityTooLow
+	}
+	var sum uint64
+	for other, c := range pool.priority {
+		if other != id {
+			sum += c
+		}
+	}
+	if capacity > pool.totalCap || sum > pool.totalCap-capacity {
+		return errCapacityOverflow
+	}
+	old, ok := pool.priority[id]
+	if capacity == 0 {
+		if !ok {
+			return nil
+		}
+		delete(pool.priority, id)
+	} else {
+		if ok && old == capacity {
+			return nil
+		}
+		pool.priority[id] = capacity
+	}
+	if entry := pool.connected[id]; entry != nil {
+		pool.drop(entry)
+		go entry.peer.Peer.Disconnect(p2p.DiscRequested)
+	}
+	pool.store()
+	return nil
+}
+
+// store persists the priority capacity assignments.
+func (pool *clientPool) store() {
+	if pool.db == nil {
+		return
+	}
+	list := make([]clientPoolStoredEntry, 0, len(pool.priority))
+	for id, capacity := range pool.priority {
+		list = append(list, clientPoolStoredEntry{ID: id, Capacity: capacity})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID.String() < list[j].ID.String()
+	})
+	data, err := rlp.EncodeToBytes(list)
+	if err == nil {
+		err = pool.db.Put(clientPoolKey, data)
+	}
+	if err != nil {
+		log.Error("Failed to store light client pool", "err", err)
+	}
+}
+
+// clientInfo describes the state of a client in the pool.
+type clientInfo struct {
+	Connected bool   `json:"isConnected"`
+	Priority  bool   `json:"isPriority"`
+	Capacity  uint64 `json:"capacity"`
+}
+
+// info returns the state of a client in the pool.
+func (pool *clientPool) info(id discover.NodeID) clientInfo {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	info := clientInfo{Capacity: pool.freeParams.MinRecharge}
+	if capacity, ok := pool.priority[id]; ok {
+		info.Priority, info.Capacity = true, capacity
+	}
+	if entry := pool.connected[id]; entry != nil {
+		info.Connected, info.Capacity = true, entry.capacity
+	}
+	return info
+}
+
+// priorityClients returns the IDs of all clients with an assigned capacity.
+func (pool *clientPool) priorityClients() []discover.NodeID {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	ids := make([]discover.NodeID, 0, len(pool.priority))
+	for id := range pool.priority {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// stats returns the capacity usage of the pool.
+func (pool *clientPool) stats() (total, used, priority uint64, free, prio int) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for _, c := range pool.priority {
+		priority += c
+	}
+	for _, entry := range pool.connected {
+		if entry.priority {
+			prio++
+		} else {
+			free++
+		}
+	}
+	return pool.totalCap, pool.usedCap, priority, free, prio
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/rand"
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/les/flowcontrol"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func newPoolTestPeer() *peer {
+	var id discover.NodeID
+	rand.Read(id[:])
+	return newPeer(lpv2, NetworkId, p2p.NewPeer(id, "client", nil), nil)
+}
+
+// Tests that priority clients get their assigned capacity, kicking free clients
+// if the server is full.
+func TestClientPoolPriority(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	pool := newClientPool(db, flowcontrol.ServerParams{BufLimit: 1000, MinRecharge: 10}, 3)
+
+	prio := newPoolTestPeer()
+	if err := pool.setCapacity(prio.ID(), 5); err != errCapacityTooLow {
+		t.Fatalf("capacity below free capacity: have %v, want %v", err, errCapacityTooLow)
+	}
+	if err := pool.setCapacity(prio.ID(), 40); err != errCapacityOverflow {
+		t.Fatalf("capacity above total capacity: have %v, want %v", err, errCapacityOverflow)
+	}
+	if err := pool.setCapacity(prio.ID(), 20); err != nil {
+		t.Fatalf("failed to set capacity: %v", err)
+	}
+	if err := pool.setCapacity(newPoolTestPeer().ID(), math.MaxUint64-5); err != errCapacityOverflow {
+		t.Fatalf("wrapping capacity: have %v, want %v", err, errCapacityOverflow)
+	}
+	// Fill the server with free clients
+	free := make([]*peer, 3)
+	for i := range free {
+		free[i] = newPoolTestPeer()
+		params, err := pool.connect(free[i], false)
+		if err != nil {
+			t.Fatalf("free client %d rejected: %v", i, err)
+		}
+		if params.BufLimit != 1000 || params.MinRecharge != 10 {
+			t.Fatalf("free client %d params mismatch: have %+v", i, params)
+		}
+	}
+	if _, err := pool.connect(newPoolTestPeer(), false); err != p2p.DiscTooManyPeers {
+		t.Fatalf("free client over capacity: have %v, want %v", err, p2p.DiscTooManyPeers)
+	}
+	if _, err := pool.connect(newPoolTestPeer(), true); err != nil {
+		t.Fatalf("trusted client rejected: %v", err)
+	}
+	// Connect the priority client, all untrusted free clients need to be kicked
+	params, err := pool.connect(prio, false)
+	if err != nil {
+		t.Fatalf("priority client rejected: %v", err)
+	}
+	if params.BufLimit != 2000 || params.MinRecharge != 20 {
+		t.Fatalf("priority client params mismatch: have %+v", params)
+	}
+	total, used, priority, freeCount, prioCount := pool.stats()
+	if total != 30 || used != 30 || priority != 20 || freeCount != 1 || prioCount != 1 {
+		t.Fatalf("pool stats mismatch: total %d, used %d, priority %d, free %d, prio %d", total, used, priority, freeCount, prioCount)
+	}
+	info := pool.info(prio.ID())
+	if !info.Connected || !info.Priority || info.Capacity != 20 {
+		t.Fatalf("priority client info mismatch: %+v", info)
+	}
+	// Disconnecting kicked clients must not release capacity again
+	for _, p := range free {
+		pool.disconnect(p)
+	}
+	if _, used, _, _, _ := pool.stats(); used != 30 {
+		t.Fatalf("used capacity mismatch: have %d, want %d", used, 30)
+	}
+	// Check that the assignments are persisted
+	if info := newClientPool(db, flowcontrol.ServerParams{BufLimit: 1000, MinRecharge: 10}, 3).info(prio.ID()); !info.Priority || info.Capacity != 20 {
+		t.Fatalf("reloaded priority client info mismatch: %+v", info)
+	}
+	// Revoke the priority, the client should be dropped from the pool
+	if err := pool.setCapacity(prio.ID(), 0); err != nil {
+		t.Fatalf("failed to revoke capacity: %v", err)
+	}
+	if info := pool.info(prio.ID()); info.Connected || info.Priority || info.Capacity != 10 {
+		t.Fatalf("revoked client info mismatch: %+v", info)
+	}
+}
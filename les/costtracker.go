@@ -0,0 +1,142 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	costUpdateInterval  = 10 * time.Minute // Interval of deriving new cost tables from the measured serving costs
+	costUpdateThreshold = 0.1              // Relative cost change required to advertise a new cost table
+	costSwitchDelay     = 10 * time.Second // Time given to clients to apply an advertised cost table
+)
+
+// requestNames are the names of the request types in metrics and API results.
+var requestNames = map[uint64]string{
+	GetBlockHeadersMsg:     "headers",
+	GetBlockBodiesMsg:      "bodies",
+	GetCodeMsg:             "code",
+	GetReceiptsMsg:         "receipts",
+	GetProofsV1Msg:         "proofsV1",
+	SendTxMsg:              "sendTx",
+	SendTxV2Msg:            "sendTxV2",
+	GetTxStatusMsg:         "txStatus",
+	GetHeaderProofsMsg:     "headerProofs",
+	GetProofsV2Msg:         "proofsV2",
+	GetHelperTrieProofsMsg: "helperTrieProofs",
+}
+
+// requestUsage is the serving statistics of a request type.
+type requestUsage struct {
+	Requests uint64 `json:"requests"` // Number of requests served
+	Amount   uint64 `json:"amount"`   // Number of items requested
+	Cost     uint64 `json:"cost"`     // Sum of the measured serving costs
+}
+
+// requestMeters are the metrics of a request type.
+type requestMeters struct {
+	requests, amount, cost metrics.Meter // Served requests, items and measured costs
+	baseCost, reqCost      metrics.Gauge // Advertised costs
+}
+
+// costMeters are the metrics of the request types served by the light server.
+var costMeters = func() map[uint64]*requestMeters {
+	meters := make(map[uint64]*requestMeters)
+	for code, name := range requestNames {
+		meters[code] = &requestMeters{
+			requests: metrics.NewRegisteredMeter("les/server/req/"+name+"/requests", nil),
+			amount:   metrics.NewRegisteredMeter("les/server/req/"+name+"/amount", nil),
+			cost:     metrics.NewRegisteredMeter("les/server/req/"+name+"/cost", nil),
+			baseCost: metrics.NewRegisteredGauge("les/server/cost/"+name+"/base", nil),
+			reqCost:  metrics.NewRegisteredGauge("les/server/cost/"+name+"/req", nil),
+		}
+	}
+	return meters
+}()
+
+// refresh derives a cost table from the measured serving costs and returns it if
+// it differs enough from the advertised one to be worth advertising.
+func (s *requestCostStats) refresh() (RequestCostList, bool) {
+	list := s.getCurrentList()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !costsChanged(s.advertised, list) {
+		return nil, false
+	}
+	s.setAdvertised(list)
+	return list, true
+}
+
+// setAdvertised sets the advertised cost table. The caller must hold the lock.
+func (s *requestCostStats) setAdvertised(list RequestCostList) {
+	s.advertised = list
+	for _, e := range list {
+		if m := costMeters[e.MsgCode]; m != nil {
+			m.baseCost.Update(int64(e.BaseCost))
+			m.reqCost.Update(int64(e.ReqCost))
+		}
+	}
+}
+
+// advertisedList returns the cost table last advertised to the clients.
+func (s *requestCostStats) advertisedList() RequestCostList {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.advertised
+}
+
+// usageStats returns the serving statistics of every request type.
+func (s *requestCostStats) usageStats() map[string]requestUsage {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	stats := make(map[string]requestUsage, len(s.usage))
+	for code, usage := range s.usage {
+		stats[requestNames[code]] = *usage
+	}
+	return stats
+}
+
+// costsChanged reports whether any cost of a request cost table changed more
+// than costUpdateThreshold relative to the old one.
+func costsChanged(old, new RequestCostList) bool {
+	costs := old.decode()
+	for _, e := range new {
+		c := costs[e.MsgCode]
+		if c == nil || costDiff(c.baseCost, e.BaseCost) > costUpdateThreshold || costDiff(c.reqCost, e.ReqCost) > costUpdateThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// costDiff returns the difference of two costs relative to the larger one.
+func costDiff(a, b uint64) float64 {
+	if a == b {
+		return 0
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return float64(a-b) / float64(a)
+}
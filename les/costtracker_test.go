@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// Tests that cost tables are only re-advertised if the measured serving costs
+// changed significantly.
+func TestCostTableRefresh(t *testing.T) {
+	stats := newCostStats(nil)
+	if _, changed := stats.refresh(); changed {
+		t.Fatalf("cost table changed without measurements")
+	}
+	for i := 0; i < 1000; i++ {
+		stats.update(GetBlockHeadersMsg, 10, 1000000)
+	}
+	list, changed := stats.refresh()
+	if !changed {
+		t.Fatalf("cost table unchanged after measurements")
+	}
+	if costs := list.decode()[GetBlockHeadersMsg]; costs.baseCost+10*costs.reqCost == 0 {
+		t.Fatalf("header request costs not raised: %+v", costs)
+	}
+	if advertised := stats.advertisedList(); costsChanged(advertised, list) {
+		t.Fatalf("refreshed cost table not advertised")
+	}
+	if _, changed := stats.refresh(); changed {
+		t.Fatalf("cost table changed without new measurements")
+	}
+	usage := stats.usageStats()["headers"]
+	if usage.Requests != 1000 || usage.Amount != 10000 || usage.Cost != 1000000000 {
+		t.Fatalf("header usage mismatch: %+v", usage)
+	}
+}
+
+// Tests that clients are charged the cheaper costs while an updated cost table is
+// rolled out, and switched to the new table afterwards.
+func TestServingCostsRollout(t *testing.T) {
+	p := &peer{fcCosts: requestCostTable{
+		GetBlockHeadersMsg: {baseCost: 100, reqCost: 10},
+		GetBlockBodiesMsg:  {baseCost: 100, reqCost: 10},
+	}}
+	p.scheduleCosts(requestCostTable{
+		GetBlockHeadersMsg: {baseCost: 50, reqCost: 5},
+		GetBlockBodiesMsg:  {baseCost: 200, reqCost: 20},
+	})
+	if costs := p.servingCosts(GetBlockHeadersMsg); costs.baseCost != 50 {
+		t.Errorf("lowered costs not charged during rollout: %+v", costs)
+	}
+	if costs := p.servingCosts(GetBlockBodiesMsg); costs.baseCost != 100 {
+		t.Errorf("raised costs charged during rollout: %+v", costs)
+	}
+	p.fcCostsSwitch = mclock.Now()
+	if costs := p.servingCosts(GetBlockBodiesMsg); costs.baseCost != 200 {
+		t.Errorf("raised costs not charged after rollout: %+v", costs)
+	}
+	if p.fcCostsNext != nil {
+		t.Errorf("cost table not switched after rollout")
+	}
+}
+
+// Tests that clients apply cost updates advertised by servers.
+func TestClientCostUpdate(t *testing.T) {
+	p := &peer{fcCosts: requestCostTable{
+		GetBlockHeadersMsg: {baseCost: 100, reqCost: 10},
+		GetBlockBodiesMsg:  {baseCost: 100, reqCost: 10},
+	}}
+	p.updateCosts(RequestCostList{{MsgCode: GetBlockHeadersMsg, BaseCost: 200, ReqCost: 20}})
+	if costs := p.fcCosts[GetBlockHeadersMsg]; costs.baseCost != 200 || costs.reqCost != 20 {
+		t.Errorf("advertised costs not applied: %+v", costs)
+	}
+	if costs := p.fcCosts[GetBlockBodiesMsg]; costs.baseCost != 100 || costs.reqCost != 10 {
+		t.Errorf("unadvertised costs changed: %+v", costs)
+	}
+}
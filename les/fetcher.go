@@ -400,7 +400,13 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	bestSyncing := false
 
 	for p, fp := range f.peers {
+		if !f.followPeer(p) {
+			continue
+		}
 		for hash, n := range fp.nodeByHash {
+			if f.pm.ulc != nil && !f.trustedAnnounced(hash) {
+				continue
+			}
 			if !f.checkKnownNode(p, n) && !n.requested && (bestTd == nil || n.td.Cmp(bestTd) >= 0) {
 				amount := f.requestAmount(p, n)
 				if bestTd == nil || n.td.Cmp(bestTd) > 0 || amount < bestAmount {
@@ -431,7 +437,7 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 				defer f.lock.Unlock()
 
 				fp := f.peers[p]
-				return fp != nil && fp.nodeByHash[bestHash] != nil
+				return fp != nil && fp.nodeByHash[bestHash] != nil && f.followPeer(p)
 			},
 			request: func(dp distPeer) func() {
 				go func() {
@@ -455,7 +461,7 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 				defer f.lock.Unlock()
 
 				fp := f.peers[p]
-				if fp == nil {
+				if fp == nil || !f.followPeer(p) {
 					return false
 				}
 				n := fp.nodeByHash[bestHash]
@@ -489,6 +495,24 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	return rq, reqID
 }
 
+// followPeer reports whether the heads announced by a peer may be downloaded
+// and synced to. Ultra light clients only follow their trusted servers.
+func (f *lightFetcher) followPeer(p *peer) bool {
+	return f.pm.ulc == nil || p.isTrusted
+}
+
+// trustedAnnounced reports whether a block has been announced by enough trusted
+// servers for an ultra light client to accept it.
+func (f *lightFetcher) trustedAnnounced(hash common.Hash) bool {
+	count := 0
+	for p, fp := range f.peers {
+		if p.isTrusted && fp.nodeByHash[hash] != nil {
+			count++
+		}
+	}
+	return f.pm.ulc.enoughAnnouncements(count)
+}
+
 // deliverHeaders delivers header download request responses for processing
 func (f *lightFetcher) deliverHeaders(peer *peer, reqID uint64, headers []*types.Header) {
 	f.deliverChn <- fetchResponse{reqID: reqID, headers: headers, peer: peer}
@@ -107,6 +107,7 @@ type ProtocolManager struct {
 	lesTopic    discv5.Topic
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
+	ulc         *ulc // nil unless running as an ultra light client
 
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
@@ -129,7 +130,7 @@ type ProtocolManager struct {
 
 // NewProtocolManager returns a new ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
 // with the ethereum network.
-func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protocolVersions []uint, networkId uint64, mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb ethdb.Database, odr *LesOdr, txrelay *LesTxRelay, quitSync chan struct{}, wg *sync.WaitGroup) (*ProtocolManager, error) {
+func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protocolVersions []uint, networkId uint64, mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb ethdb.Database, checkpoint *params.TrustedCheckpoint, odr *LesOdr, txrelay *LesTxRelay, ulc *ulc, quitSync chan struct{}, wg *sync.WaitGroup) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		lightSync:   lightSync,
@@ -141,6 +142,7 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protoco
 		networkId:   networkId,
 		txpool:      txpool,
 		txrelay:     txrelay,
+		ulc:         ulc,
 		peers:       peers,
 		newPeerCh:   make(chan *peer),
 		quitSync:    quitSync,
@@ -206,7 +208,7 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protoco
 	}
 
 	if lightSync {
-		manager.downloader = downloader.New(downloader.LightSync, chainDb, manager.eventMux, nil, blockchain, removePeer)
+		manager.downloader = downloader.New(checkpoint, downloader.LightSync, chainDb, manager.eventMux, nil, blockchain, removePeer, nil)
 		manager.peers.notify((*downloaderPeerNotify)(manager))
 		manager.fetcher = newLightFetcher(manager)
 	}
@@ -263,10 +265,23 @@ func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgRea
 // handle is the callback invoked to manage the life cycle of a les peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	// Ignore maxPeers if this is a trusted peer
-	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
+	// Ignore maxPeers if this is a trusted peer, servers admit clients based on
+	// their capacity instead
+	if pm.ulc != nil {
+		p.isTrusted = pm.ulc.isTrusted(p.ID())
+	}
+	trusted := p.Peer.Info().Network.Trusted || p.isTrusted
+	if pm.server == nil && pm.peers.Len() >= pm.maxPeers && !trusted {
 		return p2p.DiscTooManyPeers
 	}
+	if pm.server != nil {
+		params, err := pm.server.clientPool.connect(p, trusted)
+		if err != nil {
+			return err
+		}
+		defer pm.server.clientPool.disconnect(p)
+		p.fcParams = params
+	}
 
 	p.Log().Debug("Light Ethereum peer connected", "name", p.Name())
 
@@ -345,18 +360,18 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	}
 	p.Log().Trace("Light Ethereum message arrived", "code", msg.Code, "bytes", msg.Size)
 
-	costs := p.fcCosts[msg.Code]
+	costs := p.servingCosts(msg.Code)
 	reject := func(reqCnt, maxCnt uint64) bool {
 		if p.fcClient == nil || reqCnt > maxCnt {
 			return true
 		}
 		bufValue, _ := p.fcClient.AcceptRequest()
 		cost := costs.baseCost + reqCnt*costs.reqCost
-		if cost > pm.server.defParams.BufLimit {
-			cost = pm.server.defParams.BufLimit
+		if cost > p.fcParams.BufLimit {
+			cost = p.fcParams.BufLimit
 		}
 		if cost > bufValue {
-			recharge := time.Duration((cost - bufValue) * 1000000 / pm.server.defParams.MinRecharge)
+			recharge := time.Duration((cost - bufValue) * 1000000 / p.fcParams.MinRecharge)
 			p.Log().Error("Request came too early", "recharge", common.PrettyDuration(recharge))
 			return true
 		}
@@ -396,6 +411,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.Log().Trace("Valid announcement signature")
 		}
+		var costList RequestCostList
+		if err := req.Update.decode().get("flowControl/MRC", &costList); err == nil {
+			p.Log().Debug("Updated request costs of server")
+			p.updateCosts(costList)
+		}
 
 		p.Log().Trace("Announce message content", "number", req.Number, "hash", req.Hash, "td", req.Td, "reorg", req.ReorgDepth)
 		if pm.fetcher != nil {
@@ -585,7 +605,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, req := range req.Reqs {
 			// Retrieve the requested state entry, stopping if enough was found
 			if number := rawdb.ReadHeaderNumber(pm.chainDb, req.BHash); number != nil {
-				if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil {
+				if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil && pm.stateServed(*number) {
 					statedb, err := pm.blockchain.State()
 					if err != nil {
 						continue
@@ -716,7 +736,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, req := range req.Reqs {
 			// Retrieve the requested state entry, stopping if enough was found
 			if number := rawdb.ReadHeaderNumber(pm.chainDb, req.BHash); number != nil {
-				if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil {
+				if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil && pm.stateServed(*number) {
 					statedb, err := pm.blockchain.State()
 					if err != nil {
 						continue
@@ -776,7 +796,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				statedb, root, lastBHash = nil, common.Hash{}, req.BHash
 
 				if number := rawdb.ReadHeaderNumber(pm.chainDb, req.BHash); number != nil {
-					if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil {
+					if header := rawdb.ReadHeader(pm.chainDb, req.BHash, *number); header != nil && pm.stateServed(*number) {
 						statedb, _ = pm.blockchain.State()
 						root = header.Root
 					}
@@ -1089,6 +1109,15 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		// Replies to relayed transactions aren't awaited by the retriever
+		if !pm.retriever.requested(resp.ReqID) {
+			break
+		}
+		deliverMsg = &Msg{
+			MsgType: MsgTxStatus,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Status,
+		}
 
 	default:
 		p.Log().Trace("Received unknown message", "code", msg.Code)
@@ -1107,6 +1136,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// stateServed reports whether the state of the given block is served to clients.
+func (pm *ProtocolManager) stateServed(number uint64) bool {
+	if pm.server == nil || pm.server.stateWindow == 0 {
+		return true
+	}
+	return number+pm.server.stateWindow > pm.blockchain.CurrentHeader().Number.Uint64()
+}
+
 // getAccount retrieves an account from the state based at root.
 func (pm *ProtocolManager) getAccount(statedb *state.StateDB, root, hash common.Hash) (state.Account, error) {
 	trie, err := trie.New(root, statedb.Database().TrieDB())
@@ -18,6 +18,7 @@ package les
 
 import (
 	"context"
+	"errors"
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -25,6 +26,10 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// errStateUnavailable is returned if none of the connected servers serves the
+// state of the block a request refers to.
+var errStateUnavailable = errors.New("state not available from the connected servers")
+
 // LesOdr implements light.OdrBackend
 type LesOdr struct {
 	db                                         ethdb.Database
@@ -77,6 +82,7 @@ const (
 	MsgProofsV2
 	MsgHeaderProofs
 	MsgHelperTrieProofs
+	MsgTxStatus
 )
 
 // Msg encodes a LES message that delivers reply data for a request
@@ -92,6 +98,10 @@ type Msg struct {
 func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err error) {
 	lreq := LesRequest(req)
 
+	// Fail right away if no server would answer a state request
+	if number, ok := stateRequestBlock(req); ok && !odr.retriever.peers.canServeState(number) {
+		return errStateUnavailable
+	}
 	reqID := genReqID()
 	rq := &distReq{
 		getCost: func(dp distPeer) uint64 {
@@ -109,7 +119,17 @@ func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err erro
 		},
 	}
 
-	if err = odr.retriever.retrieve(ctx, reqID, rq, func(p distPeer, msg *Msg) error { return lreq.Validate(odr.db, msg) }, odr.stop); err == nil {
+	validate := func(p distPeer, msg *Msg) error {
+		if err := lreq.Validate(odr.db, msg); err != nil {
+			return err
+		}
+		// Transaction statuses are only meaningful together with their source
+		if r, ok := req.(*light.TxStatusRequest); ok {
+			r.Server = p.(*peer).id
+		}
+		return nil
+	}
+	if err = odr.retriever.retrieve(ctx, reqID, rq, validate, odr.stop); err == nil {
 		// retrieved from network, store in db
 		req.StoreResult(odr.db)
 	} else {
@@ -117,3 +137,16 @@ func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err erro
 	}
 	return
 }
+
+// stateRequestBlock returns the number of the block whose state a request refers
+// to, if it's a state request.
+func stateRequestBlock(req light.OdrRequest) (uint64, bool) {
+	switch r := req.(type) {
+	case *light.TrieRequest:
+		return r.Id.BlockNumber, true
+	case *light.CodeRequest:
+		return r.Id.BlockNumber, true
+	default:
+		return 0, false
+	}
+}
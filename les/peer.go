@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/les/flowcontrol"
@@ -71,11 +72,16 @@ type peer struct {
 	poolEntry      *poolEntry
 	hasBlock       func(common.Hash, uint64) bool
 	responseErrors int
+	isTrusted      bool   // whether the peer is a trusted server of an ultra light client
+	stateWindow    uint64 // number of recent blocks whose state the server serves, 0 if all
 
-	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
-	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
+	fcClient       *flowcontrol.ClientNode   // nil if the peer is server only
+	fcServer       *flowcontrol.ServerNode   // nil if the peer is client only
+	fcParams       *flowcontrol.ServerParams // parameters assigned by the client pool if the peer is a client
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
+	fcCostsNext    requestCostTable // updated cost table being rolled out to a client
+	fcCostsSwitch  mclock.AbsTime   // time of switching a client to the updated cost table
 }
 
 func newPeer(version int, network uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -174,6 +180,50 @@ func (p *peer) GetRequestCost(msgcode uint64, amount int) uint64 {
 	return cost
 }
 
+// servingCosts returns the costs charged to a client for serving a request. While
+// an updated cost table is rolled out, the cheaper of the old and new costs is
+// charged, as the client might not have applied the update yet.
+func (p *peer) servingCosts(msgcode uint64) *requestCosts {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.fcCostsNext != nil && mclock.Now() >= p.fcCostsSwitch {
+		p.fcCosts, p.fcCostsNext = p.fcCostsNext, nil
+	}
+	costs := p.fcCosts[msgcode]
+	if p.fcCostsNext != nil {
+		if next := p.fcCostsNext[msgcode]; next != nil && (costs == nil || next.baseCost+next.reqCost < costs.baseCost+costs.reqCost) {
+			costs = next
+		}
+	}
+	return costs
+}
+
+// scheduleCosts switches a client to an updated cost table once it had enough
+// time to apply the advertised update.
+func (p *peer) scheduleCosts(table requestCostTable) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.fcCostsNext = table
+	p.fcCostsSwitch = mclock.Now() + mclock.AbsTime(costSwitchDelay)
+}
+
+// updateCosts applies an updated cost table advertised by a server.
+func (p *peer) updateCosts(list RequestCostList) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	costs := make(requestCostTable, len(p.fcCosts))
+	for code, c := range p.fcCosts {
+		costs[code] = c
+	}
+	for code, c := range list.decode() {
+		costs[code] = c
+	}
+	p.fcCosts = costs
+}
+
 // HasBlock checks if the peer has a given block
 func (p *peer) HasBlock(hash common.Hash, number uint64) bool {
 	p.lock.RLock()
@@ -182,6 +232,14 @@ func (p *peer) HasBlock(hash common.Hash, number uint64) bool {
 	return hasBlock != nil && hasBlock(hash, number)
 }
 
+// canServeState reports whether the server serves the state of the given block.
+func (p *peer) canServeState(number uint64) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.stateWindow == 0 || number+p.stateWindow > p.headInfo.Number
+}
+
 // SendAnnounce announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendAnnounce(request announceData) error {
@@ -408,14 +466,21 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		send = send.add("serveHeaders", nil)
 		send = send.add("serveChainSince", uint64(0))
 		send = send.add("serveStateSince", uint64(0))
+		if server.stateWindow != 0 {
+			send = send.add("serveRecentState", server.stateWindow)
+		}
 		send = send.add("txRelay", nil)
-		send = send.add("flowControl/BL", server.defParams.BufLimit)
-		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
-		list := server.fcCostStats.getCurrentList()
+		send = send.add("flowControl/BL", p.fcParams.BufLimit)
+		send = send.add("flowControl/MRR", p.fcParams.MinRecharge)
+		list := server.fcCostStats.advertisedList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
 	} else {
-		p.requestAnnounceType = announceTypeSimple // set to default until "very light" client mode is implemented
+		// ultra light clients rely on signed announcements of their trusted servers
+		p.requestAnnounceType = announceTypeSimple
+		if p.isTrusted {
+			p.requestAnnounceType = announceTypeSigned
+		}
 		send = send.add("announceType", p.requestAnnounceType)
 	}
 	recvList, err := p.sendReceiveHandshake(send)
@@ -464,7 +529,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
-		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		p.fcClient = flowcontrol.NewClientNode(server.fcManager, p.fcParams)
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
@@ -472,6 +537,10 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("serveStateSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve state")
 		}
+		// servers not limiting their state window serve all the state they have
+		if recv.get("serveRecentState", &p.stateWindow) != nil {
+			p.stateWindow = 0
+		}
 		if recv.get("txRelay", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot relay transactions")
 		}
@@ -634,6 +703,23 @@ func (ps *peerSet) BestPeer() *peer {
 	return bestPeer
 }
 
+// canServeState reports whether any of the peers serves the state of the given
+// block. Without peers there's nothing to decide on yet, so it returns true.
+func (ps *peerSet) canServeState(number uint64) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	if len(ps.peers) == 0 {
+		return true
+	}
+	for _, p := range ps.peers {
+		if p.canServeState(number) {
+			return true
+		}
+	}
+	return false
+}
+
 // AllPeers returns all peers in a list
 func (ps *peerSet) AllPeers() []*peer {
 	ps.lock.RLock()
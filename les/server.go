@@ -22,6 +22,7 @@ import (
 	"encoding/binary"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -35,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type LesServer struct {
@@ -43,6 +45,8 @@ type LesServer struct {
 	fcManager       *flowcontrol.ClientManager // nil if our node is client only
 	fcCostStats     *requestCostStats
 	defParams       *flowcontrol.ServerParams
+	clientPool      *clientPool
+	stateWindow     uint64 // Number of recent blocks whose state is served, 0 if all
 	lesTopics       []discv5.Topic
 	privateKey      *ecdsa.PrivateKey
 	quitSync        chan struct{}
@@ -50,9 +54,29 @@ type LesServer struct {
 	chtIndexer, bloomTrieIndexer *core.ChainIndexer
 }
 
+// recentStateWindow is the number of recent blocks whose state a pruning node is
+// guaranteed to keep, with a margin for the chain progressing while serving.
+const recentStateWindow = 120
+
+// stateWindow returns the number of recent blocks whose state is served to light
+// clients, 0 meaning the state of all blocks. Pruning nodes can't serve more than
+// the state they keep.
+func stateWindow(config *eth.Config) uint64 {
+	if config.NoPruning {
+		return config.LightState
+	}
+	if config.LightState > recentStateWindow {
+		log.Warn("Light state window exceeds the state kept by pruning nodes", "requested", config.LightState, "window", recentStateWindow)
+	}
+	if config.LightState == 0 || config.LightState > recentStateWindow {
+		return recentStateWindow
+	}
+	return config.LightState
+}
+
 func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 	quitSync := make(chan struct{})
-	pm, err := NewProtocolManager(eth.BlockChain().Config(), false, ServerProtocolVersions, config.NetworkId, eth.EventMux(), eth.Engine(), newPeerSet(), eth.BlockChain(), eth.TxPool(), eth.ChainDb(), nil, nil, quitSync, new(sync.WaitGroup))
+	pm, err := NewProtocolManager(eth.BlockChain().Config(), false, ServerProtocolVersions, config.NetworkId, eth.EventMux(), eth.Engine(), newPeerSet(), eth.BlockChain(), eth.TxPool(), eth.ChainDb(), nil, nil, nil, nil, quitSync, new(sync.WaitGroup))
 	if err != nil {
 		return nil, err
 	}
@@ -92,12 +116,14 @@ func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 	}
 
 	srv.chtIndexer.Start(eth.BlockChain())
+	srv.stateWindow = stateWindow(config)
 	pm.server = srv
 
 	srv.defParams = &flowcontrol.ServerParams{
 		BufLimit:    300000000,
 		MinRecharge: 50000,
 	}
+	srv.clientPool = newClientPool(eth.ChainDb(), *srv.defParams, config.LightPeers)
 	srv.fcManager = flowcontrol.NewClientManager(uint64(config.LightServ), 10, 1000000000)
 	srv.fcCostStats = newCostStats(eth.ChainDb())
 	return srv, nil
@@ -107,6 +133,18 @@ func (s *LesServer) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
 
+// APIs returns the RPC APIs of the LES server.
+func (s *LesServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightServerAPI(s),
+			Public:    false,
+		},
+	}
+}
+
 // Start starts the LES server
 func (s *LesServer) Start(srvr *p2p.Server) {
 	s.protocolManager.Start(s.config.LightPeers)
@@ -224,9 +262,11 @@ func linRegFromBytes(data []byte) *linReg {
 }
 
 type requestCostStats struct {
-	lock  sync.RWMutex
-	db    ethdb.Database
-	stats map[uint64]*linReg
+	lock       sync.RWMutex
+	db         ethdb.Database
+	stats      map[uint64]*linReg
+	usage      map[uint64]*requestUsage
+	advertised RequestCostList
 }
 
 type requestCostStatsRlp []struct {
@@ -259,10 +299,17 @@ func newCostStats(db ethdb.Database) *requestCostStats {
 		}
 	}
 
-	return &requestCostStats{
+	usage := make(map[uint64]*requestUsage)
+	for _, code := range reqList {
+		usage[code] = new(requestUsage)
+	}
+	s := &requestCostStats{
 		db:    db,
 		stats: stats,
+		usage: usage,
 	}
+	s.setAdvertised(s.getCurrentList())
+	return s
 }
 
 func (s *requestCostStats) store() {
@@ -313,17 +360,38 @@ func (s *requestCostStats) update(msgCode, reqCnt, cost uint64) {
 		return
 	}
 	c.add(float64(reqCnt), float64(cost))
+
+	usage := s.usage[msgCode]
+	usage.Requests++
+	usage.Amount += reqCnt
+	usage.Cost += cost
+
+	if m := costMeters[msgCode]; m != nil {
+		m.requests.Mark(1)
+		m.amount.Mark(int64(reqCnt))
+		m.cost.Mark(int64(cost))
+	}
 }
 
 func (pm *ProtocolManager) blockLoop() {
 	pm.wg.Add(1)
 	headCh := make(chan core.ChainHeadEvent, 10)
 	headSub := pm.blockchain.SubscribeChainHeadEvent(headCh)
+	costTicker := time.NewTicker(costUpdateInterval)
 	go func() {
-		var lastHead *types.Header
+		var (
+			lastHead    *types.Header
+			newCosts    RequestCostList // Cost table waiting to be advertised with the next head
+			newCostsTbl requestCostTable
+		)
 		lastBroadcastTd := common.Big0
 		for {
 			select {
+			case <-costTicker.C:
+				if list, changed := pm.server.fcCostStats.refresh(); changed {
+					log.Debug("Updated request cost table")
+					newCosts, newCostsTbl = list, list.decode()
+				}
 			case ev := <-headCh:
 				peers := pm.peers.AllPeers()
 				if len(peers) > 0 {
@@ -342,6 +410,9 @@ func (pm *ProtocolManager) blockLoop() {
 						log.Debug("Announcing block to peers", "number", number, "hash", hash, "td", td, "reorg", reorg)
 
 						announce := announceData{Hash: hash, Number: number, Td: td, ReorgDepth: reorg}
+						if newCosts != nil {
+							announce.Update = announce.Update.add("flowControl/MRC", newCosts)
+						}
 						var (
 							signed         bool
 							signedAnnounce announceData
@@ -353,6 +424,9 @@ func (pm *ProtocolManager) blockLoop() {
 							case announceTypeSimple:
 								select {
 								case p.announceChn <- announce:
+									if newCostsTbl != nil {
+										p.scheduleCosts(newCostsTbl)
+									}
 								default:
 									pm.removePeer(p.id)
 								}
@@ -366,14 +440,19 @@ func (pm *ProtocolManager) blockLoop() {
 
 								select {
 								case p.announceChn <- signedAnnounce:
+									if newCostsTbl != nil {
+										p.scheduleCosts(newCostsTbl)
+									}
 								default:
 									pm.removePeer(p.id)
 								}
 							}
 						}
+						newCosts, newCostsTbl = nil, nil
 					}
 				}
 			case <-pm.quitSync:
+				costTicker.Stop()
 				headSub.Unsubscribe()
 				pm.wg.Done()
 				return
@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/light"
+)
+
+// Tests the number of recent blocks whose state is served by a server.
+func TestStateWindowConfig(t *testing.T) {
+	tests := []struct {
+		archive bool
+		state   uint64
+		window  uint64
+	}{
+		{archive: true, state: 0, window: 0},
+		{archive: true, state: 1000, window: 1000},
+		{archive: false, state: 0, window: recentStateWindow},
+		{archive: false, state: 50, window: 50},
+		{archive: false, state: 1000, window: recentStateWindow},
+	}
+	for i, tt := range tests {
+		if window := stateWindow(&eth.Config{NoPruning: tt.archive, LightState: tt.state}); window != tt.window {
+			t.Errorf("test %d: window mismatch: have %d, want %d", i, window, tt.window)
+		}
+	}
+}
+
+// Tests that state requests are only routed to servers serving the state of the
+// requested block, failing explicitly if there are none.
+func TestStateWindowRouting(t *testing.T) {
+	peers := newPeerSet()
+	odr := &LesOdr{retriever: &retrieveManager{peers: peers}}
+
+	recent := newPoolTestPeer()
+	recent.headInfo = &announceData{Number: 1000}
+	recent.stateWindow = 100
+	recent.hasBlock = func(common.Hash, uint64) bool { return true }
+
+	if err := peers.Register(recent); err != nil {
+		t.Fatal(err)
+	}
+	for _, number := range []uint64{901, 1000} {
+		req := &TrieRequest{Id: &light.TrieID{BlockNumber: number}}
+		if !req.CanSend(recent) {
+			t.Errorf("state of block %d not routed to server", number)
+		}
+	}
+	old := &light.TrieRequest{Id: &light.TrieID{BlockNumber: 900}}
+	if (*TrieRequest)(old).CanSend(recent) {
+		t.Errorf("state of block 900 routed to server without it")
+	}
+	if err := odr.Retrieve(context.Background(), old); err != errStateUnavailable {
+		t.Errorf("retrieval error mismatch: have %v, want %v", err, errStateUnavailable)
+	}
+	// An archive server makes the old state available again
+	archive := newPoolTestPeer()
+	archive.headInfo = &announceData{Number: 1000}
+	if err := peers.Register(archive); err != nil {
+		t.Fatal(err)
+	}
+	if !peers.canServeState(900) {
+		t.Errorf("state of block 900 not available from archive server")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // syncer is responsible for periodically synchronising with the network, both
@@ -74,6 +75,10 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	pm.blockchain.(*light.LightChain).SyncCht(ctx)
+	// Start from the trusted checkpoint instead of verifying headers from genesis
+	if !pm.blockchain.(*light.LightChain).SyncCheckpoint(ctx) {
+		log.Debug("Failed to sync to trusted checkpoint, retrying later")
+		return
+	}
 	pm.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), downloader.LightSync)
 }
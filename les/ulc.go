@@ -0,0 +1,89 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+var errNoTrustedServers = errors.New("no trusted servers configured for ultra light client")
+
+// ulc holds the configuration of an ultra light client. Instead of verifying the
+// headers it downloads, an ultra light client only accepts heads announced with
+// a valid signature by a large enough fraction of its trusted servers.
+type ulc struct {
+	servers  map[discover.NodeID]*discover.Node // Trusted servers
+	fraction int                                // Minimum percentage of trusted servers announcing a head
+}
+
+// newULC parses the ultra light client configuration.
+func newULC(config *eth.ULCConfig) (*ulc, error) {
+	u := &ulc{
+		servers:  make(map[discover.NodeID]*discover.Node),
+		fraction: config.MinTrustedFraction,
+	}
+	for _, url := range config.TrustedServers {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted server %q: %v", url, err)
+		}
+		u.servers[node.ID] = node
+	}
+	if len(u.servers) == 0 {
+		return nil, errNoTrustedServers
+	}
+	if u.fraction <= 0 || u.fraction > 100 {
+		log.Warn("Invalid ultra light client trusted fraction, using default", "fraction", u.fraction, "default", eth.DefaultULCMinTrustedFraction)
+		u.fraction = eth.DefaultULCMinTrustedFraction
+	}
+	return u, nil
+}
+
+// isTrusted reports whether the node with the given ID is a trusted server.
+func (u *ulc) isTrusted(id discover.NodeID) bool {
+	_, ok := u.servers[id]
+	return ok
+}
+
+// enoughAnnouncements reports whether the given number of trusted servers
+// announcing a head is enough to accept it.
+func (u *ulc) enoughAnnouncements(count int) bool {
+	return count*100 >= u.fraction*len(u.servers)
+}
+
+// ulcEngine wraps the consensus engine of an ultra light client, skipping the
+// seal verification of the headers which are vouched for by trusted servers.
+type ulcEngine struct {
+	consensus.Engine
+}
+
+// VerifyHeader implements consensus.Engine, never verifying the seal.
+func (e ulcEngine) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	return e.Engine.VerifyHeader(chain, header, false)
+}
+
+// VerifyHeaders implements consensus.Engine, never verifying the seals.
+func (e ulcEngine) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	return e.Engine.VerifyHeaders(chain, headers, make([]bool, len(headers)))
+}
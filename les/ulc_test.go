@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func testULCServers(n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		key, _ := crypto.GenerateKey()
+		urls[i] = fmt.Sprintf("enode://%x@127.0.0.1:%d", discover.PubkeyID(&key.PublicKey).Bytes(), 30303+i)
+	}
+	return urls
+}
+
+// Tests that ultra light clients only accept heads announced by enough of their
+// trusted servers.
+func TestULCTrustedAnnouncements(t *testing.T) {
+	if _, err := newULC(&eth.ULCConfig{}); err != errNoTrustedServers {
+		t.Fatalf("empty server list: have %v, want %v", err, errNoTrustedServers)
+	}
+	if _, err := newULC(&eth.ULCConfig{TrustedServers: []string{"enode://invalid"}}); err == nil {
+		t.Fatalf("invalid server accepted")
+	}
+	urls := testULCServers(3)
+	u, err := newULC(&eth.ULCConfig{TrustedServers: urls, MinTrustedFraction: 200})
+	if err != nil {
+		t.Fatalf("failed to create ultra light client config: %v", err)
+	}
+	if u.fraction != eth.DefaultULCMinTrustedFraction {
+		t.Fatalf("invalid fraction not defaulted: have %d, want %d", u.fraction, eth.DefaultULCMinTrustedFraction)
+	}
+	u.fraction = 60
+
+	f := &lightFetcher{pm: &ProtocolManager{ulc: u}, peers: make(map[*peer]*fetcherPeerInfo)}
+	announce := func(trusted bool) *peer {
+		p := &peer{isTrusted: trusted}
+		f.peers[p] = &fetcherPeerInfo{nodeByHash: make(map[common.Hash]*fetcherTreeNode)}
+		f.peers[p].nodeByHash[common.Hash{1}] = &fetcherTreeNode{hash: common.Hash{1}}
+		return p
+	}
+	untrusted := announce(false)
+	if f.followPeer(untrusted) {
+		t.Fatalf("untrusted peer followed")
+	}
+	announce(false)
+	if f.trustedAnnounced(common.Hash{1}) {
+		t.Fatalf("head accepted from untrusted announcements")
+	}
+	if trusted := announce(true); !f.followPeer(trusted) {
+		t.Fatalf("trusted peer not followed")
+	}
+	if f.trustedAnnounced(common.Hash{1}) {
+		t.Fatalf("head accepted from 1/3 trusted announcements")
+	}
+	announce(true)
+	if !f.trustedAnnounced(common.Hash{1}) {
+		t.Fatalf("head rejected with 2/3 trusted announcements")
+	}
+	if f.trustedAnnounced(common.Hash{2}) {
+		t.Fatalf("unannounced head accepted")
+	}
+}
+
+// sealRecorder is a consensus engine recording the seal verification flags.
+type sealRecorder struct {
+	consensus.Engine
+	seals []bool
+}
+
+func (e *sealRecorder) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	e.seals = append(e.seals, seal)
+	return nil
+}
+
+func (e *sealRecorder) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	e.seals = append(e.seals, seals...)
+	return nil, nil
+}
+
+// Tests that ultra light clients never verify header seals.
+func TestULCEngineSkipsSeals(t *testing.T) {
+	recorder := new(sealRecorder)
+	engine := ulcEngine{recorder}
+
+	engine.VerifyHeader(nil, new(types.Header), true)
+	engine.VerifyHeaders(nil, []*types.Header{new(types.Header), new(types.Header)}, []bool{true, true})
+	if len(recorder.seals) != 3 {
+		t.Fatalf("verification count mismatch: have %d, want %d", len(recorder.seals), 3)
+	}
+	for i, seal := range recorder.seals {
+		if seal {
+			t.Errorf("header %d: seal verified", i)
+		}
+	}
+}
@@ -58,6 +58,7 @@ type LightChain struct {
 	chainHeadFeed event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
+	checkpoint    *params.TrustedCheckpoint // Latest trusted checkpoint the chain was started from
 
 	mu      sync.RWMutex
 	chainmu sync.RWMutex
@@ -77,10 +78,11 @@ type LightChain struct {
 
 // NewLightChain returns a fully initialised light chain using information
 // available in the database. It initialises the default Ethereum header
-// validator.
+// validator. If a trusted checkpoint is given, syncing starts from it instead
+// of the built-in checkpoint of the network (if any).
 // DB상의 사용가능한 정보를 활용하여 완전히 초기화된 라이트 체인을 리턴한다.
 // 기본 이더리움 헤더 검증자를 초기화 한다
-func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.Engine) (*LightChain, error) {
+func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.Engine, checkpoint *params.TrustedCheckpoint) (*LightChain, error) {
 // Least Recently Used
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
@@ -104,8 +106,11 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 	if bc.genesisBlock == nil {
 		return nil, core.ErrNoGenesis
 	}
-	if cp, ok := trustedCheckpoints[bc.genesisBlock.Hash()]; ok {
-		bc.addTrustedCheckpoint(cp)
+	if checkpoint == nil {
+		checkpoint = params.TrustedCheckpoints[bc.genesisBlock.Hash()]
+	}
+	if checkpoint != nil {
+		bc.AddTrustedCheckpoint(checkpoint)
 	}
 	//이더리움은 스테이트 머신이므로 마지막 스테이트를 가지고 온다.
 	if err := bc.loadLastState(); err != nil {
@@ -123,20 +128,34 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 	return bc, nil
 }
 
-// addTrustedCheckpoint adds a trusted checkpoint to the blockchain
-func (self *LightChain) addTrustedCheckpoint(cp trustedCheckpoint) {
+// AddTrustedCheckpoint adds a trusted checkpoint to the blockchain, allowing
+// headers and logs up to its head to be retrieved without syncing them first.
+func (self *LightChain) AddTrustedCheckpoint(cp *params.TrustedCheckpoint) {
 	if self.odr.ChtIndexer() != nil {
-		StoreChtRoot(self.chainDb, cp.sectionIdx, cp.sectionHead, cp.chtRoot)
-		self.odr.ChtIndexer().AddKnownSectionHead(cp.sectionIdx, cp.sectionHead)
+		StoreChtRoot(self.chainDb, cp.SectionIndex, cp.SectionHead, cp.CHTRoot)
+		self.odr.ChtIndexer().AddKnownSectionHead(cp.SectionIndex, cp.SectionHead)
 	}
 	if self.odr.BloomTrieIndexer() != nil {
-		StoreBloomTrieRoot(self.chainDb, cp.sectionIdx, cp.sectionHead, cp.bloomTrieRoot)
-		self.odr.BloomTrieIndexer().AddKnownSectionHead(cp.sectionIdx, cp.sectionHead)
+		StoreBloomTrieRoot(self.chainDb, cp.SectionIndex, cp.SectionHead, cp.BloomRoot)
+		self.odr.BloomTrieIndexer().AddKnownSectionHead(cp.SectionIndex, cp.SectionHead)
 	}
 	if self.odr.BloomIndexer() != nil {
-		self.odr.BloomIndexer().AddKnownSectionHead(cp.sectionIdx, cp.sectionHead)
+		self.odr.BloomIndexer().AddKnownSectionHead(cp.SectionIndex, cp.SectionHead)
 	}
-	log.Info("Added trusted checkpoint", "chain", cp.name, "block", (cp.sectionIdx+1)*CHTFrequencyClient-1, "hash", cp.sectionHead)
+	self.mu.Lock()
+	self.checkpoint = cp
+	self.mu.Unlock()
+
+	log.Info("Added trusted checkpoint", "chain", cp.Name, "block", cp.HeadNumber(), "hash", cp.SectionHead)
+}
+
+// Checkpoint returns the latest trusted checkpoint added to the chain, or nil if
+// the chain is synced from genesis.
+func (self *LightChain) Checkpoint() *params.TrustedCheckpoint {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	return self.checkpoint
 }
 
 func (self *LightChain) getProcInterrupt() bool {
@@ -485,6 +504,20 @@ func (self *LightChain) SyncCht(ctx context.Context) bool {
 	return false
 }
 
+// SyncCheckpoint starts the chain from the head of the latest trusted checkpoint
+// if the local chain is behind it. The head header is retrieved with a proof
+// against the trusted CHT root, so syncing and header verification continue
+// from the checkpoint. It returns false if the chain is still behind the
+// checkpoint afterwards.
+func (self *LightChain) SyncCheckpoint(ctx context.Context) bool {
+	checkpoint := self.Checkpoint()
+	if checkpoint == nil || self.odr.ChtIndexer() == nil || self.CurrentHeader().Number.Uint64() >= checkpoint.HeadNumber() {
+		return true
+	}
+	self.SyncCht(ctx)
+	return self.CurrentHeader().Number.Uint64() >= checkpoint.HeadNumber()
+}
+
 // LockChain locks the chain mutex for reading so that multiple canonical hashes can be
 // retrieved while it is guaranteed that they belong to the same version of the chain
 func (self *LightChain) LockChain() {
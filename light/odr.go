@@ -172,3 +172,15 @@ func (req *BloomRequest) StoreResult(db ethdb.Database) {
 		rawdb.WriteBloomBits(db, req.BitIdx, sectionIdx, sectionHead, req.BloomBits[i])
 	}
 }
+
+// TxStatusRequest is the ODR request type for retrieving the status of
+// transactions as seen by a light server
+type TxStatusRequest struct {
+	OdrRequest
+	Hashes []common.Hash
+	Status []core.TxStatus
+	Server string // ID of the server that answered the request
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *TxStatusRequest) StoreResult(db ethdb.Database) {}
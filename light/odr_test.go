@@ -87,6 +87,8 @@ func (odr *testOdr) Retrieve(ctx context.Context, req OdrRequest) error {
 		req.Proof = nodes
 	case *CodeRequest:
 		req.Data, _ = odr.sdb.Get(req.Hash[:])
+	case *TxStatusRequest:
+		req.Status = make([]core.TxStatus, len(req.Hashes))
 	}
 	req.StoreResult(odr.ldb)
 	return nil
@@ -259,7 +261,7 @@ func testChainOdr(t *testing.T, protocol int, fn odrTestFn) {
 	}
 
 	odr := &testOdr{sdb: sdb, ldb: ldb}
-	lightchain, err := NewLightChain(odr, params.TestChainConfig, ethash.NewFullFaker())
+	lightchain, err := NewLightChain(odr, params.TestChainConfig, ethash.NewFullFaker(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
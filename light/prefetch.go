@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	maxPrefetchRounds   = 8  // Maximum number of speculative executions before giving up
+	prefetchConcurrency = 16 // Maximum number of state retrievals in flight
+)
+
+// errPrefetchMissing is returned by the recording backend for every state
+// request, aborting the state access without network round trips.
+var errPrefetchMissing = errors.New("state missing from local database")
+
+// recordingOdr is an ODR backend which records the state requests instead of
+// retrieving them. Other requests are passed on to the wrapped backend.
+type recordingOdr struct {
+	OdrBackend
+	lock sync.Mutex
+	reqs map[string]OdrRequest
+}
+
+// Retrieve implements OdrBackend.
+func (odr *recordingOdr) Retrieve(ctx context.Context, req OdrRequest) error {
+	var key string
+	switch r := req.(type) {
+	case *TrieRequest:
+		key = string(r.Id.Root[:]) + string(r.Id.AccKey) + string(r.Key)
+	case *CodeRequest:
+		key = string(r.Hash[:])
+	default:
+		return odr.OdrBackend.Retrieve(ctx, req)
+	}
+	odr.lock.Lock()
+	odr.reqs[key] = req
+	odr.lock.Unlock()
+	return errPrefetchMissing
+}
+
+// PrefetchState speeds up executing code against the state of a header by
+// running it speculatively on the locally available state first, recording the
+// missing account and storage proofs and contract codes instead of retrieving
+// them one round trip at a time. The recorded data is then retrieved in parallel
+// and the execution is repeated until it doesn't miss anything, so that the
+// final execution finds its state locally in a few round trips.
+//
+// Prefetching is best effort: failures are not reported, as the final execution
+// retrieves anything still missing on demand.
+func PrefetchState(ctx context.Context, header *types.Header, odr OdrBackend, run func(*state.StateDB)) {
+	for round := 0; round < maxPrefetchRounds && ctx.Err() == nil; round++ {
+		rec := &recordingOdr{OdrBackend: odr, reqs: make(map[string]OdrRequest)}
+		run(NewState(ctx, header, rec))
+		if len(rec.reqs) == 0 {
+			return
+		}
+		log.Trace("Prefetching light state", "number", header.Number, "round", round, "requests", len(rec.reqs))
+
+		if err := retrieveAll(ctx, odr, rec.reqs); err != nil {
+			log.Debug("Light state prefetch failed", "number", header.Number, "err", err)
+			return
+		}
+	}
+}
+
+// retrieveAll retrieves the given requests concurrently, returning the first
+// error encountered.
+func retrieveAll(ctx context.Context, odr OdrBackend, reqs map[string]OdrRequest) error {
+	var (
+		wg    sync.WaitGroup
+		limit = make(chan struct{}, prefetchConcurrency)
+		errc  = make(chan error, len(reqs))
+	)
+	for _, req := range reqs {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(req OdrRequest) {
+			defer func() { <-limit; wg.Done() }()
+			errc <- odr.Retrieve(ctx, req)
+		}(req)
+	}
+	wg.Wait()
+	close(errc)
+
+	for err := range errc {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
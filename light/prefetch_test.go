@@ -0,0 +1,95 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that prefetching retrieves all the state accessed by a contract call,
+// so that the call itself can be executed without any further retrievals.
+func TestPrefetchState(t *testing.T) {
+	var (
+		sdb     = ethdb.NewMemDatabase()
+		ldb     = ethdb.NewMemDatabase()
+		gspec   = core.Genesis{Alloc: core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}}}
+		genesis = gspec.MustCommit(sdb)
+	)
+	gspec.MustCommit(ldb)
+
+	blockchain, _ := core.NewBlockChain(sdb, nil, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{})
+	gchain, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), sdb, 4, testChainGen)
+	if _, err := blockchain.InsertChain(gchain); err != nil {
+		t.Fatal(err)
+	}
+	odr := &testOdr{sdb: sdb, ldb: ldb}
+	lightchain, _ := NewLightChain(odr, params.TestChainConfig, ethash.NewFullFaker(), nil)
+	headers := make([]*types.Header, len(gchain))
+	for i, block := range gchain {
+		headers[i] = block.Header()
+	}
+	if _, err := lightchain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatal(err)
+	}
+	header := lightchain.CurrentHeader()
+
+	call := func(chain core.ChainContext, st *state.StateDB) []byte {
+		data := common.Hex2Bytes("60CD26850000000000000000000000000000000000000000000000000000000000000002")
+		st.SetBalance(testBankAddress, math.MaxBig256)
+		msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 1000000, new(big.Int), data, false)}
+		evm := vm.NewEVM(core.NewEVMContext(msg, header, chain, nil), st, params.TestChainConfig, vm.Config{})
+		ret, _, _, _ := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+		return ret
+	}
+	full, _ := blockchain.State()
+	want := call(blockchain, full)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rounds := 0
+	PrefetchState(ctx, header, odr, func(st *state.StateDB) {
+		rounds++
+		call(lightchain, st)
+	})
+	if rounds < 2 || rounds > maxPrefetchRounds {
+		t.Errorf("speculative execution count mismatch: have %d, want 2..%d", rounds, maxPrefetchRounds)
+	}
+	// Execute the call without retrievals, it must find all its state locally
+	odr.disable = true
+	st := NewState(ctx, header, odr)
+	if have := call(lightchain, st); !bytes.Equal(have, want) {
+		t.Errorf("call result mismatch: have %x, want %x", have, want)
+	}
+	if err := st.Error(); err != nil {
+		t.Errorf("state retrieval after prefetching: %v", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -66,6 +67,13 @@ type TxPool struct {
 	pending      map[common.Hash]*types.Transaction   // pending transactions by tx hash
 	mined        map[common.Hash][]*types.Transaction // mined transactions by block hash
 	clearIdx     uint64                               // earliest block nr that can contain mined tx info
+	unknown      map[common.Hash]map[string]struct{}  // servers not finding a pending tx
+	checking     int32                                // whether a status check is running (atomic)
+
+	statusFeed  event.Feed
+	statusQueue []TxStatusEvent // status events waiting for delivery
+	statusWake  chan struct{}
+	statusLock  sync.Mutex
 
 	homestead bool
 }
@@ -93,6 +101,8 @@ func NewTxPool(config *params.ChainConfig, chain *LightChain, relay TxRelayBacke
 		nonce:       make(map[common.Address]uint64),
 		pending:     make(map[common.Hash]*types.Transaction),
 		mined:       make(map[common.Hash][]*types.Transaction),
+		unknown:     make(map[common.Hash]map[string]struct{}),
+		statusWake:  make(chan struct{}, 1),
 		quit:        make(chan bool),
 		chainHeadCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
 		chain:       chain,
@@ -105,6 +115,7 @@ func NewTxPool(config *params.ChainConfig, chain *LightChain, relay TxRelayBacke
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 	go pool.eventLoop()
+	go pool.statusLoop()
 
 	return pool
 }
@@ -181,7 +192,8 @@ func (pool *TxPool) checkMinedTxs(ctx context.Context, hash common.Hash, number
 	// If some transactions have been mined, write the needed data to disk and update
 	if list != nil {
 		// Retrieve all the receipts belonging to this block and write the loopup table
-		if _, err := GetBlockReceipts(ctx, pool.odr, hash, number); err != nil { // ODR caches, ignore results
+		receipts, err := GetBlockReceipts(ctx, pool.odr, hash, number)
+		if err != nil {
 			return err
 		}
 		rawdb.WriteTxLookupEntries(pool.chainDb, block)
@@ -189,7 +201,9 @@ func (pool *TxPool) checkMinedTxs(ctx context.Context, hash common.Hash, number
 		// Update the transaction pool's state
 		for _, tx := range list {
 			delete(pool.pending, tx.Hash())
+			delete(pool.unknown, tx.Hash())
 			txc.setState(tx.Hash(), true)
+			pool.postStatus(includedEvent(block, receipts, tx))
 		}
 		pool.mined[hash] = list
 	}
@@ -205,6 +219,7 @@ func (pool *TxPool) rollbackTxs(hash common.Hash, txc txStateChanges) {
 			rawdb.DeleteTxLookupEntry(pool.chainDb, txHash)
 			pool.pending[txHash] = tx
 			txc.setState(txHash, false)
+			pool.postStatus(TxStatusEvent{Hash: txHash, Status: TxTrackReorged})
 		}
 		delete(pool.mined, hash)
 	}
@@ -280,8 +295,12 @@ func (pool *TxPool) reorgOnNewHead(ctx context.Context, newHeader *types.Header)
 const blockCheckTimeout = time.Second * 3
 
 // eventLoop processes chain head events and also notifies the tx relay backend
-// about the new head hash and tx state changes
+// about the new head hash and tx state changes. It also periodically checks
+// whether the pending transactions are still known to the network.
 func (pool *TxPool) eventLoop() {
+	statusTicker := time.NewTicker(txStatusInterval)
+	defer statusTicker.Stop()
+
 	for {
 		select {
 		case ev := <-pool.chainHeadCh:
@@ -290,6 +309,15 @@ func (pool *TxPool) eventLoop() {
 			// be replaced by a subsequent PR.
 			time.Sleep(time.Millisecond)
 
+		case <-statusTicker.C:
+			// Query the servers in the background, not to hold up head processing
+			if atomic.CompareAndSwapInt32(&pool.checking, 0, 1) {
+				go func() {
+					defer atomic.StoreInt32(&pool.checking, 0)
+					pool.checkTxStatus()
+				}()
+			}
+
 		// System stopped
 		case <-pool.chainHeadSub.Err():
 			return
@@ -413,6 +441,7 @@ func (self *TxPool) add(ctx context.Context, tx *types.Transaction) error {
 		// because it's possible that somewhere during the post "Remove transaction"
 		// gets called which will then wait for the global tx pool lock and deadlock.
 		go self.txFeed.Send(core.NewTxsEvent{Txs: types.Transactions{tx}})
+		self.postStatus(TxStatusEvent{Hash: hash, Status: TxTrackPending})
 	}
 
 	// Print a log message if low enough level is set
@@ -506,21 +535,29 @@ func (self *TxPool) RemoveTransactions(txs types.Transactions) {
 	defer self.mu.Unlock()
 	var hashes []common.Hash
 	for _, tx := range txs {
-		//self.RemoveTx(tx.Hash())
-		hash := tx.Hash()
-		delete(self.pending, hash)
-		self.chainDb.Delete(hash[:])
-		hashes = append(hashes, hash)
+		hashes = append(hashes, tx.Hash())
 	}
-	self.relay.Discard(hashes)
+	self.removeTxs(hashes)
 }
 
 // RemoveTx removes the transaction with the given hash from the pool.
 func (pool *TxPool) RemoveTx(hash common.Hash) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
-	// delete from pending pool
-	delete(pool.pending, hash)
-	pool.chainDb.Delete(hash[:])
-	pool.relay.Discard([]common.Hash{hash})
+
+	pool.removeTxs([]common.Hash{hash})
+}
+
+// removeTxs removes the given transactions from the pool, reporting the pending
+// ones as dropped. The caller must hold the pool lock.
+func (pool *TxPool) removeTxs(hashes []common.Hash) {
+	for _, hash := range hashes {
+		if _, ok := pool.pending[hash]; ok {
+			delete(pool.pending, hash)
+			pool.postStatus(TxStatusEvent{Hash: hash, Status: TxTrackDropped})
+		}
+		delete(pool.unknown, hash)
+		pool.chainDb.Delete(hash[:])
+	}
+	pool.relay.Discard(hashes)
 }
@@ -0,0 +1,234 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	txStatusInterval = time.Minute     // Interval of querying the servers about the pending transactions
+	txStatusTimeout  = 5 * time.Second // Time limit of a transaction status query
+	txStatusBatch    = 256             // Maximum number of transactions queried at once
+	txLostServers    = 3               // Distinct servers not finding a transaction before it's resent
+	txStatusQueued   = 4096            // Maximum number of status events waiting for delivery
+)
+
+// TxTrackStatus is the inclusion status of a locally sent transaction.
+type TxTrackStatus uint
+
+const (
+	TxTrackPending  TxTrackStatus = iota // Sent to the network, waiting for inclusion
+	TxTrackIncluded                      // Included in a canonical block
+	TxTrackReorged                       // Including block rolled back, waiting for inclusion again
+	TxTrackLost                          // Unknown to the servers, sent to the network again
+	TxTrackDropped                       // Removed from the pool, not expected to be included anymore
+)
+
+// String implements fmt.Stringer.
+func (s TxTrackStatus) String() string {
+	switch s {
+	case TxTrackPending:
+		return "pending"
+	case TxTrackIncluded:
+		return "included"
+	case TxTrackReorged:
+		return "reorged"
+	case TxTrackLost:
+		return "lost"
+	case TxTrackDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// TxStatusEvent is posted when the inclusion status of a locally sent transaction
+// changes. The block position, the receipt and its Merkle path in the receipt
+// trie of the block are only set for included transactions. The path is derived
+// locally from the block's receipts, which were retrieved and verified against
+// the receipt root of the block.
+type TxStatusEvent struct {
+	Hash   common.Hash
+	Status TxTrackStatus
+
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint
+	Receipt     *types.Receipt
+	ReceiptPath NodeList
+}
+
+// SubscribeTxStatusEvent registers a subscription of TxStatusEvent and starts
+// sending event to the given channel.
+func (pool *TxPool) SubscribeTxStatusEvent(ch chan<- TxStatusEvent) event.Subscription {
+	return pool.scope.Track(pool.statusFeed.Subscribe(ch))
+}
+
+// postStatus queues status events for delivery to the subscribers, keeping their
+// order without blocking the caller. If the subscribers fall too far behind, the
+// oldest undelivered events are dropped.
+func (pool *TxPool) postStatus(events ...TxStatusEvent) {
+	pool.statusLock.Lock()
+	pool.statusQueue = append(pool.statusQueue, events...)
+	if drop := len(pool.statusQueue) - txStatusQueued; drop > 0 {
+		log.Warn("Dropping undelivered transaction status events", "count", drop)
+		pool.statusQueue = append([]TxStatusEvent{}, pool.statusQueue[drop:]...)
+	}
+	pool.statusLock.Unlock()
+
+	select {
+	case pool.statusWake <- struct{}{}:
+	default:
+	}
+}
+
+// statusLoop delivers the queued status events to the subscribers.
+func (pool *TxPool) statusLoop() {
+	for {
+		select {
+		case <-pool.statusWake:
+			pool.statusLock.Lock()
+			events := pool.statusQueue
+			pool.statusQueue = nil
+			pool.statusLock.Unlock()
+
+			for _, ev := range events {
+				pool.statusFeed.Send(ev)
+			}
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
+// includedEvent creates the status event of a transaction included in a block
+// from the verified receipts of the block.
+func includedEvent(block *types.Block, receipts types.Receipts, tx *types.Transaction) TxStatusEvent {
+	ev := TxStatusEvent{
+		Hash:        tx.Hash(),
+		Status:      TxTrackIncluded,
+		BlockHash:   block.Hash(),
+		BlockNumber: block.NumberU64(),
+	}
+	for i, t := range block.Transactions() {
+		if t.Hash() == ev.Hash {
+			ev.Index = uint(i)
+			break
+		}
+	}
+	if int(ev.Index) < len(receipts) {
+		ev.Receipt = receipts[ev.Index]
+		ev.ReceiptPath = receiptPath(receipts, ev.Index)
+	}
+	return ev
+}
+
+// receiptPath rebuilds the receipt trie of a block, see types.DeriveSha, and
+// returns the Merkle path of the receipt at the given index.
+func receiptPath(receipts types.Receipts, index uint) NodeList {
+	t := new(trie.Trie)
+	keybuf := new(bytes.Buffer)
+	for i := 0; i < receipts.Len(); i++ {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(i))
+		t.Update(keybuf.Bytes(), receipts.GetRlp(i))
+	}
+	key, _ := rlp.EncodeToBytes(index)
+	proof := NewNodeSet()
+	if err := t.Prove(key, 0, proof); err != nil {
+		log.Warn("Failed to create receipt path", "err", err)
+		return nil
+	}
+	return proof.NodeList()
+}
+
+// checkTxStatus queries the light servers about the pending transactions and
+// resends the ones unknown to txLostServers distinct servers.
+func (pool *TxPool) checkTxStatus() {
+	pool.mu.RLock()
+	hashes := make([]common.Hash, 0, len(pool.pending))
+	for hash := range pool.pending {
+		hashes = append(hashes, hash)
+	}
+	pool.mu.RUnlock()
+
+	for len(hashes) > 0 {
+		batch := hashes
+		if len(batch) > txStatusBatch {
+			batch = batch[:txStatusBatch]
+		}
+		hashes = hashes[len(batch):]
+
+		select {
+		case <-pool.quit:
+			return
+		default:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), txStatusTimeout)
+		req := &TxStatusRequest{Hashes: batch}
+		err := pool.odr.Retrieve(ctx, req)
+		cancel()
+		if err != nil || len(req.Status) != len(batch) {
+			log.Debug("Failed to query transaction status", "err", err)
+			return
+		}
+		pool.updateTxStatus(req.Server, batch, req.Status)
+	}
+}
+
+// updateTxStatus processes the status of pending transactions reported by a
+// light server. Local transactions are never dropped, the ones unknown to enough
+// distinct servers are reported lost and sent to the network again.
+func (pool *TxPool) updateTxStatus(server string, hashes []common.Hash, status []core.TxStatus) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var lost types.Transactions
+	for i, hash := range hashes {
+		tx, ok := pool.pending[hash]
+		if !ok || status[i] != core.TxStatusUnknown {
+			delete(pool.unknown, hash)
+			continue
+		}
+		if pool.unknown[hash] == nil {
+			pool.unknown[hash] = make(map[string]struct{})
+		}
+		pool.unknown[hash][server] = struct{}{}
+		if len(pool.unknown[hash]) >= txLostServers {
+			delete(pool.unknown, hash)
+			lost = append(lost, tx)
+		}
+	}
+	if len(lost) > 0 {
+		log.Debug("Resending transactions unknown to the servers", "count", len(lost))
+		for _, tx := range lost {
+			pool.postStatus(TxStatusEvent{Hash: tx.Hash(), Status: TxTrackLost})
+		}
+		pool.relay.Send(lost)
+	}
+}
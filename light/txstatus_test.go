@@ -0,0 +1,158 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that the status changes of local transactions are reported, including
+// a valid receipt path for mined ones.
+func TestTxStatusEvents(t *testing.T) {
+	var (
+		sdb     = ethdb.NewMemDatabase()
+		ldb     = ethdb.NewMemDatabase()
+		gspec   = core.Genesis{Alloc: core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}}}
+		genesis = gspec.MustCommit(sdb)
+	)
+	gspec.MustCommit(ldb)
+
+	mined, _ := types.SignTx(types.NewTransaction(0, acc1Addr, big.NewInt(10000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	lost, _ := types.SignTx(types.NewTransaction(1, acc1Addr, big.NewInt(10000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+
+	blockchain, _ := core.NewBlockChain(sdb, nil, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{})
+	gchain, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), sdb, 1, func(i int, block *core.BlockGen) {
+		block.AddTx(mined)
+	})
+	if _, err := blockchain.InsertChain(gchain); err != nil {
+		t.Fatal(err)
+	}
+	odr := &testOdr{sdb: sdb, ldb: ldb}
+	relay := &testTxRelay{
+		send:    make(chan int, 10),
+		discard: make(chan int, 10),
+		mined:   make(chan int, 10),
+	}
+	lightchain, _ := NewLightChain(odr, params.TestChainConfig, ethash.NewFullFaker(), nil)
+	pool := NewTxPool(params.TestChainConfig, lightchain, relay)
+	defer pool.Stop()
+
+	events := make(chan TxStatusEvent, 10)
+	sub := pool.SubscribeTxStatusEvent(events)
+	defer sub.Unsubscribe()
+
+	expect := func(hash common.Hash, status TxTrackStatus) TxStatusEvent {
+		select {
+		case ev := <-events:
+			if ev.Hash != hash || ev.Status != status {
+				t.Fatalf("event mismatch: have %x %v, want %x %v", ev.Hash[:4], ev.Status, hash[:4], status)
+			}
+			return ev
+		case <-time.After(time.Second):
+			t.Fatalf("no %v event for %x", status, hash[:4])
+		}
+		return TxStatusEvent{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Send a transaction and have it mined
+	if err := pool.Add(ctx, mined); err != nil {
+		t.Fatal(err)
+	}
+	expect(mined.Hash(), TxTrackPending)
+
+	header := gchain[0].Header()
+	if _, err := lightchain.InsertHeaderChain([]*types.Header{header}, 1); err != nil {
+		t.Fatal(err)
+	}
+	ev := expect(mined.Hash(), TxTrackIncluded)
+	if ev.BlockHash != header.Hash() || ev.BlockNumber != 1 || ev.Index != 0 {
+		t.Errorf("inclusion position mismatch: have %x/%d/%d", ev.BlockHash[:4], ev.BlockNumber, ev.Index)
+	}
+	key, _ := rlp.EncodeToBytes(uint(0))
+	value, err, _ := trie.VerifyProof(header.ReceiptHash, key, ev.ReceiptPath.NodeSet())
+	if err != nil {
+		t.Fatalf("invalid receipt path: %v", err)
+	}
+	if enc, _ := rlp.EncodeToBytes(ev.Receipt); !bytes.Equal(value, enc) {
+		t.Errorf("receipt path value mismatch")
+	}
+
+	// Send a transaction the servers never learn about, a single server missing
+	// it shouldn't be enough to consider it lost
+	if err := pool.Add(ctx, lost); err != nil {
+		t.Fatal(err)
+	}
+	expect(lost.Hash(), TxTrackPending)
+	for i := 0; i < 2*txLostServers; i++ {
+		pool.checkTxStatus()
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event after queries of a single server: %x %v", ev.Hash[:4], ev.Status)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Have enough distinct servers miss it and ensure it's resent, not dropped
+	for len(relay.send) > 0 {
+		<-relay.send
+	}
+	for i := 1; i < txLostServers; i++ {
+		pool.updateTxStatus(fmt.Sprintf("server-%d", i), []common.Hash{lost.Hash()}, []core.TxStatus{core.TxStatusUnknown})
+	}
+	expect(lost.Hash(), TxTrackLost)
+	select {
+	case n := <-relay.send:
+		if n != 1 {
+			t.Errorf("resent transaction count mismatch: have %d, want 1", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("lost transaction not resent")
+	}
+	if pool.GetTransaction(lost.Hash()) == nil {
+		t.Errorf("lost local transaction dropped from the pool")
+	}
+}
+
+// Tests that the undelivered status events are capped, dropping the oldest ones.
+func TestTxStatusQueueLimit(t *testing.T) {
+	pool := &TxPool{statusWake: make(chan struct{}, 1)}
+	for i := 0; i < txStatusQueued+10; i++ {
+		pool.postStatus(TxStatusEvent{Hash: common.BigToHash(big.NewInt(int64(i)))})
+	}
+	if len(pool.statusQueue) != txStatusQueued {
+		t.Fatalf("queued events mismatch: have %d, want %d", len(pool.statusQueue), txStatusQueued)
+	}
+	if first := pool.statusQueue[0].Hash; first != common.BigToHash(big.NewInt(10)) {
+		t.Errorf("oldest queued event mismatch: have %x, want %x", first, common.BigToHash(big.NewInt(10)))
+	}
+}
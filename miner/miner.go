@@ -20,6 +20,7 @@ package miner
 import (
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -34,6 +35,10 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
+// DefaultRecommitInterval is the default minimum interval of recreating the
+// sealing work with the newly arrived transactions.
+const DefaultRecommitInterval = 3 * time.Second
+
 // Backend wraps all methods required for mining.
 type Backend interface {
 	AccountManager() *accounts.Manager
@@ -187,6 +192,42 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetGasLimit sets the range the gas limit of the mined blocks is kept in. The
+// limit is moved towards the floor while the blocks are mostly empty, and towards
+// the ceiling while they are full.
+func (self *Miner) SetGasLimit(floor, ceil uint64) error {
+	if floor < params.MinGasLimit {
+		return fmt.Errorf("gas floor %d below minimum gas limit %d", floor, params.MinGasLimit)
+	}
+	if floor > ceil {
+		return fmt.Errorf("gas floor %d above gas ceiling %d", floor, ceil)
+	}
+	self.worker.setGasLimit(floor, ceil)
+	return nil
+}
+
+// SetRecommitInterval sets the minimum interval of recreating the sealing work
+// with the newly arrived transactions. The interval is backed off adaptively
+// while the recreated work doesn't collect more fees.
+func (self *Miner) SetRecommitInterval(interval time.Duration) {
+	if interval < minRecommitInterval {
+		log.Warn("Sanitizing miner recommit interval", "provided", interval, "updated", minRecommitInterval)
+		interval = minRecommitInterval
+	}
+	self.worker.setRecommitInterval(interval)
+}
+
+// SetTxOrdering sets the policy ordering the transactions packed into the mined
+// blocks, see TxOrderings for the available ones.
+func (self *Miner) SetTxOrdering(name string) error {
+	ordering, err := lookupTxOrdering(name)
+	if err != nil {
+		return err
+	}
+	self.worker.setTxOrdering(ordering)
+	return nil
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
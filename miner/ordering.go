@@ -0,0 +1,186 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultTxOrdering is the name of the transaction ordering policy used if none
+// is configured, packing the best paying transactions first.
+const DefaultTxOrdering = "price"
+
+// TxOrdering is the order in which the pending transactions are packed into the
+// mined blocks. Implementations must yield the transactions of each account in
+// nonce order, as later ones can't be executed before the earlier ones anyway.
+type TxOrdering interface {
+	// Peek returns the next transaction to pack, or nil if none are left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account, called if the current one was packed (or skipped).
+	Shift()
+
+	// Pop removes the current transaction without replacing it with the next one
+	// from the same account, called if the account can't have more packed.
+	Pop()
+}
+
+// TxOrderingFunc creates a TxOrdering of the given transactions, grouped by
+// account and sorted by nonce. The arrival function returns the time when a
+// transaction was first seen by the local node.
+type TxOrderingFunc func(signer types.Signer, txs map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering
+
+var (
+	txOrderingsLock sync.RWMutex
+	txOrderings     = map[string]TxOrderingFunc{
+		"price": newPriceOrdering,
+		"fifo":  newFIFOOrdering,
+	}
+)
+
+// RegisterTxOrdering makes a transaction ordering policy selectable by the given
+// name, replacing any policy registered under the same name.
+func RegisterTxOrdering(name string, ordering TxOrderingFunc) {
+	txOrderingsLock.Lock()
+	defer txOrderingsLock.Unlock()
+
+	txOrderings[name] = ordering
+}
+
+// TxOrderings returns the names of the available transaction ordering policies.
+func TxOrderings() []string {
+	txOrderingsLock.RLock()
+	defer txOrderingsLock.RUnlock()
+
+	names := make([]string, 0, len(txOrderings))
+	for name := range txOrderings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTxOrdering returns the transaction ordering policy registered under the
+// given name.
+func lookupTxOrdering(name string) (TxOrderingFunc, error) {
+	txOrderingsLock.RLock()
+	defer txOrderingsLock.RUnlock()
+
+	ordering, ok := txOrderings[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transaction ordering %q, want one of %v", name, TxOrderings())
+	}
+	return ordering, nil
+}
+
+// newPriceOrdering orders the transactions by gas price, respecting the nonces.
+func newPriceOrdering(signer types.Signer, txs map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering {
+	return types.NewTransactionsByPriceAndNonce(signer, txs)
+}
+
+// fifoHead is the next transaction of an account in the arrival ordering.
+type fifoHead struct {
+	tx      *types.Transaction
+	arrival time.Time
+}
+
+// fifoHeads is a heap of the next transactions of each account, the earliest
+// arrived one first.
+type fifoHeads []fifoHead
+
+func (h fifoHeads) Len() int { return len(h) }
+func (h fifoHeads) Less(i, j int) bool {
+	if h[i].arrival.Equal(h[j].arrival) {
+		return h[i].tx.GasPrice().Cmp(h[j].tx.GasPrice()) > 0
+	}
+	return h[i].arrival.Before(h[j].arrival)
+}
+func (h fifoHeads) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *fifoHeads) Push(x interface{}) {
+	*h = append(*h, x.(fifoHead))
+}
+
+func (h *fifoHeads) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// fifoOrdering orders the transactions by the time of their arrival, respecting
+// the nonces: a transaction is packed only after the earlier nonces of its
+// account, even if it arrived first.
+type fifoOrdering struct {
+	txs     map[common.Address]types.Transactions
+	heads   fifoHeads
+	signer  types.Signer
+	arrival func(common.Hash) time.Time
+}
+
+// newFIFOOrdering creates a transaction ordering packing the earliest arrived
+// transactions first.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// it after providing it to the constructor.
+func newFIFOOrdering(signer types.Signer, txs map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering {
+	t := &fifoOrdering{
+		txs:     txs,
+		heads:   make(fifoHeads, 0, len(txs)),
+		signer:  signer,
+		arrival: arrival,
+	}
+	for from, accTxs := range txs {
+		t.heads = append(t.heads, fifoHead{accTxs[0], arrival(accTxs[0].Hash())})
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&t.heads)
+	return t
+}
+
+// Peek implements TxOrdering.
+func (t *fifoOrdering) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+// Shift implements TxOrdering.
+func (t *fifoOrdering) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0].tx)
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = fifoHead{txs[0], t.arrival(txs[0].Hash())}, txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop implements TxOrdering.
+func (t *fifoOrdering) Pop() {
+	heap.Pop(&t.heads)
+}
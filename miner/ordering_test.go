@@ -0,0 +1,92 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the FIFO ordering packs the earliest arrived transactions first,
+// while keeping the transactions of each account in nonce order.
+func TestFIFOOrdering(t *testing.T) {
+	signer := types.HomesteadSigner{}
+
+	addrs := make([]common.Address, 3)
+	groups := make(map[common.Address]types.Transactions)
+	arrivals := make(map[common.Hash]time.Time)
+
+	// Account #i sends transactions arriving at i+3*nonce seconds, except the first
+	// transaction of the last account, arriving after all the others
+	start := time.Now()
+	for i := range addrs {
+		key, _ := crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+
+		for nonce := 0; nonce < 3; nonce++ {
+			// Price the transactions inversely to their arrival
+			price := big.NewInt(int64(100 - i - 3*nonce))
+			tx, _ := types.SignTx(types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(0), 21000, price, nil), signer, key)
+			groups[addrs[i]] = append(groups[addrs[i]], tx)
+
+			arrivals[tx.Hash()] = start.Add(time.Duration(i+3*nonce) * time.Second)
+			if i == len(addrs)-1 && nonce == 0 {
+				arrivals[tx.Hash()] = start.Add(time.Hour)
+			}
+		}
+	}
+	ordering := newFIFOOrdering(signer, groups, func(hash common.Hash) time.Time { return arrivals[hash] })
+
+	var txs types.Transactions
+	for tx := ordering.Peek(); tx != nil; tx = ordering.Peek() {
+		txs = append(txs, tx)
+		ordering.Shift()
+	}
+	if len(txs) != 9 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), 9)
+	}
+	// The first two accounts are interleaved by arrival, the last comes last
+	want := []struct {
+		from  int
+		nonce uint64
+	}{
+		{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}, {1, 2}, {2, 0}, {2, 1}, {2, 2},
+	}
+	for i, tx := range txs {
+		from, _ := types.Sender(signer, tx)
+		if from != addrs[want[i].from] || tx.Nonce() != want[i].nonce {
+			t.Errorf("transaction %d: have account %x nonce %d, want account %x nonce %d", i, from[:4], tx.Nonce(), addrs[want[i].from][:4], want[i].nonce)
+		}
+	}
+}
+
+// Tests that the transaction ordering policies are selectable by name.
+func TestTxOrderingLookup(t *testing.T) {
+	for _, name := range []string{DefaultTxOrdering, "price", "fifo"} {
+		if _, err := lookupTxOrdering(name); err != nil {
+			t.Errorf("ordering %q: %v", name, err)
+		}
+	}
+	if _, err := lookupTxOrdering("random"); err == nil {
+		t.Errorf("unknown ordering accepted")
+	}
+}
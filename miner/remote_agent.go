@@ -17,19 +17,27 @@
 package miner
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"math/big"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// remoteNotifyTimeout is the maximum time allowed for a notify URL to accept a
+// new work package, so slow receivers don't hold up the others.
+const remoteNotifyTimeout = time.Second
+
 type hashrate struct {
 	ping time.Time
 	rate uint64
@@ -50,15 +58,22 @@ type RemoteAgent struct {
 	hashrateMu sync.RWMutex
 	hashrate   map[common.Hash]hashrate
 
+	notifyURLs   []string     // HTTP endpoints to push the new work packages to
+	notifyClient *http.Client // HTTP client used to push the work packages
+
 	running int32 // running indicates whether the agent is active. Call atomically
 }
 
-func NewRemoteAgent(chain consensus.ChainReader, engine consensus.Engine) *RemoteAgent {
+// NewRemoteAgent creates an agent handing out the sealing work to remote miners,
+// which either poll it via GetWork or get it pushed to the given notify URLs.
+func NewRemoteAgent(chain consensus.ChainReader, engine consensus.Engine, notifyURLs []string) *RemoteAgent {
 	return &RemoteAgent{
-		chain:    chain,
-		engine:   engine,
-		work:     make(map[common.Hash]*Work),
-		hashrate: make(map[common.Hash]hashrate),
+		chain:        chain,
+		engine:       engine,
+		work:         make(map[common.Hash]*Work),
+		hashrate:     make(map[common.Hash]hashrate),
+		notifyURLs:   notifyURLs,
+		notifyClient: &http.Client{Timeout: remoteNotifyTimeout},
 	}
 }
 
@@ -114,16 +129,7 @@ func (a *RemoteAgent) GetWork() ([3]string, error) {
 
 	if a.currentWork != nil {
 		block := a.currentWork.Block
-
-		res[0] = block.HashNoNonce().Hex()
-		seedHash := ethash.SeedHash(block.NumberU64())
-		res[1] = common.BytesToHash(seedHash).Hex()
-		// Calculate the "target" to be returned to the external miner
-		n := big.NewInt(1)
-		n.Lsh(n, 255)
-		n.Div(n, block.Difficulty())
-		n.Lsh(n, 1)
-		res[2] = common.BytesToHash(n.Bytes()).Hex()
+		copy(res[:], workPackage(block))
 
 		a.work[block.HashNoNonce()] = a.currentWork
 		return res, nil
@@ -131,6 +137,46 @@ func (a *RemoteAgent) GetWork() ([3]string, error) {
 	return res, errors.New("No work available yet, don't panic.")
 }
 
+// workPackage assembles the work package of a block for the external miners,
+// consisting of the header pow-hash, the seed hash of the DAG, the boundary
+// condition ("target") and the block number.
+func workPackage(block *types.Block) []string {
+	// Calculate the "target" to be returned to the external miner
+	n := big.NewInt(1)
+	n.Lsh(n, 255)
+	n.Div(n, block.Difficulty())
+	n.Lsh(n, 1)
+
+	return []string{
+		block.HashNoNonce().Hex(),
+		common.BytesToHash(ethash.SeedHash(block.NumberU64())).Hex(),
+		common.BytesToHash(n.Bytes()).Hex(),
+		hexutil.EncodeBig(block.Number()),
+	}
+}
+
+// notifyWork pushes the work package of a new sealing work to all the notify
+// URLs concurrently, tracking the work so that the solutions are accepted.
+func (a *RemoteAgent) notifyWork(work *Work) {
+	a.work[work.Block.HashNoNonce()] = work
+
+	blob, err := json.Marshal(workPackage(work.Block))
+	if err != nil {
+		log.Error("Failed to encode work package", "err", err)
+		return
+	}
+	for _, url := range a.notifyURLs {
+		go func(url string) {
+			res, err := a.notifyClient.Post(url, "application/json", bytes.NewReader(blob))
+			if err != nil {
+				log.Warn("Failed to notify remote miner", "url", url, "err", err)
+				return
+			}
+			res.Body.Close()
+		}(url)
+	}
+}
+
 // SubmitWork tries to inject a pow solution into the remote agent, returning
 // whether the solution was accepted or not (not can be both a bad pow as well as
 // any other error, like no work pending).
@@ -179,6 +225,9 @@ func (a *RemoteAgent) loop(workCh chan *Work, quitCh chan struct{}) {
 		case work := <-workCh:
 			a.mu.Lock()
 			a.currentWork = work
+			if len(a.notifyURLs) > 0 {
+				a.notifyWork(work)
+			}
 			a.mu.Unlock()
 		case <-ticker.C:
 			// cleanup
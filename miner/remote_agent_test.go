@@ -0,0 +1,80 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that new work packages are pushed to the notify URLs, even if some of
+// the receivers are stuck, and that solutions for them are accepted.
+func TestRemoteNotify(t *testing.T) {
+	// Start a stuck receiver and a working one
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(2 * remoteNotifyTimeout)
+	}))
+	defer stuck.Close()
+
+	sink := make(chan []string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var work []string
+		if err := json.NewDecoder(req.Body).Decode(&work); err != nil {
+			t.Errorf("failed to decode work package: %v", err)
+		}
+		sink <- work
+	}))
+	defer server.Close()
+
+	agent := NewRemoteAgent(nil, ethash.NewFaker(), []string{stuck.URL, server.URL})
+	results := make(chan *Result, 1)
+	agent.SetReturnCh(results)
+	agent.Start()
+	defer agent.Stop()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(100)})
+	agent.Work() <- &Work{Block: block, createdAt: time.Now()}
+
+	select {
+	case work := <-sink:
+		if len(work) != 4 {
+			t.Fatalf("work package length mismatch: have %d, want %d", len(work), 4)
+		}
+		if work[0] != block.HashNoNonce().Hex() {
+			t.Errorf("header hash mismatch: have %s, want %s", work[0], block.HashNoNonce().Hex())
+		}
+		if work[3] != "0x64" {
+			t.Errorf("block number mismatch: have %s, want %s", work[3], "0x64")
+		}
+	case <-time.After(remoteNotifyTimeout):
+		t.Fatalf("work package not pushed")
+	}
+	// Submit a solution without ever polling the work
+	if !agent.SubmitWork(types.EncodeNonce(1), block.MixDigest(), block.HashNoNonce()) {
+		t.Fatalf("solution of the notified work rejected")
+	}
+	if result := <-results; result.Block.Nonce() != 1 {
+		t.Errorf("sealed nonce mismatch: have %d, want %d", result.Block.Nonce(), 1)
+	}
+}
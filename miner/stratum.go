@@ -0,0 +1,691 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultStratumDifficulty is the default initial share difficulty assigned to
+	// the stratum workers, in EthereumStratum units of 2^32 hashes.
+	DefaultStratumDifficulty = 1.0
+
+	stratumProtocol       = "EthereumStratum/1.0.0"
+	stratumExtranonceSize = 2                // Number of nonce bytes fixed by the server for each session
+	stratumDifficulty1    = 4294967296.0     // Number of hashes per unit of share difficulty (2^32)
+	stratumMinDifficulty  = 0.0001           // Minimum share difficulty to avoid flooding the server
+	stratumShareInterval  = 10 * time.Second // Targeted interval between two shares of a worker
+	stratumRetarget       = 30 * time.Second // Interval of recalculating the worker difficulties and hashrates
+	stratumReadTimeout    = 10 * time.Minute // Maximum time to wait for a message from an idle worker
+	stratumWriteTimeout   = 10 * time.Second // Maximum time to wait for a message to be sent to a worker
+	stratumJobLifetime    = 7 * (12 * time.Second)
+
+	stratumMaxInvalidShares = 32               // Maximum number of invalid shares of a worker per retarget interval
+	stratumBanDuration      = 10 * time.Minute // Time to reject the connections of a worker banned for invalid shares
+)
+
+var (
+	errStratumNotEthash = errors.New("stratum mining requires the ethash engine")
+
+	// maxUint256 is a big integer representing 2^256, the boundary of the
+	// proof-of-work values of a difficulty of one.
+	maxUint256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+	stratumSessionGauge = metrics.NewRegisteredGauge("miner/stratum/sessions", nil)
+	stratumAcceptMeter  = metrics.NewRegisteredMeter("miner/stratum/shares/accepted", nil)
+	stratumStaleMeter   = metrics.NewRegisteredMeter("miner/stratum/shares/stale", nil)
+	stratumInvalidMeter = metrics.NewRegisteredMeter("miner/stratum/shares/invalid", nil)
+	stratumBlockMeter   = metrics.NewRegisteredMeter("miner/stratum/blocks", nil)
+)
+
+// stratumError is an error reported to the stratum workers, encoded in the
+// [code, message, traceback] format of the protocol.
+type stratumError struct {
+	code    int
+	message string
+}
+
+func (err *stratumError) Error() string { return err.message }
+
+func (err *stratumError) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{err.code, err.message, nil})
+}
+
+var (
+	errStratumUnknown       = &stratumError{20, "Other/Unknown"}
+	errStratumJobNotFound   = &stratumError{21, "Job not found"}
+	errStratumDuplicate     = &stratumError{22, "Duplicate share"}
+	errStratumLowDifficulty = &stratumError{23, "Low difficulty share"}
+	errStratumUnauthorized  = &stratumError{24, "Unauthorized worker"}
+	errStratumNotSubscribed = &stratumError{25, "Not subscribed"}
+)
+
+// stratumRequest is a method call sent by a stratum worker.
+type stratumRequest struct {
+	Id     *json.RawMessage  `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// param returns the i-th parameter of the request if it's a string.
+func (req *stratumRequest) param(i int) string {
+	var param string
+	if i < len(req.Params) {
+		json.Unmarshal(req.Params[i], &param)
+	}
+	return param
+}
+
+// stratumResponse is the reply to a method call of a stratum worker.
+type stratumResponse struct {
+	Id     *json.RawMessage `json:"id"`
+	Result interface{}      `json:"result"`
+	Error  interface{}      `json:"error"`
+}
+
+// stratumNotification is a method call pushed to the stratum workers.
+type stratumNotification struct {
+	Id     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// stratumJob is a sealing work handed out to the stratum workers.
+type stratumJob struct {
+	id    string
+	work  *Work
+	seen  map[types.BlockNonce]struct{} // Nonces already submitted, rejecting duplicates
+	clean bool                          // Whether the job invalidates all the previous ones
+}
+
+// stratumSession is a connection of a stratum worker.
+type stratumSession struct {
+	conn       net.Conn
+	extranonce string // Hex encoded nonce prefix reserved for the session
+
+	subscribed bool
+	worker     string  // Name of the worker, empty until authorized
+	difficulty float64 // Share difficulty assigned to the worker
+	hashes     float64 // Number of hashes proven by the shares since the last retarget
+	hashrate   float64 // Hashrate of the worker measured at the last retarget
+	invalid    int32   // Number of invalid shares since the last retarget (atomic)
+
+	writeLock sync.Mutex
+}
+
+// send writes a single message to the stratum worker.
+func (s *stratumSession) send(msg interface{}) error {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	_, err = s.conn.Write(append(blob, '\n'))
+	return err
+}
+
+// StratumAgent is a mining agent serving the sealing work over the EthereumStratum
+// protocol, pushing new jobs to the connected workers as soon as they are created
+// and accepting shares of a per-worker difficulty.
+type StratumAgent struct {
+	mu sync.Mutex
+
+	quitCh   chan struct{}
+	workCh   chan *Work
+	returnCh chan<- *Result
+
+	chain      consensus.ChainReader
+	engine     *ethash.Ethash
+	addr       string
+	difficulty float64
+
+	listener   net.Listener
+	current    *stratumJob
+	jobs       map[string]*stratumJob
+	sessions   map[*stratumSession]struct{}
+	extranonce uint16
+	gauges     map[string]metrics.Gauge // Hashrate gauges of the workers
+	banned     map[string]time.Time     // Hosts banned for invalid shares, until the given time
+
+	running int32 // running indicates whether the agent is active. Call atomically
+}
+
+// NewStratumAgent creates a stratum mining agent listening on the given address
+// once started, assigning the given initial share difficulty to the workers.
+func NewStratumAgent(chain consensus.ChainReader, engine consensus.Engine, addr string, difficulty float64) (*StratumAgent, error) {
+	pow, ok := engine.(*ethash.Ethash)
+	if !ok {
+		return nil, errStratumNotEthash
+	}
+	if difficulty < stratumMinDifficulty {
+		log.Warn("Sanitizing stratum share difficulty", "provided", difficulty, "updated", stratumMinDifficulty)
+		difficulty = stratumMinDifficulty
+	}
+	return &StratumAgent{
+		chain:      chain,
+		engine:     pow,
+		addr:       addr,
+		difficulty: difficulty,
+		jobs:       make(map[string]*stratumJob),
+		sessions:   make(map[*stratumSession]struct{}),
+		gauges:     make(map[string]metrics.Gauge),
+		banned:     make(map[string]time.Time),
+	}, nil
+}
+
+func (a *StratumAgent) Work() chan<- *Work {
+	return a.workCh
+}
+
+func (a *StratumAgent) SetReturnCh(returnCh chan<- *Result) {
+	a.returnCh = returnCh
+}
+
+func (a *StratumAgent) Start() {
+	if !atomic.CompareAndSwapInt32(&a.running, 0, 1) {
+		return
+	}
+	listener, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		log.Error("Failed to start stratum server", "addr", a.addr, "err", err)
+		atomic.StoreInt32(&a.running, 0)
+		return
+	}
+	a.mu.Lock()
+	a.listener = listener
+	a.mu.Unlock()
+
+	a.quitCh = make(chan struct{})
+	a.workCh = make(chan *Work, 1)
+	go a.listen(listener, a.quitCh)
+	go a.loop(a.workCh, a.quitCh)
+
+	log.Info("Stratum server started", "addr", listener.Addr())
+}
+
+func (a *StratumAgent) Stop() {
+	if !atomic.CompareAndSwapInt32(&a.running, 1, 0) {
+		return
+	}
+	close(a.quitCh)
+	close(a.workCh)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.listener.Close()
+	for session := range a.sessions {
+		session.conn.Close()
+	}
+	a.current = nil
+	a.jobs = make(map[string]*stratumJob)
+
+	log.Info("Stratum server stopped", "addr", a.addr)
+}
+
+// GetHashRate returns the hashrate of all the connected workers combined, as
+// measured from their accepted shares.
+func (a *StratumAgent) GetHashRate() (tot int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for session := range a.sessions {
+		tot += int64(session.hashrate)
+	}
+	return
+}
+
+// listen accepts the incoming connections of the stratum workers until the
+// listener is closed.
+func (a *StratumAgent) listen(listener net.Listener, quitCh chan struct{}) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-quitCh:
+			default:
+				log.Error("Stratum server failed", "err", err)
+			}
+			return
+		}
+		session, err := a.connect(conn)
+		if err != nil {
+			log.Warn("Rejected stratum worker", "remote", conn.RemoteAddr(), "err", err)
+			conn.Close()
+			continue
+		}
+		go a.handle(session)
+	}
+}
+
+// connect registers a new stratum session, reserving a unique nonce prefix for it.
+func (a *StratumAgent) connect(conn net.Conn) (*stratumSession, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.sessions) > math.MaxUint16 {
+		return nil, errors.New("too many sessions")
+	}
+	host := remoteHost(conn)
+	if until, ok := a.banned[host]; ok {
+		if time.Now().Before(until) {
+			return nil, errors.New("banned for invalid shares")
+		}
+		delete(a.banned, host)
+	}
+	inuse := make(map[string]struct{}, len(a.sessions))
+	for session := range a.sessions {
+		inuse[session.extranonce] = struct{}{}
+	}
+	var extranonce string
+	for {
+		a.extranonce++
+		extranonce = fmt.Sprintf("%0*x", 2*stratumExtranonceSize, a.extranonce)
+		if _, ok := inuse[extranonce]; !ok {
+			break
+		}
+	}
+	session := &stratumSession{
+		conn:       conn,
+		extranonce: extranonce,
+		difficulty: a.difficulty,
+	}
+	a.sessions[session] = struct{}{}
+	stratumSessionGauge.Update(int64(len(a.sessions)))
+
+	return session, nil
+}
+
+// handle processes the method calls of a stratum worker until it disconnects.
+func (a *StratumAgent) handle(session *stratumSession) {
+	defer func() {
+		a.mu.Lock()
+		delete(a.sessions, session)
+		stratumSessionGauge.Update(int64(len(a.sessions)))
+		a.mu.Unlock()
+
+		session.conn.Close()
+	}()
+	logger := log.New("remote", session.conn.RemoteAddr())
+	logger.Debug("Stratum worker connected")
+
+	reader := bufio.NewScanner(session.conn)
+	for {
+		session.conn.SetReadDeadline(time.Now().Add(stratumReadTimeout))
+		if !reader.Scan() {
+			logger.Debug("Stratum worker disconnected", "err", reader.Err())
+			return
+		}
+		req := new(stratumRequest)
+		if err := json.Unmarshal(reader.Bytes(), req); err != nil {
+			logger.Debug("Invalid stratum message", "err", err)
+			return
+		}
+		result, err := a.call(session, req)
+		if err != nil {
+			logger.Trace("Stratum call failed", "method", req.Method, "err", err)
+		}
+		res := &stratumResponse{Id: req.Id, Result: result}
+		if err != nil {
+			res.Result, res.Error = false, err
+		}
+		if err := session.send(res); err != nil {
+			logger.Debug("Failed to reply to stratum worker", "err", err)
+			return
+		}
+		// Drop and ban the workers flooding the server with invalid shares
+		if atomic.LoadInt32(&session.invalid) > stratumMaxInvalidShares {
+			logger.Warn("Banning stratum worker for invalid shares", "worker", session.worker, "duration", stratumBanDuration)
+
+			a.mu.Lock()
+			a.banned[remoteHost(session.conn)] = time.Now().Add(stratumBanDuration)
+			a.mu.Unlock()
+			return
+		}
+		// Hand out the current job after the worker authorized itself
+		if req.Method == "mining.authorize" && err == nil {
+			a.mu.Lock()
+			job := a.current
+			a.mu.Unlock()
+
+			session.send(a.difficultyNotification(session))
+			if job != nil {
+				session.send(a.jobNotification(job, true))
+			}
+		}
+	}
+}
+
+// call executes a single method call of a stratum worker.
+func (a *StratumAgent) call(session *stratumSession, req *stratumRequest) (interface{}, error) {
+	switch req.Method {
+	case "mining.subscribe":
+		a.mu.Lock()
+		session.subscribed = true
+		a.mu.Unlock()
+
+		notify := []string{"mining.notify", session.extranonce, stratumProtocol}
+		return []interface{}{notify, session.extranonce}, nil
+
+	case "mining.extranonce.subscribe":
+		return true, nil
+
+	case "mining.authorize":
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		if !session.subscribed {
+			return nil, errStratumNotSubscribed
+		}
+		worker := req.param(0)
+		if worker == "" {
+			return nil, errStratumUnauthorized
+		}
+		session.worker = worker
+		log.Info("Stratum worker authorized", "worker", worker, "remote", session.conn.RemoteAddr())
+		return true, nil
+
+	case "mining.submit":
+		return a.submit(session, req.param(1), req.param(2))
+
+	default:
+		return nil, errStratumUnknown
+	}
+}
+
+// submit verifies a share of a stratum worker, sealing the block of the job if
+// the share meets the block difficulty too. The job and the nonce are checked
+// before the costly proof-of-work verification, and invalid shares are counted
+// against the worker.
+func (a *StratumAgent) submit(session *stratumSession, id string, nonceHex string) (bool, error) {
+	a.mu.Lock()
+	if session.worker == "" {
+		a.mu.Unlock()
+		return false, errStratumUnauthorized
+	}
+	// Assemble the full nonce from the session prefix and the submitted suffix
+	nonceHex = strings.TrimPrefix(nonceHex, "0x")
+	if len(nonceHex) != 2*(len(types.BlockNonce{})-stratumExtranonceSize) {
+		a.mu.Unlock()
+		return false, invalidShare(session, errStratumUnknown)
+	}
+	blob, err := hex.DecodeString(session.extranonce + nonceHex)
+	if err != nil {
+		a.mu.Unlock()
+		return false, invalidShare(session, errStratumUnknown)
+	}
+	var nonce types.BlockNonce
+	copy(nonce[:], blob)
+
+	// Make sure the job is still current and the nonce wasn't submitted before
+	job := a.jobs[id]
+	if job == nil {
+		a.mu.Unlock()
+		stratumStaleMeter.Mark(1)
+		return false, errStratumJobNotFound
+	}
+	if _, ok := job.seen[nonce]; ok {
+		a.mu.Unlock()
+		return false, invalidShare(session, errStratumDuplicate)
+	}
+	job.seen[nonce] = struct{}{}
+	difficulty := session.difficulty
+	a.mu.Unlock()
+
+	// Verify the proof-of-work of the share against both difficulties
+	header := job.work.Block.Header()
+	digest, result := a.engine.Hashimoto(header, nonce)
+	value := result.Big()
+
+	if value.Cmp(new(big.Int).Div(maxUint256, header.Difficulty)) <= 0 {
+		header.Nonce, header.MixDigest = nonce, digest
+		if err := a.engine.VerifySeal(a.chain, header); err != nil {
+			log.Warn("Invalid proof-of-work submitted", "worker", session.worker, "err", err)
+			return false, invalidShare(session, errStratumUnknown)
+		}
+		log.Info("Stratum worker sealed block", "worker", session.worker, "number", header.Number, "hash", header.Hash())
+		stratumBlockMeter.Mark(1)
+
+		a.returnCh <- &Result{job.work, job.work.Block.WithSeal(header)}
+	} else if value.Cmp(shareTarget(difficulty)) > 0 {
+		return false, invalidShare(session, errStratumLowDifficulty)
+	}
+	stratumAcceptMeter.Mark(1)
+
+	a.mu.Lock()
+	session.hashes += difficulty * stratumDifficulty1
+	a.mu.Unlock()
+
+	return true, nil
+}
+
+// invalidShare counts an invalid share against the worker of a session, returning
+// the error to report for it.
+func invalidShare(session *stratumSession, err error) error {
+	atomic.AddInt32(&session.invalid, 1)
+	stratumInvalidMeter.Mark(1)
+	return err
+}
+
+// remoteHost returns the host of the remote end of a connection, which the bans
+// are tracked by.
+func remoteHost(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// loop monitors mining events on the work and quit channels, pushing the new jobs
+// to the workers and periodically retargeting their share difficulties.
+func (a *StratumAgent) loop(workCh chan *Work, quitCh chan struct{}) {
+	ticker := time.NewTicker(stratumRetarget)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-quitCh:
+			return
+
+		case work := <-workCh:
+			// New sealing work arrived, invalidate the old jobs on head change
+			a.mu.Lock()
+			hash := work.Block.HashNoNonce()
+			job := &stratumJob{
+				id:    hex.EncodeToString(hash[:8]),
+				work:  work,
+				seen:  make(map[types.BlockNonce]struct{}),
+				clean: a.current == nil || a.current.work.Block.NumberU64() != work.Block.NumberU64(),
+			}
+			if job.clean {
+				a.jobs = make(map[string]*stratumJob)
+			}
+			a.jobs[job.id] = job
+			a.current = job
+
+			sessions := a.authorized()
+			a.mu.Unlock()
+
+			notification := a.jobNotification(job, job.clean)
+			for _, session := range sessions {
+				if err := session.send(notification); err != nil {
+					session.conn.Close()
+				}
+			}
+
+		case now := <-ticker.C:
+			// Drop the expired jobs and retarget the workers
+			a.mu.Lock()
+			for id, job := range a.jobs {
+				if job != a.current && time.Since(job.work.createdAt) > stratumJobLifetime {
+					delete(a.jobs, id)
+				}
+			}
+			for host, until := range a.banned {
+				if now.After(until) {
+					delete(a.banned, host)
+				}
+			}
+			elapsed := now.Sub(last)
+			last = now
+
+			rates := make(map[string]float64)
+			changed := make([]*stratumSession, 0)
+			for _, session := range a.authorized() {
+				session.hashrate = session.hashes / elapsed.Seconds()
+				rates[session.worker] += session.hashrate
+
+				if difficulty := retargetShareDifficulty(session.difficulty, session.hashes, elapsed); difficulty != session.difficulty {
+					session.difficulty = difficulty
+					changed = append(changed, session)
+				}
+				session.hashes = 0
+				atomic.StoreInt32(&session.invalid, 0)
+			}
+			a.reportHashrates(rates)
+			job := a.current
+			a.mu.Unlock()
+
+			// Miners only apply the new difficulty with the next job, so resend it
+			for _, session := range changed {
+				session.send(a.difficultyNotification(session))
+				if job != nil {
+					session.send(a.jobNotification(job, false))
+				}
+			}
+		}
+	}
+}
+
+// authorized returns the sessions of all the authorized workers. The caller must
+// hold the agent lock.
+func (a *StratumAgent) authorized() []*stratumSession {
+	sessions := make([]*stratumSession, 0, len(a.sessions))
+	for session := range a.sessions {
+		if session.worker != "" {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// reportHashrates updates the hashrate metrics of the workers, dropping the ones
+// of the disconnected workers. The caller must hold the agent lock.
+func (a *StratumAgent) reportHashrates(rates map[string]float64) {
+	for worker, gauge := range a.gauges {
+		if _, ok := rates[worker]; !ok {
+			metrics.DefaultRegistry.Unregister(stratumHashrateMetric(worker))
+			delete(a.gauges, worker)
+			gauge.Update(0)
+		}
+	}
+	for worker, rate := range rates {
+		gauge, ok := a.gauges[worker]
+		if !ok {
+			gauge = metrics.GetOrRegisterGauge(stratumHashrateMetric(worker), nil)
+			a.gauges[worker] = gauge
+		}
+		gauge.Update(int64(rate))
+	}
+}
+
+// jobNotification creates the mining.notify message of a job.
+func (a *StratumAgent) jobNotification(job *stratumJob, clean bool) *stratumNotification {
+	block := job.work.Block
+	return &stratumNotification{
+		Method: "mining.notify",
+		Params: []interface{}{
+			job.id,
+			hex.EncodeToString(ethash.SeedHash(block.NumberU64())),
+			hex.EncodeToString(block.HashNoNonce().Bytes()),
+			clean,
+		},
+	}
+}
+
+// difficultyNotification creates the mining.set_difficulty message of a session.
+func (a *StratumAgent) difficultyNotification(session *stratumSession) *stratumNotification {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return &stratumNotification{
+		Method: "mining.set_difficulty",
+		Params: []interface{}{session.difficulty},
+	}
+}
+
+// stratumHashrateMetric returns the name of the hashrate metric of a worker.
+func stratumHashrateMetric(worker string) string {
+	return "miner/stratum/workers/" + worker + "/hashrate"
+}
+
+// shareTarget converts a share difficulty into the boundary the proof-of-work
+// value of a share must not exceed.
+func shareTarget(difficulty float64) *big.Int {
+	hashes, _ := new(big.Float).Mul(big.NewFloat(difficulty), big.NewFloat(stratumDifficulty1)).Int(nil)
+	if hashes.Sign() <= 0 {
+		return new(big.Int).Set(maxUint256)
+	}
+	return hashes.Div(maxUint256, hashes)
+}
+
+// retargetShareDifficulty recalculates the share difficulty of a worker from the
+// hashes it proved over the elapsed time, aiming for a share every share interval.
+// The difficulty is changed by at most a factor of four at once, and it's kept
+// as is if it's within a factor of two of the ideal one to avoid constant churn.
+func retargetShareDifficulty(current float64, hashes float64, elapsed time.Duration) float64 {
+	next := current / 2
+	if hashes > 0 {
+		next = hashes / elapsed.Seconds() * stratumShareInterval.Seconds() / stratumDifficulty1
+		if next > current/2 && next < current*2 {
+			return current
+		}
+	}
+	if next > current*4 {
+		next = current * 4
+	}
+	if next < current/4 {
+		next = current / 4
+	}
+	if next < stratumMinDifficulty {
+		next = stratumMinDifficulty
+	}
+	return next
+}
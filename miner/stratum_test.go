@@ -0,0 +1,202 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// stratumTestClient is a stratum worker connected to a test server.
+type stratumTestClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Scanner
+}
+
+func (c *stratumTestClient) call(id int, method string, params ...string) {
+	blob, _ := json.Marshal(map[string]interface{}{"id": id, "method": method, "params": params})
+	if _, err := c.conn.Write(append(blob, '\n')); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+}
+
+func (c *stratumTestClient) read() map[string]interface{} {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if !c.reader.Scan() {
+		c.t.Fatalf("failed to read message: %v", c.reader.Err())
+	}
+	msg := make(map[string]interface{})
+	if err := json.Unmarshal(c.reader.Bytes(), &msg); err != nil {
+		c.t.Fatalf("failed to decode message: %v", err)
+	}
+	return msg
+}
+
+// Tests that stratum workers get the jobs pushed, and that their shares are
+// verified and sealed into blocks.
+func TestStratumMining(t *testing.T) {
+	agent, err := NewStratumAgent(nil, ethash.NewFaker(), "127.0.0.1:0", DefaultStratumDifficulty)
+	if err != nil {
+		t.Fatalf("failed to create stratum agent: %v", err)
+	}
+	results := make(chan *Result, 1)
+	agent.SetReturnCh(results)
+	agent.Start()
+	defer agent.Stop()
+
+	conn, err := net.Dial("tcp", agent.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := &stratumTestClient{t: t, conn: conn, reader: bufio.NewScanner(conn)}
+
+	// Subscribe and authorize, expecting the difficulty in return
+	client.call(1, "mining.subscribe", "tester", stratumProtocol)
+	res := client.read()
+	extranonce := res["result"].([]interface{})[1].(string)
+	if len(extranonce) != 2*stratumExtranonceSize {
+		t.Fatalf("extranonce length mismatch: have %d, want %d", len(extranonce), 2*stratumExtranonceSize)
+	}
+	client.call(2, "mining.authorize", "rig1", "x")
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("authorization failed: %v", res["error"])
+	}
+	if msg := client.read(); msg["method"] != "mining.set_difficulty" {
+		t.Fatalf("method mismatch: have %v, want mining.set_difficulty", msg["method"])
+	}
+	// Push a new job and ensure the worker is notified
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1000)})
+	agent.Work() <- &Work{Block: block, createdAt: time.Now()}
+
+	msg := client.read()
+	if msg["method"] != "mining.notify" {
+		t.Fatalf("method mismatch: have %v, want mining.notify", msg["method"])
+	}
+	params := msg["params"].([]interface{})
+	if params[2] != hex.EncodeToString(block.HashNoNonce().Bytes()) {
+		t.Errorf("header hash mismatch: have %v, want %x", params[2], block.HashNoNonce())
+	}
+	if params[3] != true {
+		t.Errorf("job of a new head not clean")
+	}
+	job := params[0].(string)
+
+	// Submit a share sealing the block, and ensure duplicates and unknown jobs are rejected
+	client.call(3, "mining.submit", "rig1", job, "000000000001")
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("share rejected: %v", res["error"])
+	}
+	select {
+	case result := <-results:
+		want, _ := hex.DecodeString(extranonce + "000000000001")
+		if nonce := result.Block.Header().Nonce; !bytes.Equal(nonce[:], want) {
+			t.Errorf("sealed nonce mismatch: have %x, want %x", nonce, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("block not sealed")
+	}
+	client.call(4, "mining.submit", "rig1", job, "000000000001")
+	if res := client.read(); res["result"] != false {
+		t.Errorf("duplicate share accepted")
+	}
+	client.call(5, "mining.submit", "rig1", "deadbeef", "000000000002")
+	if res := client.read(); res["result"] != false {
+		t.Errorf("share of unknown job accepted")
+	}
+}
+
+// Tests that the share difficulty of the workers is retargeted towards the
+// share interval, but only gradually and not on small fluctuations.
+func TestStratumRetarget(t *testing.T) {
+	tests := []struct {
+		current float64
+		ideal   float64 // Share difficulty the worker would need for the share interval
+		want    float64
+	}{
+		{1, 1, 1},      // exactly on target
+		{1, 1.5, 1},    // within the tolerated fluctuation
+		{1, 3, 3},      // too many shares
+		{1, 10, 4},     // too many shares, capped increase
+		{1, 0.1, 0.25}, // too few shares, capped decrease
+		{1, 0, 0.5},    // no shares at all
+		{stratumMinDifficulty, 0, stratumMinDifficulty}, // never below the minimum
+	}
+	for i, tt := range tests {
+		hashes := tt.ideal * stratumDifficulty1
+		if have := retargetShareDifficulty(tt.current, hashes, stratumShareInterval); have != tt.want {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that workers submitting too many invalid shares are disconnected and
+// banned, without their shares being verified.
+func TestStratumInvalidShares(t *testing.T) {
+	agent, err := NewStratumAgent(nil, ethash.NewFaker(), "127.0.0.1:0", DefaultStratumDifficulty)
+	if err != nil {
+		t.Fatalf("failed to create stratum agent: %v", err)
+	}
+	agent.SetReturnCh(make(chan *Result, 1))
+	agent.Start()
+	defer agent.Stop()
+
+	conn, err := net.Dial("tcp", agent.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := &stratumTestClient{t: t, conn: conn, reader: bufio.NewScanner(conn)}
+
+	client.call(1, "mining.subscribe", "tester", stratumProtocol)
+	client.read()
+	client.call(2, "mining.authorize", "rig1", "x")
+	client.read()
+	client.read()
+
+	// Submit malformed shares until the worker gets dropped
+	for i := 0; i <= stratumMaxInvalidShares; i++ {
+		client.call(3+i, "mining.submit", "rig1", "deadbeef", "00")
+		if res := client.read(); res["result"] != false {
+			t.Fatalf("share %d: malformed share accepted", i)
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if client.reader.Scan() {
+		t.Fatalf("worker not disconnected, received %s", client.reader.Bytes())
+	}
+	// Ensure that the worker can't reconnect while banned
+	if conn, err = net.Dial("tcp", agent.listener.Addr().String()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("banned worker not disconnected")
+	}
+}
@@ -19,6 +19,7 @@ package miner
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -49,6 +50,16 @@ const (
 	chainHeadChanSize = 10
 	// chainSideChanSize is the size of channel listening to ChainSideEvent.
 	chainSideChanSize = 10
+
+	// minRecommitInterval is the minimal time interval to recreate the sealing work
+	// with any newly arrived transactions.
+	minRecommitInterval = 1 * time.Second
+	// maxRecommitInterval is the maximum time interval the recreation of the sealing
+	// work is backed off to if it doesn't collect more fees.
+	maxRecommitInterval = 15 * time.Second
+	// intervalAdjustRatio is the impact a single adjustment has on the recommit
+	// interval.
+	intervalAdjustRatio = 0.1
 )
 
 // Agent can register themself with the worker
@@ -114,6 +125,12 @@ type worker struct {
 
 	coinbase common.Address
 	extra    []byte
+	ordering TxOrderingFunc // Policy ordering the transactions packed into blocks
+	gasFloor uint64         // Target gas limit of the mined blocks when they are empty
+	gasCeil  uint64         // Target gas limit of the mined blocks when they are full
+
+	recommit int64 // Minimum interval of recreating the sealing work with new transactions (atomic)
+	newTxs   int32 // Number of transactions arrived since the sealing work was created (atomic)
 
 	currentMu sync.Mutex
 	current   *Work
@@ -148,6 +165,10 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		proc:           eth.BlockChain().Validator(),
 		possibleUncles: make(map[common.Hash]*types.Block),
 		coinbase:       coinbase,
+		ordering:       newPriceOrdering,
+		gasFloor:       params.TargetGasLimit,
+		gasCeil:        math.MaxUint64,
+		recommit:       int64(DefaultRecommitInterval),
 		agents:         make(map[Agent]struct{}),
 		unconfirmed:    newUnconfirmedBlocks(eth.BlockChain(), miningLogAtDepth),
 	}
@@ -199,6 +220,28 @@ func (self *worker) setExtra(extra []byte) {
 	self.extra = extra
 }
 
+func (self *worker) setGasLimit(floor, ceil uint64) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.gasFloor, self.gasCeil = floor, ceil
+}
+
+func (self *worker) setRecommitInterval(interval time.Duration) {
+	atomic.StoreInt64(&self.recommit, int64(interval))
+}
+
+func (self *worker) setTxOrdering(ordering TxOrderingFunc) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.ordering = ordering
+}
+
+// orderTxs creates the given ordering of the transactions, grouped by account
+// and sorted by nonce.
+func (self *worker) orderTxs(ordering TxOrderingFunc, txs map[common.Address]types.Transactions) TxOrdering {
+	return ordering(self.current.signer, txs, self.eth.TxPool().Arrival)
+}
+
 func (self *worker) pending() (*types.Block, *state.StateDB) {
 	if atomic.LoadInt32(&self.mining) == 0 {
 		// return a snapshot to avoid contention on currentMu mutex
@@ -271,12 +314,34 @@ func (self *worker) update() {
 	defer self.chainHeadSub.Unsubscribe()
 	defer self.chainSideSub.Unsubscribe()
 
+	// Periodically recreate the sealing work while mining to include the newly
+	// arrived transactions, backing off if it doesn't pay off
+	interval := time.Duration(atomic.LoadInt64(&self.recommit))
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
 	for {
 		// A real event arrived, process interesting content
 		select {
 		// Handle ChainHeadEvent
 		case <-self.chainHeadCh:
 			self.commitNewWork()
+			resetTimer(timer, interval)
+
+		// Recreate the sealing work if new transactions arrived since
+		case <-timer.C:
+			minimum := time.Duration(atomic.LoadInt64(&self.recommit))
+			switch {
+			case atomic.LoadInt32(&self.mining) == 0:
+				interval = minimum
+			case atomic.LoadInt32(&self.newTxs) > 0:
+				paid := self.recommitWork()
+				interval = recalcRecommit(interval, minimum, !paid)
+				log.Trace("Recreated sealing work", "paid", paid, "interval", interval)
+			case interval < minimum:
+				interval = minimum
+			}
+			timer.Reset(interval)
 
 		// Handle ChainSideEvent
 		case ev := <-self.chainSideCh:
@@ -292,17 +357,23 @@ func (self *worker) update() {
 			// already included in the current mining block. These transactions will
 			// be automatically eliminated.
 			if atomic.LoadInt32(&self.mining) == 0 {
+				self.mu.Lock()
+				ordering := self.ordering
+				self.mu.Unlock()
+
 				self.currentMu.Lock()
 				txs := make(map[common.Address]types.Transactions)
 				for _, tx := range ev.Txs {
 					acc, _ := types.Sender(self.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(self.current.signer, txs)
+				txset := self.orderTxs(ordering, txs)
 				self.current.commitTransactions(self.mux, txset, self.chain, self.coinbase)
 				self.updateSnapshot()
 				self.currentMu.Unlock()
 			} else {
+				atomic.AddInt32(&self.newTxs, int32(len(ev.Txs)))
+
 				// If we're mining, but nothing is being processed, wake on new transactions
 				if self.config.Clique != nil && self.config.Clique.Period == 0 {
 					self.commitNewWork()
@@ -452,7 +523,7 @@ func (self *worker) commitNewWork() {
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent),
+		GasLimit:   core.CalcGasLimit(parent, self.gasFloor, self.gasCeil),
 		Extra:      self.extra,
 		Time:       big.NewInt(tstamp),
 	}
@@ -493,13 +564,14 @@ func (self *worker) commitNewWork() {
 	}
 	// 현재 프로세싱이 가능한 트렌젝션을 검색하고 
 	// 관련 어카운트별로 그룹핑한 후 논스로 정렬한다.
+	atomic.StoreInt32(&self.newTxs, 0)
 	pending, err := self.eth.TxPool().Pending()
 	if err != nil {
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
 	//논스 존중 방식으로 가격정렬된 트렌젝션의 세트를 만든다
-	txs := types.NewTransactionsByPriceAndNonce(self.current.signer, pending)
+	txs := self.orderTxs(self.ordering, pending)
 
 	// 트렌젝션을 적용하고 트렌젝션과 영수증을 만든다
 	// 주어진 스테이트 DB에 트렌젝션을 적용하고,
@@ -548,6 +620,56 @@ func (self *worker) commitNewWork() {
 	self.updateSnapshot()
 }
 
+// recommitWork recreates the sealing work on top of the same parent to include
+// the newly arrived transactions, returning whether the new work collects more
+// fees than the replaced one.
+func (self *worker) recommitWork() bool {
+	self.currentMu.Lock()
+	prev := self.current
+	self.currentMu.Unlock()
+
+	self.commitNewWork()
+
+	self.currentMu.Lock()
+	work := self.current
+	self.currentMu.Unlock()
+
+	if work == prev || work.Block == nil || prev.Block == nil || work.header.ParentHash != prev.header.ParentHash {
+		return false
+	}
+	return work.fees().Cmp(prev.fees()) > 0
+}
+
+// recalcRecommit moves the recommit interval towards the maximum if recreating
+// the sealing work didn't pay off, or back towards the configured minimum if it
+// did.
+func recalcRecommit(interval, minimum time.Duration, backoff bool) time.Duration {
+	target := minimum
+	if backoff {
+		target = maxRecommitInterval
+	}
+	next := time.Duration(float64(interval)*(1-intervalAdjustRatio) + float64(target)*intervalAdjustRatio)
+	if next < minimum {
+		next = minimum
+	}
+	if next > maxRecommitInterval && minimum < maxRecommitInterval {
+		next = maxRecommitInterval
+	}
+	return next
+}
+
+// resetTimer restarts a timer with the given interval, discarding any expiry not
+// yet received.
+func resetTimer(timer *time.Timer, interval time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(interval)
+}
+
 func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
 	hash := uncle.Hash()
 	if work.uncles.Has(hash) {
@@ -576,8 +698,18 @@ func (self *worker) updateSnapshot() {
 	self.snapshotState = self.current.state.Copy()
 }
 
+// fees returns the total transaction fees collected by the work.
+func (env *Work) fees() *big.Int {
+	fees := new(big.Int)
+	for i, tx := range env.txs {
+		fee := new(big.Int).SetUint64(env.receipts[i].GasUsed)
+		fees.Add(fees, fee.Mul(fee, tx.GasPrice()))
+	}
+	return fees
+}
+
 // 트렌젝션을 실행하고 mux를 통해 펜딩 스테이트 이벤트를 전송한다
-func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address) {
+func (env *Work) commitTransactions(mux *event.TypeMux, txs TxOrdering, bc *core.BlockChain, coinbase common.Address) {
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
 	}
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testBankKey, _  = crypto.GenerateKey()
+	testBankAddress = crypto.PubkeyToAddress(testBankKey.PublicKey)
+	testBankFunds   = big.NewInt(1000000000000000000)
+)
+
+// testWorkerBackend implements Backend with a chain and a transaction pool on
+// top of a genesis block funding the test account.
+type testWorkerBackend struct {
+	db     ethdb.Database
+	chain  *core.BlockChain
+	txPool *core.TxPool
+}
+
+func newTestWorkerBackend(t *testing.T) *testWorkerBackend {
+	db := ethdb.NewMemDatabase()
+	genesis := &core.Genesis{
+		Config:   params.TestChainConfig,
+		GasLimit: params.GenesisGasLimit,
+		Alloc:    core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+	}
+	genesis.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+
+	return &testWorkerBackend{
+		db:     db,
+		chain:  chain,
+		txPool: core.NewTxPool(config, params.TestChainConfig, chain),
+	}
+}
+
+func (b *testWorkerBackend) AccountManager() *accounts.Manager { return nil }
+func (b *testWorkerBackend) BlockChain() *core.BlockChain      { return b.chain }
+func (b *testWorkerBackend) TxPool() *core.TxPool              { return b.txPool }
+func (b *testWorkerBackend) ChainDb() ethdb.Database           { return b.db }
+
+func (b *testWorkerBackend) close() {
+	b.txPool.Stop()
+	b.chain.Stop()
+}
+
+// testAgent is a mining agent which hands the sealing work to the test instead
+// of sealing it.
+type testAgent struct {
+	work chan *Work
+}
+
+func (a *testAgent) Work() chan<- *Work         { return a.work }
+func (a *testAgent) SetReturnCh(chan<- *Result) {}
+func (a *testAgent) Stop()                      {}
+func (a *testAgent) Start()                     {}
+func (a *testAgent) GetHashRate() int64         { return 0 }
+
+// Tests that the recommit interval is backed off towards the maximum while the
+// recreated sealing work doesn't pay off, and recovers to the minimum otherwise.
+func TestRecommitBackoff(t *testing.T) {
+	minimum := 2 * time.Second
+
+	// Back off until the interval saturates at the maximum
+	interval := minimum
+	for i := 0; i < 100; i++ {
+		next := recalcRecommit(interval, minimum, true)
+		if next < interval || next > maxRecommitInterval {
+			t.Fatalf("backoff %d: interval %v -> %v out of bounds", i, interval, next)
+		}
+		interval = next
+	}
+	if maxRecommitInterval-interval > time.Second {
+		t.Errorf("backed off interval mismatch: have %v, want ~%v", interval, maxRecommitInterval)
+	}
+	// Recover until the interval reaches the minimum again
+	for i := 0; i < 100; i++ {
+		next := recalcRecommit(interval, minimum, false)
+		if next > interval || next < minimum {
+			t.Fatalf("recovery %d: interval %v -> %v out of bounds", i, interval, next)
+		}
+		interval = next
+	}
+	if interval-minimum > time.Second {
+		t.Errorf("recovered interval mismatch: have %v, want ~%v", interval, minimum)
+	}
+	// A minimum above the backoff limit is never undercut
+	if next := recalcRecommit(time.Minute, time.Minute, true); next != time.Minute {
+		t.Errorf("interval undercut the minimum: have %v, want %v", next, time.Minute)
+	}
+}
+
+// Tests that the sealing work is recreated on top of the same parent when the
+// recommit timer fires, including the transactions that arrived in the meantime.
+func TestRecommitNewTransactions(t *testing.T) {
+	backend := newTestWorkerBackend(t)
+	defer backend.close()
+
+	w := newWorker(params.TestChainConfig, ethash.NewFaker(), common.Address{1}, backend, new(event.TypeMux))
+
+	agent := &testAgent{work: make(chan *Work, 16)}
+	w.register(agent)
+	w.start()
+	defer w.stop()
+
+	// Start sealing an empty block
+	w.commitNewWork()
+
+	var first *Work
+	select {
+	case first = <-agent.work:
+		if txs := len(first.Block.Transactions()); txs != 0 {
+			t.Fatalf("initial work transaction count mismatch: have %d, want 0", txs)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("initial sealing work not received")
+	}
+	// Add a transaction and wait for the recommit to pick it up
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{2}, big.NewInt(1000), params.TxGas, big.NewInt(params.Shannon), nil), types.NewEIP155Signer(params.TestChainConfig.ChainId), testBankKey)
+	if err := backend.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	select {
+	case work := <-agent.work:
+		if work.Block.ParentHash() != first.Block.ParentHash() {
+			t.Errorf("parent mismatch: have %x, want %x", work.Block.ParentHash(), first.Block.ParentHash())
+		}
+		if txs := work.Block.Transactions(); len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+			t.Errorf("recreated work transactions mismatch: have %v, want [%x]", txs, tx.Hash())
+		}
+	case <-time.After(DefaultRecommitInterval + time.Second):
+		t.Fatalf("sealing work not recreated")
+	}
+}
@@ -102,6 +102,64 @@ func (opts *TransactOpts) SetGasPrice(price *BigInt)   { opts.opts.GasPrice = pr
 func (opts *TransactOpts) SetGasLimit(limit int64)     { opts.opts.GasLimit = uint64(limit) }
 func (opts *TransactOpts) SetContext(context *Context) { opts.opts.Context = context.context }
 
+// FilterOpts is the collection of options to fine tune filtering for past
+// contract events.
+type FilterOpts struct {
+	opts bind.FilterOpts
+}
+
+// NewFilterOpts creates a new option set for filtering past contract events.
+func NewFilterOpts() *FilterOpts {
+	return new(FilterOpts)
+}
+
+func (opts *FilterOpts) GetStart() int64 { return int64(opts.opts.Start) }
+func (opts *FilterOpts) GetEnd() int64 {
+	if opts.opts.End == nil {
+		return -1
+	}
+	return int64(*opts.opts.End)
+}
+
+func (opts *FilterOpts) SetStart(start int64) { opts.opts.Start = uint64(start) }
+func (opts *FilterOpts) SetEnd(end int64) {
+	if end < 0 {
+		opts.opts.End = nil // filter up to the latest block
+		return
+	}
+	number := uint64(end)
+	opts.opts.End = &number
+}
+func (opts *FilterOpts) SetContext(context *Context) { opts.opts.Context = context.context }
+
+// WatchOpts is the collection of options to fine tune subscribing for contract
+// events.
+type WatchOpts struct {
+	opts bind.WatchOpts
+}
+
+// NewWatchOpts creates a new option set for subscribing to contract events.
+func NewWatchOpts() *WatchOpts {
+	return new(WatchOpts)
+}
+
+func (opts *WatchOpts) GetStart() int64 {
+	if opts.opts.Start == nil {
+		return -1
+	}
+	return int64(*opts.opts.Start)
+}
+
+func (opts *WatchOpts) SetStart(start int64) {
+	if start < 0 {
+		opts.opts.Start = nil // watch from the current head
+		return
+	}
+	number := uint64(start)
+	opts.opts.Start = &number
+}
+func (opts *WatchOpts) SetContext(context *Context) { opts.opts.Context = context.context }
+
 // BoundContract is the base wrapper object that reflects a contract on the
 // Ethereum network. It contains a collection of methods that are used by the
 // higher level contract bindings to operate.
@@ -189,3 +247,82 @@ func (c *BoundContract) Transfer(opts *TransactOpts) (tx *Transaction, _ error)
 	}
 	return &Transaction{rawTx}, nil
 }
+
+// FilterLogs retrieves the past logs of the given contract event, matching the
+// topics of its indexed arguments (the event signature is added implicitly).
+func (c *BoundContract) FilterLogs(opts *FilterOpts, event string, topics *Topics) (logs *Logs, _ error) {
+	ch, sub, err := c.contract.FilterLogs(&opts.opts, event, topicRules(topics)...)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	var res []*types.Log
+	for {
+		select {
+		case log := <-ch:
+			res = append(res, &log)
+		case err := <-sub.Err():
+			if err != nil {
+				return nil, err
+			}
+			// All logs have been queued, collect the buffered ones
+			for {
+				select {
+				case log := <-ch:
+					res = append(res, &log)
+				default:
+					return &Logs{res}, nil
+				}
+			}
+		}
+	}
+}
+
+// WatchLogs subscribes to the future logs of the given contract event, matching
+// the topics of its indexed arguments (the event signature is added implicitly).
+//
+// Typed event watchers are only generated for Java bindings, ObjC code needs to
+// watch the logs via this method and decode them with UnpackLog.
+func (c *BoundContract) WatchLogs(opts *WatchOpts, event string, topics *Topics, handler FilterLogsHandler, buffer int) (sub *Subscription, _ error) {
+	ch, rawSub, err := c.contract.WatchLogs(&opts.opts, event, topicRules(topics)...)
+	if err != nil {
+		return nil, err
+	}
+	// Start up a dispatcher to feed into the callback
+	go func() {
+		for {
+			select {
+			case log := <-ch:
+				handler.OnFilterLogs(&Log{&log})
+
+			case err := <-rawSub.Err():
+				if err != nil {
+					handler.OnError(err.Error())
+				}
+				return
+			}
+		}
+	}()
+	return &Subscription{rawSub}, nil
+}
+
+// UnpackLog decodes the arguments of a contract event from a log into out, which
+// must hold a default value of the correct type for every event argument.
+func (c *BoundContract) UnpackLog(out *Interfaces, event string, log *Log) error {
+	return c.contract.UnpackLogIntoValues(out.objects, event, *log.log)
+}
+
+// topicRules converts a mobile topic filter into the rules of the bind package.
+func topicRules(topics *Topics) [][]interface{} {
+	if topics == nil {
+		return nil
+	}
+	rules := make([][]interface{}, len(topics.topics))
+	for i, hashes := range topics.topics {
+		for _, hash := range hashes {
+			rules[i] = append(rules[i], hash)
+		}
+	}
+	return rules
+}
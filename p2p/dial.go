@@ -82,6 +82,7 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	reputation  *reputationTracker
 
 	lookupRunning bool
 	dialing       map[discover.NodeID]connFlag
@@ -193,7 +194,11 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 
 	var newtasks []task
 	addDial := func(flag connFlag, n *discover.Node) bool {
-		if err := s.checkDial(n, peers); err != nil {
+		err := s.checkDial(n, peers)
+		if err == nil && s.reputation.get(n.ID) < ReputationThreshold {
+			err = errBadReputation
+		}
+		if err != nil {
 			log.Trace("Skipping dial candidate", "id", n.ID, "addr", &net.TCPAddr{IP: n.IP, Port: int(n.TCP)}, "err", err)
 			return false
 		}
@@ -297,6 +302,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errBadReputation    = errors.New("reputation too low")
 )
 
 func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer) error {
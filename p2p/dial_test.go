@@ -432,6 +432,34 @@ func TestDialStateNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that nodes with bad reputation are not dialed.
+func TestDialStateReputation(t *testing.T) {
+	table := fakeTable{
+		{ID: uintID(1)},
+		{ID: uintID(2)},
+		{ID: uintID(3)},
+		{ID: uintID(4)},
+	}
+	dialer := newDialState(nil, nil, table, 10, nil)
+	dialer.reputation = newReputationTracker(nil)
+	dialer.reputation.adjust(uintID(2), ReputationThreshold-1)
+	dialer.reputation.adjust(uintID(3), ReputationThreshold)
+
+	runDialTest(t, dialtest{
+		init: dialer,
+		rounds: []round{
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: table[0]},
+					&dialTask{flags: dynDialedConn, dest: table[2]},
+					&dialTask{flags: dynDialedConn, dest: table[3]},
+					&discoverTask{},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that static dials are launched.
 func TestDialStateStaticDial(t *testing.T) {
 	wantStatic := []*discover.Node{
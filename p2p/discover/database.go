@@ -74,6 +74,9 @@ var (
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
 	nodeDBDiscoverPong      = nodeDBDiscoverRoot + ":lastpong"
 	nodeDBDiscoverFindFails = nodeDBDiscoverRoot + ":findfail"
+
+	nodeDBReputationRoot    = ":reputation"
+	nodeDBReputationUpdated = nodeDBReputationRoot + ":updated"
 )
 
 // newNodeDB creates a new node database for storing and retrieving infos about
@@ -353,6 +356,24 @@ func (db *nodeDB) updateFindFails(id NodeID, fails int) error {
 	return db.storeInt64(makeKey(id, nodeDBDiscoverFindFails), int64(fails))
 }
 
+// reputation retrieves the reputation score of a remote node, accumulated from
+// its behaviour across the connections made to it, and the time it was last
+// updated at.
+func (db *nodeDB) reputation(id NodeID) (int64, time.Time) {
+	score := db.fetchInt64(makeKey(id, nodeDBReputationRoot))
+	updated := time.Unix(db.fetchInt64(makeKey(id, nodeDBReputationUpdated)), 0)
+	return score, updated
+}
+
+// updateReputation updates the reputation score of a remote node, along with
+// the time it was calculated at.
+func (db *nodeDB) updateReputation(id NodeID, score int64, updated time.Time) error {
+	if err := db.storeInt64(makeKey(id, nodeDBReputationRoot), score); err != nil {
+		return err
+	}
+	return db.storeInt64(makeKey(id, nodeDBReputationUpdated), updated.Unix())
+}
+
 // querySeeds retrieves random nodes to be used as potential seed nodes
 // for bootstrapping.
 // querySeeds함수는 부트 스트래핑을 위한 시드노드로서 가능성있는 랜덤 노드를 반환한다
@@ -144,6 +144,16 @@ func TestNodeDBFetchStore(t *testing.T) {
 	if stored := db.findFails(node.ID); stored != num {
 		t.Errorf("find-node fails: value mismatch: have %v, want %v", stored, num)
 	}
+	// Check fetch/store operations on a node reputation object
+	if stored, updated := db.reputation(node.ID); stored != 0 || updated.Unix() != 0 {
+		t.Errorf("reputation: non-existing object: %v at %v", stored, updated)
+	}
+	if err := db.updateReputation(node.ID, -int64(num), inst); err != nil {
+		t.Errorf("reputation: failed to update: %v", err)
+	}
+	if stored, updated := db.reputation(node.ID); stored != -int64(num) || updated.Unix() != inst.Unix() {
+		t.Errorf("reputation: value mismatch: have %v at %v, want %v at %v", stored, updated, -num, inst)
+	}
 	// Check fetch/store operations on an actual node object
 	if stored := db.node(node.ID); stored != nil {
 		t.Errorf("node: non-existing object: %v", stored)
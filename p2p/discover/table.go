@@ -195,6 +195,17 @@ func (tab *Table) Self() *Node {
 	return tab.self
 }
 
+// Reputation retrieves the persisted reputation score of a node, along with the
+// time it was last updated at.
+func (tab *Table) Reputation(id NodeID) (int64, time.Time) {
+	return tab.db.reputation(id)
+}
+
+// SetReputation persists the reputation score of a node as of the given time.
+func (tab *Table) SetReputation(id NodeID, score int64, updated time.Time) error {
+	return tab.db.updateReputation(id, score, updated)
+}
+
 // ReadRandomNodes fills the given slice with random nodes from the
 // table. It will not write the same node more than once. The nodes in
 // the slice are copies and can be modified by the caller.
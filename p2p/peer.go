@@ -119,6 +119,8 @@ type Peer struct {
 	// events receives message send / receive events if set
 	// 이벤트들은 message send/recevie이벤트를 받는다
 	events *event.Feed
+
+	reputation *reputationTracker // Reputation scores of the remote nodes
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -127,6 +129,7 @@ func NewPeer(id discover.NodeID, name string, caps []Cap) *Peer {
 	pipe, _ := net.Pipe()
 	conn := &conn{fd: pipe, transport: nil, id: id, caps: caps, name: name}
 	peer := newPeer(conn, nil)
+	peer.reputation = newReputationTracker(nil)
 	close(peer.closed) // ensures Disconnect doesn't block
 	return peer
 }
@@ -173,6 +176,23 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// Reputation returns the reputation score of the remote node.
+func (p *Peer) Reputation() int64 {
+	return p.reputation.get(p.ID())
+}
+
+// AdjustReputation changes the reputation score of the remote node by delta and
+// returns the updated score. If the score drops below ReputationThreshold, the
+// peer is disconnected unless it's a trusted or static one.
+func (p *Peer) AdjustReputation(delta int64) int64 {
+	score := p.reputation.adjust(p.ID(), delta)
+	if delta < 0 && score < ReputationThreshold && !p.rw.is(trustedConn|staticDialedConn) {
+		p.log.Debug("Dropping peer with bad reputation", "reputation", score)
+		p.Disconnect(DiscUselessPeer)
+	}
+	return score
+}
+
 // String implements fmt.Stringer.
 // String 함수는 fmt.Stringer를 구현한다
 func (p *Peer) String() string {
@@ -466,7 +486,8 @@ type PeerInfo struct {
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Reputation int64                  `json:"reputation"` // Reputation score accumulated from the peer's behaviour
+	Protocols  map[string]interface{} `json:"protocols"`  // Sub-protocol specific metadata fields
 	// 노드의 id (암호화된 키)
 	// 노드의 이름(타입, version, os, 임의 데이터)
 	// 특정 피어에서 알려진 서브 프로토콜들 
@@ -487,10 +508,11 @@ func (p *Peer) Info() *PeerInfo {
 	// Assemble the generic peer metadata
 	// 피어의 메타데이터를 생성
 	info := &PeerInfo{
-		ID:        p.ID().String(),
-		Name:      p.Name(),
-		Caps:      caps,
-		Protocols: make(map[string]interface{}),
+		ID:         p.ID().String(),
+		Name:       p.Name(),
+		Caps:       caps,
+		Reputation: p.Reputation(),
+		Protocols:  make(map[string]interface{}),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
@@ -0,0 +1,194 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+const (
+	// MinReputation and MaxReputation are the bounds of a node's reputation score.
+	MinReputation = -1000
+	MaxReputation = 1000
+
+	// ReputationThreshold is the reputation score below which connected peers
+	// are dropped, inbound connections are refused and nodes aren't dialed any
+	// more. Trusted and static nodes are exempt.
+	ReputationThreshold = -100
+
+	// reputationHalfLife is the time it takes for a reputation score to decay
+	// halfway towards neutral, so that past behaviour is gradually forgotten.
+	reputationHalfLife = time.Hour
+
+	// reputationCleanupCycle is the time period for dropping the scores which
+	// decayed to neutral from the tracker.
+	reputationCleanupCycle = 10 * time.Minute
+)
+
+// reputationStore is a backend persisting the reputation scores of nodes along
+// with the time they were last updated at. It is implemented by the discovery
+// table, storing the scores in the node database.
+type reputationStore interface {
+	Reputation(id discover.NodeID) (int64, time.Time)
+	SetReputation(id discover.NodeID, score int64, updated time.Time) error
+}
+
+// reputation is the score of a node as of its last update.
+type reputation struct {
+	score   float64
+	updated mclock.AbsTime
+}
+
+// decayed returns the reputation score decayed towards neutral until now.
+func (r *reputation) decayed(now mclock.AbsTime) float64 {
+	elapsed := time.Duration(now - r.updated)
+	return r.score * math.Exp2(-float64(elapsed)/float64(reputationHalfLife))
+}
+
+// reputationTracker tracks the reputation scores of nodes across the connections
+// made to them, decaying them towards neutral over time. Scores are persisted
+// into the store if available, and loaded from it when a node is first seen. A
+// nil tracker considers every node neutral.
+type reputationTracker struct {
+	store  reputationStore // Persistent backend of the scores, nil if unavailable
+	scores map[discover.NodeID]*reputation
+	clock  func() mclock.AbsTime // Source of the current time, replaceable in tests
+	lock   sync.Mutex
+}
+
+// newReputationTracker creates a reputation tracker on top of the given store,
+// or an in-memory only one if nil.
+func newReputationTracker(store reputationStore) *reputationTracker {
+	return &reputationTracker{
+		store:  store,
+		scores: make(map[discover.NodeID]*reputation),
+		clock:  mclock.Now,
+	}
+}
+
+// get retrieves the reputation score of a node.
+func (t *reputationTracker) get(id discover.NodeID) int64 {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return int64(math.Round(t.current(id, t.clock())))
+}
+
+// adjust changes the reputation score of a node by delta, capping it to the
+// allowed bounds, and returns the updated score.
+func (t *reputationTracker) adjust(id discover.NodeID, delta int64) int64 {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.clock()
+	score := t.current(id, now) + float64(delta)
+	switch {
+	case score < MinReputation:
+		score = MinReputation
+	case score > MaxReputation:
+		score = MaxReputation
+	}
+	if math.Round(score) == 0 {
+		score = 0
+		delete(t.scores, id)
+	} else {
+		t.scores[id] = &reputation{score: score, updated: now}
+	}
+	if t.store != nil {
+		if err := t.store.SetReputation(id, int64(math.Round(score)), time.Now()); err != nil {
+			log.Warn("Failed to persist node reputation", "id", id, "err", err)
+		}
+	}
+	return int64(math.Round(score))
+}
+
+// current returns the decayed reputation score of a node, loading it from the
+// store if not yet tracked and forgetting it once it became neutral. The caller
+// must hold the lock.
+func (t *reputationTracker) current(id discover.NodeID, now mclock.AbsTime) float64 {
+	rep, ok := t.scores[id]
+	if !ok {
+		if rep = t.load(id, now); rep == nil {
+			return 0
+		}
+		t.scores[id] = rep
+	}
+	score := rep.decayed(now)
+	if math.Round(score) == 0 {
+		delete(t.scores, id)
+		return 0
+	}
+	return score
+}
+
+// load retrieves the persisted reputation of a node, converting the time of its
+// last update to the tracker's clock. Nil is returned for neutral nodes.
+func (t *reputationTracker) load(id discover.NodeID, now mclock.AbsTime) *reputation {
+	if t.store == nil {
+		return nil
+	}
+	score, updated := t.store.Reputation(id)
+	if score == 0 {
+		return nil
+	}
+	elapsed := time.Since(updated)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return &reputation{score: float64(score), updated: now - mclock.AbsTime(elapsed)}
+}
+
+// expire drops the scores which decayed to neutral from the tracker.
+func (t *reputationTracker) expire() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.clock()
+	for id, rep := range t.scores {
+		if math.Round(rep.decayed(now)) == 0 {
+			delete(t.scores, id)
+		}
+	}
+}
+
+// expirer should be started in a go routine, periodically dropping the neutral
+// scores from the tracker until quit is closed.
+func (t *reputationTracker) expirer(quit <-chan struct{}) {
+	tick := time.NewTicker(reputationCleanupCycle)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			t.expire()
+		case <-quit:
+			return
+		}
+	}
+}
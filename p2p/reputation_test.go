@@ -0,0 +1,167 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// Tests that reputation scores are capped to the allowed bounds.
+func TestReputationBounds(t *testing.T) {
+	tracker := newReputationTracker(nil)
+	id := randomID()
+
+	if score := tracker.adjust(id, 2*MaxReputation); score != MaxReputation {
+		t.Errorf("upper bound mismatch: have %d, want %d", score, MaxReputation)
+	}
+	if score := tracker.adjust(id, 4*MinReputation); score != MinReputation {
+		t.Errorf("lower bound mismatch: have %d, want %d", score, MinReputation)
+	}
+	if score := tracker.get(id); score != MinReputation {
+		t.Errorf("stored score mismatch: have %d, want %d", score, MinReputation)
+	}
+	// Ensure a nil tracker considers everyone neutral
+	var nilTracker *reputationTracker
+	if score := nilTracker.adjust(id, -1); score != 0 {
+		t.Errorf("nil tracker score mismatch: have %d, want 0", score)
+	}
+}
+
+// Tests that reputation scores decay towards neutral over time.
+func TestReputationDecay(t *testing.T) {
+	var now mclock.AbsTime
+	tracker := newReputationTracker(nil)
+	tracker.clock = func() mclock.AbsTime { return now }
+
+	good, bad := randomID(), randomID()
+	tracker.adjust(good, 400)
+	tracker.adjust(bad, -400)
+
+	now += mclock.AbsTime(reputationHalfLife)
+	if score := tracker.get(good); score != 200 {
+		t.Errorf("good score mismatch after one half-life: have %d, want %d", score, 200)
+	}
+	if score := tracker.get(bad); score != -200 {
+		t.Errorf("bad score mismatch after one half-life: have %d, want %d", score, -200)
+	}
+	// Adjustments apply on top of the decayed score
+	if score := tracker.adjust(bad, -10); score != -210 {
+		t.Errorf("adjusted score mismatch: have %d, want %d", score, -210)
+	}
+	// Scores eventually become neutral and are forgotten
+	now += mclock.AbsTime(16 * reputationHalfLife)
+	if score := tracker.get(good); score != 0 {
+		t.Errorf("good score not forgotten: have %d", score)
+	}
+	if score := tracker.get(bad); score != 0 {
+		t.Errorf("bad score not forgotten: have %d", score)
+	}
+	if len(tracker.scores) != 0 {
+		t.Errorf("neutral scores still tracked: %d", len(tracker.scores))
+	}
+}
+
+// Tests that neutral scores are dropped by the periodic expiration, without the
+// nodes ever being looked up again.
+func TestReputationExpiry(t *testing.T) {
+	var now mclock.AbsTime
+	tracker := newReputationTracker(nil)
+	tracker.clock = func() mclock.AbsTime { return now }
+
+	tracker.adjust(randomID(), 400)
+	tracker.adjust(randomID(), -400)
+
+	now += mclock.AbsTime(reputationHalfLife)
+	tracker.expire()
+	if len(tracker.scores) != 2 {
+		t.Fatalf("non-neutral scores dropped: %d left", len(tracker.scores))
+	}
+	now += mclock.AbsTime(16 * reputationHalfLife)
+	tracker.expire()
+	if len(tracker.scores) != 0 {
+		t.Errorf("neutral scores not dropped: %d left", len(tracker.scores))
+	}
+}
+
+// testReputationStore is an in-memory reputation store for testing.
+type testReputationStore map[discover.NodeID]testReputation
+
+type testReputation struct {
+	score   int64
+	updated time.Time
+}
+
+func (s testReputationStore) Reputation(id discover.NodeID) (int64, time.Time) {
+	return s[id].score, s[id].updated
+}
+
+func (s testReputationStore) SetReputation(id discover.NodeID, score int64, updated time.Time) error {
+	s[id] = testReputation{score, updated}
+	return nil
+}
+
+// Tests that reputation scores are persisted and loaded back decayed by the time
+// elapsed since their last update.
+func TestReputationPersistence(t *testing.T) {
+	store := make(testReputationStore)
+	id := randomID()
+
+	newReputationTracker(store).adjust(id, -400)
+	if score := newReputationTracker(store).get(id); score != -400 {
+		t.Errorf("loaded score mismatch: have %d, want %d", score, -400)
+	}
+	store[id] = testReputation{-400, time.Now().Add(-reputationHalfLife)}
+	if score := newReputationTracker(store).get(id); score != -200 {
+		t.Errorf("decayed score mismatch: have %d, want %d", score, -200)
+	}
+	// Neutral scores are persisted too, overriding any previous one
+	tracker := newReputationTracker(store)
+	tracker.adjust(id, 200)
+	if score := newReputationTracker(store).get(id); score != 0 {
+		t.Errorf("neutral score mismatch: have %d, want 0", score)
+	}
+}
+
+// Tests that a peer is disconnected when its reputation drops below the threshold.
+func TestPeerReputationDrop(t *testing.T) {
+	closer, _, peer, disc := testPeer(nil)
+	defer closer()
+
+	peer.reputation = newReputationTracker(nil)
+	if score := peer.AdjustReputation(ReputationThreshold); score != ReputationThreshold {
+		t.Fatalf("reputation mismatch: have %d, want %d", score, ReputationThreshold)
+	}
+	select {
+	case reason := <-disc:
+		t.Fatalf("peer dropped at threshold: %v", reason)
+	case <-time.After(100 * time.Millisecond):
+	}
+	peer.AdjustReputation(-1)
+
+	select {
+	case reason := <-disc:
+		if reason != DiscUselessPeer {
+			t.Errorf("disconnect reason mismatch: have %v, want %v", reason, DiscUselessPeer)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("peer with bad reputation not dropped")
+	}
+}
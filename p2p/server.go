@@ -85,6 +85,21 @@ type Config struct {
 	// 만약 2라면 절반이 dialed될것이고, 0으로 설정하면 3이된다
 	DialRatio int `toml:",omitempty"`
 
+	// ReservedPeers is the number of peer slots (out of MaxPeers) reserved for
+	// trusted and static nodes, which dialed and inbound connections to other
+	// nodes can't use up.
+	ReservedPeers int `toml:",omitempty"`
+
+	// MaxInboundPerSubnet limits the number of inbound connections accepted from
+	// the same /24 network, making it costlier to eclipse the node. Connections
+	// from the local network are exempt. Zero means no limit.
+	MaxInboundPerSubnet int `toml:",omitempty"`
+
+	// EvictionInterval enables evicting peers if non-zero: when the inbound slots
+	// are full and a node with better reputation connects, the inbound peer with
+	// the lowest reputation is dropped to make room, at most once per interval.
+	EvictionInterval time.Duration `toml:",omitempty"`
+
 	// NoDiscovery can be used to disable the peer discovery mechanism.
 	// Disabling is useful for protocol debugging (manual topology).
 	// NoDiscovery는 피어 발견 메커니즘을 끄기위해 사용된다
@@ -201,6 +216,7 @@ type Server struct {
 	running bool
 
 	ntab         discoverTable
+	reputation   *reputationTracker
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
@@ -568,8 +584,13 @@ func (srv *Server) Start() (err error) {
 		srv.DiscV5 = ntab
 	}
 
+	// reputation tracking, persisted in the node database if available
+	store, _ := srv.ntab.(reputationStore)
+	srv.reputation = newReputationTracker(store)
+
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.reputation = srv.reputation
 
 	// handshake
 	// 핸드쉐이크
@@ -588,7 +609,11 @@ func (srv *Server) Start() (err error) {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
 
-	srv.loopWG.Add(1)
+	srv.loopWG.Add(2)
+	go func() {
+		srv.reputation.expirer(srv.quit)
+		srv.loopWG.Done()
+	}()
 	go srv.run(dialer)
 	srv.running = true
 	return nil
@@ -629,7 +654,7 @@ func (srv *Server) run(dialstate dialer) {
 	defer srv.loopWG.Done()
 	var (
 		peers        = make(map[discover.NodeID]*Peer)
-		inboundCount = 0
+		slots        = newPeerSlots(srv.MaxInboundPerSubnet)
 		trusted      = make(map[discover.NodeID]bool, len(srv.TrustedNodes))
 		taskdone     = make(chan task, maxActiveDialTasks)
 		runningTasks []task
@@ -734,8 +759,12 @@ running:
 			}
 			// TODO: track in-progress inbound node IDs (pre-Peer) to avoid dialing them.
 			// 처리중인 연결요청에 dial을 피하기 위해 그들의 node ID를 관리해야 한다
+			err := srv.encHandshakeChecks(peers, slots, c)
+			if err == DiscTooManyPeers && srv.evictFor(peers, slots, c) {
+				err = srv.encHandshakeChecks(peers, slots, c)
+			}
 			select {
-			case c.cont <- srv.encHandshakeChecks(peers, inboundCount, c):
+			case c.cont <- err:
 			case <-srv.quit:
 				break running
 			}
@@ -744,11 +773,12 @@ running:
 			// Its capabilities are known and the remote identity is verified.
 			// 이시점에서 연결은 프로토콜 핸드쉐이크 뒤이다
 			// 이미 능력은 알려졌고, 원격의 신원을 검증되었다.
-			err := srv.protoHandshakeChecks(peers, inboundCount, c)
+			err := srv.protoHandshakeChecks(peers, slots, c)
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				// 핸드쉐이크는 끝났고 모든 체크가 패스함
 				p := newPeer(c, srv.Protocols)
+				p.reputation = srv.reputation
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				// 만약 메시지 이벤트가 켜져있다면 피어로가는 peerFeed로 전달
@@ -759,9 +789,7 @@ running:
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				go srv.runPeer(p)
 				peers[c.id] = p
-				if p.Inbound() {
-					inboundCount++
-				}
+				slots.add(p)
 			}
 			// The dialer logic relies on the assumption that
 			// dial tasks complete after the peer has been added or
@@ -779,9 +807,7 @@ running:
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			pd.log.Debug("Removing p2p peer", "duration", d, "peers", len(peers)-1, "req", pd.requested, "err", pd.err)
 			delete(peers, pd.ID())
-			if pd.Inbound() {
-				inboundCount--
-			}
+			slots.remove(pd.Peer)
 		}
 	}
 
@@ -812,7 +838,7 @@ running:
 	}
 }
 
-func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) error {
+func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, slots *peerSlots, c *conn) error {
 	// Drop connections with no matching protocols.
 	// 매칭된 프로토콜이 없을 경우 drop
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
@@ -821,26 +847,42 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, inbound
 	// Repeat the encryption handshake checks because the
 	// peer set might have changed between the handshakes.
 	// 피어셋이 핸드쉐이크중에 변경되었을수 있기 때문에 암호화된 핸드쉐이크를 반복한다
-	return srv.encHandshakeChecks(peers, inboundCount, c)
+	return srv.encHandshakeChecks(peers, slots, c)
 }
 
-func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) error {
+func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, slots *peerSlots, c *conn) error {
+	reserved := c.is(trustedConn | staticDialedConn)
 	switch {
-	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
-		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
-		return DiscTooManyPeers
 	case peers[c.id] != nil:
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !reserved && srv.reputation.get(c.id) < ReputationThreshold:
+		return DiscUselessPeer
+	case !reserved && c.is(inboundConn) && slots.subnetFull(c):
+		return DiscTooManyPeers
+	case !reserved && slots.total >= srv.MaxPeers:
+		return DiscTooManyPeers
+	case !reserved && slots.total-slots.reserved >= srv.maxUnreservedConns():
+		return DiscTooManyPeers
+	case !c.is(trustedConn) && c.is(inboundConn) && slots.inbound >= srv.maxInboundConns():
+		return DiscTooManyPeers
 	default:
 		return nil
 	}
 }
 
+// maxUnreservedConns returns the number of peer slots not reserved for trusted
+// and static nodes.
+func (srv *Server) maxUnreservedConns() int {
+	if srv.ReservedPeers >= srv.MaxPeers {
+		return 0
+	}
+	return srv.MaxPeers - srv.ReservedPeers
+}
+
 func (srv *Server) maxInboundConns() int {
-	return srv.MaxPeers - srv.maxDialedConns()
+	return srv.maxUnreservedConns() - srv.maxDialedConns()
 }
 
 func (srv *Server) maxDialedConns() int {
@@ -851,7 +893,7 @@ func (srv *Server) maxDialedConns() int {
 	if r == 0 {
 		r = defaultDialRatio
 	}
-	return srv.MaxPeers / r
+	return srv.maxUnreservedConns() / r
 }
 
 type tempError interface {
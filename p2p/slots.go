@@ -0,0 +1,160 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// inboundSubnet is the number of prefix bits of the networks whose inbound
+// connections are limited by MaxInboundPerSubnet.
+const inboundSubnet = 24
+
+// peerSlots tracks the connection slots occupied by the peers of a server. It
+// is owned by the server's run loop and must not be accessed concurrently.
+//
+// Peers being evicted don't occupy any slots, even though they stay in the peer
+// set until their connection is torn down.
+type peerSlots struct {
+	total    int // Number of connected peers
+	reserved int // Number of trusted and static peers
+	inbound  int // Number of inbound peers
+
+	nets      netutil.DistinctNetSet   // Networks of the inbound peers
+	evicted   map[discover.NodeID]bool // Peers being dropped to make room for better ones
+	lastEvict time.Time                // Time of the last eviction
+}
+
+// newPeerSlots creates a slot tracker, limiting inbound connections to the
+// given number per subnet (0 = unlimited).
+func newPeerSlots(subnetLimit int) *peerSlots {
+	return &peerSlots{
+		nets:    netutil.DistinctNetSet{Subnet: inboundSubnet, Limit: uint(subnetLimit)},
+		evicted: make(map[discover.NodeID]bool),
+	}
+}
+
+// add allocates the slots used by a newly connected peer.
+func (s *peerSlots) add(p *Peer) {
+	s.total++
+	if p.rw.is(trustedConn | staticDialedConn) {
+		s.reserved++
+	}
+	if p.rw.is(inboundConn) {
+		s.inbound++
+		if ip := remoteIP(p.rw); ip != nil {
+			s.nets.Add(ip)
+		}
+	}
+}
+
+// remove releases the slots of a disconnected peer, unless already released
+// by evicting it.
+func (s *peerSlots) remove(p *Peer) {
+	if s.evicted[p.ID()] {
+		delete(s.evicted, p.ID())
+		return
+	}
+	s.release(p)
+}
+
+// evict marks a peer as being dropped and releases its slots right away.
+func (s *peerSlots) evict(p *Peer, now time.Time) {
+	s.evicted[p.ID()] = true
+	s.lastEvict = now
+	s.release(p)
+}
+
+// release frees the slots occupied by a peer.
+func (s *peerSlots) release(p *Peer) {
+	s.total--
+	if p.rw.is(trustedConn | staticDialedConn) {
+		s.reserved--
+	}
+	if p.rw.is(inboundConn) {
+		s.inbound--
+		if ip := remoteIP(p.rw); ip != nil {
+			s.nets.Remove(ip)
+		}
+	}
+}
+
+// subnetFull reports whether accepting an inbound connection from the given
+// address would exceed the per-subnet limit.
+func (s *peerSlots) subnetFull(c *conn) bool {
+	if s.nets.Limit == 0 {
+		return false
+	}
+	ip := remoteIP(c)
+	if ip == nil {
+		return false
+	}
+	if !s.nets.Add(ip) {
+		return true
+	}
+	s.nets.Remove(ip)
+	return false
+}
+
+// remoteIP returns the remote address of a connection if it's subject to the
+// per-subnet limits, i.e. it's a TCP connection from outside the local network.
+func remoteIP(c *conn) net.IP {
+	addr, ok := c.fd.RemoteAddr().(*net.TCPAddr)
+	if !ok || netutil.IsLAN(addr.IP) {
+		return nil
+	}
+	return addr.IP
+}
+
+// evictFor tries to make room for a connection rejected because the inbound
+// slots are full, by dropping the connected inbound peer with the lowest
+// reputation if the connecting node has a better one. Trusted and static peers
+// are never evicted, and evictions happen at most once per EvictionInterval.
+func (srv *Server) evictFor(peers map[discover.NodeID]*Peer, slots *peerSlots, c *conn) bool {
+	if srv.EvictionInterval == 0 || !c.is(inboundConn) || c.is(trustedConn) || slots.subnetFull(c) {
+		return false
+	}
+	now := time.Now()
+	if now.Sub(slots.lastEvict) < srv.EvictionInterval {
+		return false
+	}
+	var (
+		victim *Peer
+		score  int64
+	)
+	for _, p := range peers {
+		if !p.rw.is(inboundConn) || p.rw.is(trustedConn|staticDialedConn) || slots.evicted[p.ID()] {
+			continue
+		}
+		// Prefer dropping the worst peer, and the youngest one among equals
+		rep := p.Reputation()
+		if victim == nil || rep < score || (rep == score && p.created > victim.created) {
+			victim, score = p, rep
+		}
+	}
+	if victim == nil || score >= srv.reputation.get(c.id) {
+		return false
+	}
+	victim.log.Debug("Evicting peer for better candidate", "reputation", score, "candidate", c.id)
+	slots.evict(victim, now)
+	victim.Disconnect(DiscTooManyPeers)
+	return true
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// remoteAddrConn is an in-memory connection pretending to come from a TCP endpoint.
+type remoteAddrConn struct {
+	net.Conn
+	remote *net.TCPAddr
+}
+
+func (p remoteAddrConn) RemoteAddr() net.Addr { return p.remote }
+
+// startSlotTestServer starts a server not dialing any peers, returning it along
+// with a constructor for inbound connections from the given IP.
+func startSlotTestServer(t *testing.T, config Config) (*Server, func(discover.NodeID, string) *conn) {
+	config.PrivateKey = newkey()
+	config.NoDial = true
+
+	srv := &Server{Config: config}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	newconn := func(id discover.NodeID, ip string) *conn {
+		fd, _ := net.Pipe()
+		if ip != "" {
+			fd = remoteAddrConn{Conn: fd, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 30303}}
+		}
+		tx := newTestTransport(id, fd)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, id: id, cont: make(chan error)}
+	}
+	return srv, newconn
+}
+
+// Tests that slots reserved for trusted nodes can't be used by other peers.
+func TestServerReservedSlots(t *testing.T) {
+	trustedID := randomID()
+	srv, newconn := startSlotTestServer(t, Config{
+		MaxPeers:      10,
+		ReservedPeers: 2,
+		TrustedNodes:  []*discover.Node{{ID: trustedID}},
+	})
+	defer srv.Stop()
+
+	for i := 0; i < 8; i++ {
+		if err := srv.checkpoint(newconn(randomID(), ""), srv.addpeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	if err := srv.checkpoint(newconn(randomID(), ""), srv.posthandshake); err != DiscTooManyPeers {
+		t.Errorf("unreserved conn error mismatch: have %v, want %v", err, DiscTooManyPeers)
+	}
+	if err := srv.checkpoint(newconn(trustedID, ""), srv.posthandshake); err != nil {
+		t.Errorf("trusted conn rejected: %v", err)
+	}
+}
+
+// Tests that inbound connections are limited per subnet.
+func TestServerInboundSubnetLimit(t *testing.T) {
+	srv, newconn := startSlotTestServer(t, Config{
+		MaxPeers:            10,
+		MaxInboundPerSubnet: 2,
+	})
+	defer srv.Stop()
+
+	for i, ip := range []string{"1.2.3.4", "1.2.3.5"} {
+		if err := srv.checkpoint(newconn(randomID(), ip), srv.addpeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	if err := srv.checkpoint(newconn(randomID(), "1.2.3.6"), srv.posthandshake); err != DiscTooManyPeers {
+		t.Errorf("same subnet conn error mismatch: have %v, want %v", err, DiscTooManyPeers)
+	}
+	if err := srv.checkpoint(newconn(randomID(), "1.2.4.1"), srv.posthandshake); err != nil {
+		t.Errorf("other subnet conn rejected: %v", err)
+	}
+	if err := srv.checkpoint(newconn(randomID(), "192.168.0.1"), srv.posthandshake); err != nil {
+		t.Errorf("local network conn rejected: %v", err)
+	}
+}
+
+// Tests that the worst inbound peer is evicted if a better node connects, and
+// that evictions are rate limited.
+func TestServerEviction(t *testing.T) {
+	srv, newconn := startSlotTestServer(t, Config{
+		MaxPeers:         4,
+		EvictionInterval: time.Hour,
+	})
+	defer srv.Stop()
+
+	ids := make([]discover.NodeID, 4)
+	for i := range ids {
+		ids[i] = randomID()
+		if err := srv.checkpoint(newconn(ids[i], ""), srv.addpeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	srv.reputation.adjust(ids[1], -5)
+	srv.reputation.adjust(ids[2], -10)
+
+	// A node no better than the worst peer is rejected
+	worse := randomID()
+	srv.reputation.adjust(worse, -10)
+	if err := srv.checkpoint(newconn(worse, ""), srv.posthandshake); err != DiscTooManyPeers {
+		t.Fatalf("worse conn error mismatch: have %v, want %v", err, DiscTooManyPeers)
+	}
+	// A better node evicts the worst peer
+	better := newconn(randomID(), "")
+	if err := srv.checkpoint(better, srv.posthandshake); err != nil {
+		t.Fatalf("better conn rejected: %v", err)
+	}
+	if err := srv.checkpoint(better, srv.addpeer); err != nil {
+		t.Fatalf("better conn not added: %v", err)
+	}
+	for i := 0; ; i++ {
+		evicted := true
+		for _, p := range srv.Peers() {
+			if p.ID() == ids[2] {
+				evicted = false
+			}
+		}
+		if evicted {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("worst peer not evicted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Another eviction is not allowed until the interval passes
+	if err := srv.checkpoint(newconn(randomID(), ""), srv.posthandshake); err != DiscTooManyPeers {
+		t.Errorf("rate limited conn error mismatch: have %v, want %v", err, DiscTooManyPeers)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// MainnetTrustedCheckpoint contains the light client trusted checkpoint for the main network.
+	MainnetTrustedCheckpoint = &TrustedCheckpoint{
+		Name:         "mainnet",
+		SectionIndex: 170,
+		SectionHead:  common.HexToHash("0x3bb2c28bcce463d57968f14f56cdb3fbf35349ab7a701f44c1afb57349c9a356"),
+		CHTRoot:      common.HexToHash("0xd92b6d0853455f8439086292338e87f69781921680dd7aa072fb71547b87415e"),
+		BloomRoot:    common.HexToHash("0xe4e8250a2fefddead7ae42daecd848cbf9b66d748a8270f8bbd4370b764bb9e9"),
+	}
+
+	// TestnetTrustedCheckpoint contains the light client trusted checkpoint for the Ropsten test network.
+	TestnetTrustedCheckpoint = &TrustedCheckpoint{
+		Name:         "ropsten",
+		SectionIndex: 97,
+		SectionHead:  common.HexToHash("0x719448c67c01eb5b9f27833a36a4e34612f66801316d7ff37daf9e77fb4cd095"),
+		CHTRoot:      common.HexToHash("0xa7857afc15930ca6e583b6c3d563a025144011655843d52d28e2fdaadd417bea"),
+		BloomRoot:    common.HexToHash("0x9c71d4b50cbec86dfeaa8e08992de8a4667b81d13c54d6522b17ce2fc5d36416"),
+	}
+)
+
+// TrustedCheckpoints associates each known checkpoint with the genesis hash of
+// the chain it belongs to.
+var TrustedCheckpoints = map[common.Hash]*TrustedCheckpoint{
+	MainnetGenesisHash: MainnetTrustedCheckpoint,
+	TestnetGenesisHash: TestnetTrustedCheckpoint,
+}
+
+// TrustedCheckpoint represents a set of post-processed trie roots (CHT and
+// BloomTrie) associated with the appropriate section index and head hash. It is
+// used to start syncing from this checkpoint and avoid downloading and verifying
+// the entire header chain while still being able to securely access old headers
+// and logs.
+type TrustedCheckpoint struct {
+	Name         string      `json:"-"`
+	SectionIndex uint64      `json:"sectionIndex"`
+	SectionHead  common.Hash `json:"sectionHead"`
+	CHTRoot      common.Hash `json:"chtRoot"`
+	BloomRoot    common.Hash `json:"bloomRoot"`
+}
+
+// HeadNumber returns the number of the last block covered by the checkpoint.
+func (c *TrustedCheckpoint) HeadNumber() uint64 {
+	return (c.SectionIndex+1)*CHTFrequencyClient - 1
+}
+
+// Hash returns the hash of the checkpoint's fields, which is the value signed
+// by the checkpoint oracle signers.
+func (c *TrustedCheckpoint) Hash() common.Hash {
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], c.SectionIndex)
+	return crypto.Keccak256Hash(index[:], c.SectionHead[:], c.CHTRoot[:], c.BloomRoot[:])
+}
+
+// Empty returns an indicator whether the checkpoint is regarded as empty.
+func (c *TrustedCheckpoint) Empty() bool {
+	return c.SectionHead == (common.Hash{}) || c.CHTRoot == (common.Hash{}) || c.BloomRoot == (common.Hash{})
+}
+
+// String implements fmt.Stringer, returning the checkpoint in the format that is
+// accepted by ParseTrustedCheckpoint.
+func (c *TrustedCheckpoint) String() string {
+	return fmt.Sprintf("%d:%x:%x:%x", c.SectionIndex, c.SectionHead, c.CHTRoot, c.BloomRoot)
+}
+
+// ParseTrustedCheckpoint parses a checkpoint in the format of
+// <section index>:<section head>:<cht root>:<bloom trie root>.
+func ParseTrustedCheckpoint(s string) (*TrustedCheckpoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 4 {
+		return nil, errors.New("checkpoint must be <index>:<section head>:<cht root>:<bloom root>")
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid section index: %v", err)
+	}
+	var hashes [3]common.Hash
+	for i, part := range parts[1:] {
+		blob := common.FromHex(part)
+		if len(blob) != common.HashLength {
+			return nil, fmt.Errorf("invalid checkpoint hash %q", part)
+		}
+		hashes[i] = common.BytesToHash(blob)
+	}
+	return &TrustedCheckpoint{
+		SectionIndex: index,
+		SectionHead:  hashes[0],
+		CHTRoot:      hashes[1],
+		BloomRoot:    hashes[2],
+	}, nil
+}
+
+// CheckpointOracleConfig represents a set of checkpoint contract (which acts as
+// an oracle) config which used for light client checkpoint syncing.
+type CheckpointOracleConfig struct {
+	Address   common.Address   `json:"address"`
+	Signers   []common.Address `json:"signers"`
+	Threshold uint64           `json:"threshold"`
+}
@@ -23,4 +23,8 @@ const (
 	// BloomBitsBlocks is the number of blocks a single bloom bit section vector
 	// contains.
 	BloomBitsBlocks uint64 = 4096
+
+	// CHTFrequencyClient is the block frequency for creating CHTs on the client
+	// side, which is also the section size of trusted checkpoints.
+	CHTFrequencyClient = 32768
 )
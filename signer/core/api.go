@@ -46,6 +46,10 @@ type ExternalAPI interface {
 	SignTransaction(ctx context.Context, args SendTxArgs, methodSelector *string) (*ethapi.SignTransactionResult, error)
 	// Sign - request to sign the given data (plus prefix)
 	Sign(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error)
+	// SignData - request to sign the given data of the given content type
+	SignData(ctx context.Context, contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error)
+	// SignTypedData - request to sign the given EIP-712 typed structured data
+	SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data ethapi.TypedData) (hexutil.Bytes, error)
 	// EcRecover - request to perform ecrecover
 	EcRecover(ctx context.Context, data, sig hexutil.Bytes) (common.Address, error)
 	// Export - request to export an account
@@ -155,11 +159,14 @@ type (
 		NewPassword string `json:"new_password"`
 	}
 	SignDataRequest struct {
-		Address common.MixedcaseAddress `json:"address"`
-		Rawdata hexutil.Bytes           `json:"raw_data"`
-		Message string                  `json:"message"`
-		Hash    hexutil.Bytes           `json:"hash"`
-		Meta    Metadata                `json:"meta"`
+		ContentType string                  `json:"content_type,omitempty"` // Content type of the data (empty for typed data)
+		Address     common.MixedcaseAddress `json:"address"`
+		Rawdata     hexutil.Bytes           `json:"raw_data"`
+		Message     string                  `json:"message"`
+		Messages    []*ethapi.NameValueType `json:"messages,omitempty"`   // Structured view of typed or decoded data
+		TypedData   *ethapi.TypedData       `json:"typed_data,omitempty"` // Typed data being signed, if any
+		Hash        hexutil.Bytes           `json:"hash"`
+		Meta        Metadata                `json:"meta"`
 	}
 	SignDataResponse struct {
 		Approved bool `json:"approved"`
@@ -388,7 +395,44 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 	sighash, msg := SignHash(data)
 	// We make the request prior to looking up if we actually have the account, to prevent
 	// account-enumeration via the API
-	req := &SignDataRequest{Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
+	req := &SignDataRequest{ContentType: accounts.MimetypeTextPlain, Address: addr, Rawdata: data, Message: msg, Hash: sighash, Meta: MetadataFromContext(ctx)}
+	return api.sign(addr, req, true)
+}
+
+// SignTypedData calculates an Ethereum ECDSA signature for EIP-712 typed structured data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+//
+// The typed data is validated and shown to the UI field by field, and must be bound
+// to the chain of the signer if its domain contains a chain id.
+//
+// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-712.md
+func (api *SignerAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data ethapi.TypedData) (hexutil.Bytes, error) {
+	sighash, rawdata, err := data.SigningHash()
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := data.ChainId()
+	if err != nil {
+		return nil, err
+	}
+	if chainID != nil && chainID.Cmp(api.chainID) != 0 {
+		return nil, fmt.Errorf("typed data bound to chain %v, signer on chain %v", chainID, api.chainID)
+	}
+	req := &SignDataRequest{
+		Address:   addr,
+		Rawdata:   rawdata,
+		Messages:  data.Format(),
+		TypedData: &data,
+		Hash:      sighash,
+		Meta:      MetadataFromContext(ctx),
+	}
+	return api.sign(addr, req, true)
+}
+
+// sign asks the UI to approve a data signing request and, if approved, signs its
+// hash with the requested account. If legacyV is set, the V value of the produced
+// signature is transformed to 27 or 28.
+func (api *SignerAPI) sign(addr common.MixedcaseAddress, req *SignDataRequest, legacyV bool) (hexutil.Bytes, error) {
 	res, err := api.UI.ApproveSignData(req)
 
 	if err != nil {
@@ -404,12 +448,14 @@ func (api *SignerAPI) Sign(ctx context.Context, addr common.MixedcaseAddress, da
 		return nil, err
 	}
 	// Assemble sign the data with the wallet
-	signature, err := wallet.SignHashWithPassphrase(account, res.Password, sighash)
+	signature, err := wallet.SignHashWithPassphrase(account, res.Password, req.Hash)
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, err
 	}
-	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	if legacyV {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
 	return signature, nil
 }
 
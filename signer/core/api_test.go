@@ -19,6 +19,7 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -27,11 +28,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -254,6 +258,126 @@ func TestSignData(t *testing.T) {
 		t.Errorf("Expected 65 byte signature (got %d bytes)", len(h))
 	}
 }
+
+func TestSignDataContentTypes(t *testing.T) {
+
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0].Address)
+
+	header := &types.Header{
+		Difficulty: big.NewInt(2),
+		Number:     big.NewInt(1),
+		GasLimit:   4700000,
+		Time:       big.NewInt(1500000000),
+		Extra:      make([]byte, 32+65),
+	}
+	validator := common.HexToAddress("0x1337")
+	tests := []struct {
+		contentType string
+		data        []byte
+		hash        []byte
+		legacyV     bool
+	}{
+		{accounts.MimetypeTextPlain, []byte("EHLO world"), crypto.Keccak256([]byte("\x19Ethereum Signed Message:\n10EHLO world")), true},
+		{accounts.MimetypeValidator, append(validator.Bytes(), 0xca, 0xfe), crypto.Keccak256([]byte{0x19, 0x00}, validator.Bytes(), []byte{0xca, 0xfe}), true},
+		{accounts.MimetypeClique, clique.CliqueRLP(header), crypto.Keccak256(clique.CliqueRLP(header)), false},
+	}
+	for i, tt := range tests {
+		control <- "Y"
+		control <- "apassword"
+		sig, err := api.SignData(context.Background(), tt.contentType, a, tt.data)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign: %v", i, err)
+		}
+		if tt.legacyV {
+			if sig[64] != 27 && sig[64] != 28 {
+				t.Errorf("test %d: V value not 27 or 28: %d", i, sig[64])
+			}
+			sig[64] -= 27
+		}
+		pubkey, err := crypto.SigToPub(tt.hash, sig)
+		if err != nil {
+			t.Fatalf("test %d: failed to recover signer: %v", i, err)
+		}
+		if addr := crypto.PubkeyToAddress(*pubkey); addr != a.Address() {
+			t.Errorf("test %d: signer mismatch: have %x, want %x", i, addr, a.Address())
+		}
+	}
+	// Malformed data is rejected without bothering the UI
+	if _, err := api.SignData(context.Background(), accounts.MimetypeClique, a, []byte{0x01}); err == nil {
+		t.Errorf("Expected error for invalid clique header")
+	}
+	if _, err := api.SignData(context.Background(), accounts.MimetypeValidator, a, []byte{0x01}); err == nil {
+		t.Errorf("Expected error for short validator data")
+	}
+	if _, err := api.SignData(context.Background(), "image/png", a, []byte{0x01}); err == nil {
+		t.Errorf("Expected error for unsupported content type")
+	}
+}
+
+func mkTestTypedData(chainId string) ethapi.TypedData {
+	return ethapi.TypedData{
+		Types: ethapi.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+			"Greeting":     {{Name: "to", Type: "address"}, {Name: "contents", Type: "string"}},
+		},
+		PrimaryType: "Greeting",
+		Domain:      map[string]interface{}{"name": "Test", "chainId": json.Number(chainId)},
+		Message:     map[string]interface{}{"to": "0x0000000000000000000000000000000000001337", "contents": "EHLO world"},
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0].Address)
+
+	// Typed data for another chain is rejected without bothering the UI
+	if _, err := api.SignTypedData(context.Background(), a, mkTestTypedData("2")); err == nil {
+		t.Errorf("Expected error for typed data of another chain")
+	}
+	control <- "No way"
+	h, err := api.SignTypedData(context.Background(), a, mkTestTypedData("1"))
+	if h != nil {
+		t.Errorf("Expected nil-data, got %x", h)
+	}
+	if err != ErrRequestDenied {
+		t.Errorf("Expected ErrRequestDenied! %v", err)
+	}
+
+	control <- "Y"
+	control <- "apassword"
+	typedData := mkTestTypedData("1")
+	h, err = api.SignTypedData(context.Background(), a, typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == nil || len(h) != 65 {
+		t.Fatalf("Expected 65 byte signature (got %d bytes)", len(h))
+	}
+	sighash, _, _ := typedData.SigningHash()
+	h[64] -= 27
+	pubkey, err := crypto.SigToPub(sighash, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := crypto.PubkeyToAddress(*pubkey); addr != a.Address() {
+		t.Errorf("Signer mismatch: have %x, want %x", addr, a.Address())
+	}
+}
+
 func mkTestTx(from common.MixedcaseAddress) SendTxArgs {
 	to := common.NewMixedcaseAddress(common.HexToAddress("0x1337"))
 	gas := hexutil.Uint64(21000)
@@ -0,0 +1,111 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// SignData calculates an Ethereum ECDSA signature of data, hashed the way its
+// content type requires:
+//   - text/plain: personal message, as signed by Sign
+//   - data/validator: EIP-191 version 0x00 data, consisting of the 20 byte address
+//     of the intended validator followed by the message
+//   - application/x-clique-header: RLP encoded clique header without its seal
+//
+// The decoded data is shown to the UI. The V value of the produced signature is 0
+// or 1 for clique headers, as stored in the header, and 27 or 28 otherwise.
+func (api *SignerAPI) SignData(ctx context.Context, contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	req, legacyV, err := newSignDataRequest(contentType, addr, data)
+	if err != nil {
+		return nil, err
+	}
+	req.Meta = MetadataFromContext(ctx)
+	return api.sign(addr, req, legacyV)
+}
+
+// newSignDataRequest decodes data of the given content type into a request to
+// sign it, also returning whether the signature's V value should be 27 or 28.
+func newSignDataRequest(contentType string, addr common.MixedcaseAddress, data []byte) (*SignDataRequest, bool, error) {
+	req := &SignDataRequest{ContentType: contentType, Address: addr, Rawdata: data}
+
+	switch contentType {
+	case accounts.MimetypeTextPlain:
+		req.Hash, req.Message = SignHash(data)
+		return req, true, nil
+
+	case accounts.MimetypeValidator:
+		if len(data) < common.AddressLength {
+			return nil, false, fmt.Errorf("validator data too short: %d bytes", len(data))
+		}
+		validator, message := common.BytesToAddress(data[:common.AddressLength]), data[common.AddressLength:]
+
+		req.Rawdata = append([]byte{0x19, 0x00}, data...)
+		req.Hash = crypto.Keccak256(req.Rawdata)
+		req.Messages = []*ethapi.NameValueType{
+			{Name: "validator", Typ: "address", Value: validator.Hex()},
+			{Name: "message", Typ: "bytes", Value: hexutil.Encode(message)},
+		}
+		return req, true, nil
+
+	case accounts.MimetypeClique:
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(data, header); err != nil {
+			return nil, false, fmt.Errorf("invalid clique header: %v", err)
+		}
+		// Only sign canonical encodings, so the data signed is the header shown
+		if enc, _ := rlp.EncodeToBytes(header); !bytes.Equal(enc, data) {
+			return nil, false, fmt.Errorf("invalid clique header: non-canonical encoding")
+		}
+		req.Hash = crypto.Keccak256(data)
+		req.Messages = cliqueHeaderFields(header)
+		return req, false, nil
+	}
+	return nil, false, fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// cliqueHeaderFields returns the fields of a clique header in a human readable
+// form.
+func cliqueHeaderFields(header *types.Header) []*ethapi.NameValueType {
+	return []*ethapi.NameValueType{
+		{Name: "number", Typ: "uint256", Value: header.Number.String()},
+		{Name: "parentHash", Typ: "bytes32", Value: header.ParentHash.Hex()},
+		{Name: "timestamp", Typ: "uint256", Value: fmt.Sprintf("%v (%v)", header.Time, time.Unix(header.Time.Int64(), 0).UTC())},
+		{Name: "difficulty", Typ: "uint256", Value: header.Difficulty.String()},
+		{Name: "coinbase", Typ: "address", Value: header.Coinbase.Hex()},
+		{Name: "nonce", Typ: "bytes8", Value: hexutil.Encode(header.Nonce[:])},
+		{Name: "extraData", Typ: "bytes", Value: hexutil.Encode(header.Extra)},
+		{Name: "gasLimit", Typ: "uint64", Value: header.GasLimit},
+		{Name: "gasUsed", Typ: "uint64", Value: header.GasUsed},
+		{Name: "stateRoot", Typ: "bytes32", Value: header.Root.Hex()},
+		{Name: "transactionsRoot", Typ: "bytes32", Value: header.TxHash.Hex()},
+		{Name: "receiptsRoot", Typ: "bytes32", Value: header.ReceiptHash.Hex()},
+		{Name: "sha3Uncles", Typ: "bytes32", Value: header.UncleHash.Hex()},
+		{Name: "mixHash", Typ: "bytes32", Value: header.MixDigest.Hex()},
+	}
+}
@@ -17,6 +17,7 @@
 package rules
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"strings"
@@ -629,3 +630,54 @@ function ApproveSignData(r){
 		t.Fatalf("Expected approved")
 	}
 }
+
+func TestSignTypedData(t *testing.T) {
+
+	js := `function ApproveSignData(r){
+    if(r.typed_data && r.typed_data.primaryType == "Order" && r.typed_data.message.amount <= 100){
+        return "Approve"
+    }
+    return "Reject"
+}`
+	r, err := initRuleEngine(js)
+	if err != nil {
+		t.Errorf("Couldn't create evaluator %v", err)
+		return
+	}
+	addr, _ := mixAddr("0x694267f14675d7e1b9494fd8d72fefe1755710fa")
+	for _, tt := range []struct {
+		amount   string
+		approved bool
+	}{
+		{"50", true},
+		{"500", false},
+	} {
+		typedData := ethapi.TypedData{
+			Types: ethapi.Types{
+				"EIP712Domain": {{Name: "name", Type: "string"}},
+				"Order":        {{Name: "amount", Type: "uint256"}},
+			},
+			PrimaryType: "Order",
+			Domain:      map[string]interface{}{"name": "Exchange"},
+			Message:     map[string]interface{}{"amount": json.Number(tt.amount)},
+		}
+		hash, raw, err := typedData.SigningHash()
+		if err != nil {
+			t.Fatalf("Failed to hash typed data: %v", err)
+		}
+		resp, err := r.ApproveSignData(&core.SignDataRequest{
+			Address:   *addr,
+			Rawdata:   raw,
+			Messages:  typedData.Format(),
+			TypedData: &typedData,
+			Hash:      hash,
+			Meta:      core.Metadata{Remote: "remoteip", Local: "localip", Scheme: "inproc"},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if resp.Approved != tt.approved {
+			t.Errorf("Amount %s: approval mismatch: have %v, want %v", tt.amount, resp.Approved, tt.approved)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package trie
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -129,7 +130,7 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 		if err != nil {
 			return nil, fmt.Errorf("bad proof node %d: %v", i, err), i
 		}
-		keyrest, cld := get(n, key)
+		keyrest, cld := get(n, key, true)
 		switch cld := cld.(type) {
 		case nil:
 			// The trie doesn't contain the key.
@@ -143,7 +144,401 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 	}
 }
 
-func get(tn node, key []byte) ([]byte, node) {
+// proofToPath converts a merkle proof to trie node path. The main purpose of
+// this function is recovering a node path from the merkle proof stream. All
+// necessary nodes will be resolved and leave the remaining as hashnode.
+//
+// The given edge proof is allowed to be an existent or non-existent proof.
+// Every resolved node is also written into the given nodes database, since
+// its hash has been verified against the root.
+func proofToPath(rootHash common.Hash, root node, key []byte, proofDb DatabaseReader, nodes ethdb.Putter) (node, error) {
+	// resolveNode retrieves and resolves trie node from merkle proof stream
+	resolveNode := func(hash common.Hash) (node, error) {
+		buf, _ := proofDb.Get(hash[:])
+		if buf == nil {
+			return nil, fmt.Errorf("proof node (hash %064x) missing", hash)
+		}
+		n, err := decodeNode(hash[:], buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %v", err)
+		}
+		nodes.Put(hash[:], buf)
+		return n, nil
+	}
+	// If the root node is empty, resolve it first
+	if root == nil {
+		n, err := resolveNode(rootHash)
+		if err != nil {
+			return nil, err
+		}
+		root = n
+	}
+	var (
+		err           error
+		child, parent node
+		keyrest       []byte
+	)
+	key, parent = keybytesToHex(key), root
+	for {
+		keyrest, child = get(parent, key, false)
+		switch cld := child.(type) {
+		case nil:
+			// The trie doesn't contain the key. It's a non-existent proof, but
+			// all the resolved nodes are correct, which is enough to prove the
+			// edge of the range.
+			return root, nil
+		case *shortNode, *fullNode:
+			key, parent = keyrest, child // Already resolved
+			continue
+		case hashNode:
+			child, err = resolveNode(common.BytesToHash(cld))
+			if err != nil {
+				return nil, err
+			}
+		case valueNode:
+			return root, nil // The whole path is resolved
+		}
+		// Link the parent and child
+		switch pnode := parent.(type) {
+		case *shortNode:
+			pnode.Val = child
+		case *fullNode:
+			pnode.Children[key[0]] = child
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", pnode, pnode))
+		}
+		key, parent = keyrest, child
+	}
+}
+
+// unsetInternal removes all internal node references (hashnode, embedded node)
+// between the left and right edge paths. It returns whether the entire trie
+// was removed, in which case the range rebuild must start from an empty trie.
+//
+// Note we have the assumption here that the two edge proofs are the same length
+// and the left key is strictly smaller than the right one.
+func unsetInternal(n node, left []byte, right []byte) (bool, error) {
+	left, right = keybytesToHex(left), keybytesToHex(right)
+
+	// Step down to the fork point. The fork point is either a short node whose
+	// key diverges from one of the edge paths, or a full node where the two edge
+	// paths take different branches.
+	var (
+		pos    = 0
+		parent node
+
+		// fork indicators, 0 means no fork, -1 means the path is smaller than
+		// the short node's key, 1 means the path is larger
+		shortForkLeft, shortForkRight int
+	)
+findFork:
+	for {
+		switch rn := (n).(type) {
+		case *shortNode:
+			rn.flags = nodeFlag{dirty: true}
+
+			if len(left)-pos < len(rn.Key) {
+				shortForkLeft = bytes.Compare(left[pos:], rn.Key)
+			} else {
+				shortForkLeft = bytes.Compare(left[pos:pos+len(rn.Key)], rn.Key)
+			}
+			if len(right)-pos < len(rn.Key) {
+				shortForkRight = bytes.Compare(right[pos:], rn.Key)
+			} else {
+				shortForkRight = bytes.Compare(right[pos:pos+len(rn.Key)], rn.Key)
+			}
+			if shortForkLeft != 0 || shortForkRight != 0 {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Val, pos+len(rn.Key)
+
+		case *fullNode:
+			rn.flags = nodeFlag{dirty: true}
+
+			leftnode, rightnode := rn.Children[left[pos]], rn.Children[right[pos]]
+			if leftnode == nil || rightnode == nil || left[pos] != right[pos] {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Children[left[pos]], pos+1
+
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+		}
+	}
+	switch rn := n.(type) {
+	case *shortNode:
+		// Both edge paths on the same side of the short node leave no valid range
+		if shortForkLeft == -1 && shortForkRight == -1 {
+			return false, errors.New("empty range")
+		}
+		if shortForkLeft == 1 && shortForkRight == 1 {
+			return false, errors.New("empty range")
+		}
+		// The short node is entirely within the range, unset it
+		if shortForkLeft != 0 && shortForkRight != 0 {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[left[pos-1]] = nil
+			return false, nil
+		}
+		// Only one edge path diverges from the short node
+		if shortForkRight != 0 {
+			if _, ok := rn.Val.(valueNode); ok {
+				if parent == nil {
+					return true, nil
+				}
+				parent.(*fullNode).Children[left[pos-1]] = nil
+				return false, nil
+			}
+			return false, unset(rn, rn.Val, left[pos:], len(rn.Key), false)
+		}
+		if shortForkLeft != 0 {
+			if _, ok := rn.Val.(valueNode); ok {
+				if parent == nil {
+					return true, nil
+				}
+				parent.(*fullNode).Children[right[pos-1]] = nil
+				return false, nil
+			}
+			return false, unset(rn, rn.Val, right[pos:], len(rn.Key), true)
+		}
+		return false, nil
+
+	case *fullNode:
+		// Unset all children between the two edge paths, then trim the edges
+		for i := left[pos] + 1; i < right[pos]; i++ {
+			rn.Children[i] = nil
+		}
+		if err := unset(rn, rn.Children[left[pos]], left[pos:], 1, false); err != nil {
+			return false, err
+		}
+		if err := unset(rn, rn.Children[right[pos]], right[pos:], 1, true); err != nil {
+			return false, err
+		}
+		return false, nil
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
+
+// unset removes all internal node references either the left most or right most.
+// It can meet these scenarios:
+//
+// - The given path is existent in the trie, unset the associated nodes with the
+//   specific direction
+// - The given path is non-existent in the trie
+//   - the fork point is a fullnode, the corresponding child pointed by path
+//     is nil, return
+//   - the fork point is a shortnode, the shortnode is included in the range,
+//     keep the entire branch and return.
+//   - the fork point is a shortnode, the shortnode is excluded in the range,
+//     unset the entire branch.
+func unset(parent node, child node, key []byte, pos int, removeLeft bool) error {
+	switch cld := child.(type) {
+	case *fullNode:
+		if removeLeft {
+			for i := 0; i < int(key[pos]); i++ {
+				cld.Children[i] = nil
+			}
+		} else {
+			for i := key[pos] + 1; i < 16; i++ {
+				cld.Children[i] = nil
+			}
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Children[key[pos]], key, pos+1, removeLeft)
+
+	case *shortNode:
+		if len(key[pos:]) < len(cld.Key) || !bytes.Equal(cld.Key, key[pos:pos+len(cld.Key)]) {
+			// Found the fork point, it's a non-existent branch. If the short
+			// node's key is within the range, unset the entire branch (the
+			// parent must be a full node), otherwise keep it as is.
+			if removeLeft {
+				if bytes.Compare(cld.Key, key[pos:]) < 0 {
+					parent.(*fullNode).Children[key[pos-1]] = nil
+				}
+			} else {
+				if bytes.Compare(cld.Key, key[pos:]) > 0 {
+					parent.(*fullNode).Children[key[pos-1]] = nil
+				}
+			}
+			return nil
+		}
+		if _, ok := cld.Val.(valueNode); ok {
+			parent.(*fullNode).Children[key[pos-1]] = nil
+			return nil
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Val, key, pos+len(cld.Key), removeLeft)
+
+	case nil:
+		// A non-existent branch of the fork point full node
+		return nil
+
+	default:
+		panic("it shouldn't happen") // hashNode, valueNode
+	}
+}
+
+// hasRightElement returns the indicator whether there exists more elements
+// on the right side of the given path. The given path can point to an existent
+// key or a non-existent one. This function has the assumption that the whole
+// path should already be resolved.
+func hasRightElement(node node, key []byte) bool {
+	pos, key := 0, keybytesToHex(key)
+	for node != nil {
+		switch rn := node.(type) {
+		case *fullNode:
+			for i := key[pos] + 1; i < 16; i++ {
+				if rn.Children[i] != nil {
+					return true
+				}
+			}
+			node, pos = rn.Children[key[pos]], pos+1
+		case *shortNode:
+			if len(key)-pos < len(rn.Key) || !bytes.Equal(rn.Key, key[pos:pos+len(rn.Key)]) {
+				return bytes.Compare(rn.Key, key[pos:]) > 0
+			}
+			node, pos = rn.Val, pos+len(rn.Key)
+		case valueNode:
+			return false // We have resolved the whole path
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", node, node)) // hashnode
+		}
+	}
+	return false
+}
+
+// VerifyRangeProof checks whether the given leaves and edge proof can prove
+// that the leaves are exactly the contiguous range of the trie with the given
+// root hash starting at firstKey (inclusive) and ending at the last given key.
+//
+// The proof must contain the merkle proofs of both firstKey and the last key
+// in keys. As a special case, a nil proof means the leaves are expected to be
+// the entire trie. Another special case is an empty leaf set, in which case
+// the proof of firstKey must show that no more elements exist after it.
+//
+// On success, the trie nodes reconstructed from the range (all of which are
+// genuine nodes of the trie with the given root) are returned in a database,
+// along with a flag whether more elements are available to the right.
+func VerifyRangeProof(rootHash common.Hash, firstKey []byte, keys [][]byte, values [][]byte, proofDb DatabaseReader) (*ethdb.MemDatabase, bool, error) {
+	if len(keys) != len(values) {
+		return nil, false, fmt.Errorf("inconsistent proof data, keys: %d, values: %d", len(keys), len(values))
+	}
+	// Ensure the received batch is monotonically increasing
+	for i := 0; i < len(keys)-1; i++ {
+		if bytes.Compare(keys[i], keys[i+1]) >= 0 {
+			return nil, false, errors.New("range is not monotonically increasing")
+		}
+	}
+	nodes := ethdb.NewMemDatabase()
+
+	// Special case, there is no edge proof at all. The given range is expected
+	// to be the whole leaf-set in the trie.
+	if proofDb == nil {
+		tr := &Trie{db: NewDatabase(nodes)}
+		for i, key := range keys {
+			if err := tr.TryUpdate(key, values[i]); err != nil {
+				return nil, false, err
+			}
+		}
+		if err := commitRange(tr, rootHash); err != nil {
+			return nil, false, err
+		}
+		return nodes, false, nil
+	}
+	// Special case, there is a provided edge proof but zero key/value pairs,
+	// ensure there are no more elements in the trie.
+	if len(keys) == 0 {
+		root, err := proofToPath(rootHash, nil, firstKey, proofDb, nodes)
+		if err != nil {
+			return nil, false, err
+		}
+		if hasRightElement(root, firstKey) {
+			return nil, false, errors.New("more entries available")
+		}
+		if _, val := get(root, keybytesToHex(firstKey), true); val != nil {
+			return nil, false, errors.New("more entries available")
+		}
+		return nodes, false, nil
+	}
+	lastKey := keys[len(keys)-1]
+	if bytes.Compare(firstKey, keys[0]) > 0 {
+		return nil, false, errors.New("range starts before the first edge key")
+	}
+	// Special case, there is only one element and the two edge keys are the
+	// same. In this case, we can't construct two edge paths.
+	if bytes.Equal(firstKey, lastKey) {
+		root, err := proofToPath(rootHash, nil, firstKey, proofDb, nodes)
+		if err != nil {
+			return nil, false, err
+		}
+		_, val := get(root, keybytesToHex(firstKey), true)
+		if val, ok := val.(valueNode); !ok || !bytes.Equal(val, values[0]) {
+			return nil, false, errors.New("correct proof but invalid data")
+		}
+		return nodes, hasRightElement(root, firstKey), nil
+	}
+	if len(firstKey) != len(lastKey) {
+		return nil, false, errors.New("inconsistent edge keys")
+	}
+	// Convert the edge proofs to edge trie paths, the second path being merged
+	// into the first one. Non-existent proofs are allowed for both edges.
+	root, err := proofToPath(rootHash, nil, firstKey, proofDb, nodes)
+	if err != nil {
+		return nil, false, err
+	}
+	root, err = proofToPath(rootHash, root, lastKey, proofDb, nodes)
+	if err != nil {
+		return nil, false, err
+	}
+	// Remove all internal references, which are to be reconstructed from the
+	// given leaves. The rebuilt trie should have the same shape as the original.
+	empty, err := unsetInternal(root, firstKey, lastKey)
+	if err != nil {
+		return nil, false, err
+	}
+	tr := &Trie{root: root, db: NewDatabase(nodes)}
+	if empty {
+		tr.root = nil
+	}
+	for i, key := range keys {
+		if err := tr.TryUpdate(key, values[i]); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := commitRange(tr, rootHash); err != nil {
+		return nil, false, err
+	}
+	return nodes, hasRightElement(root, lastKey), nil
+}
+
+// commitRange checks that a trie rebuilt from a leaf range hashes to the
+// expected root and flushes all its nodes into the backing database.
+func commitRange(tr *Trie, rootHash common.Hash) error {
+	if hash := tr.Hash(); hash != rootHash {
+		return fmt.Errorf("invalid proof, want hash %x, got %x", rootHash, hash)
+	}
+	if rootHash == emptyRoot {
+		return nil
+	}
+	root, err := tr.Commit(nil)
+	if err != nil {
+		return err
+	}
+	return tr.db.Commit(root, false)
+}
+
+// get returns the child of the given node. Return nil if the node with specified
+// key doesn't exist at all.
+//
+// There is an additional flag `skipResolved`. If it's set then all resolved nodes
+// won't be returned.
+func get(tn node, key []byte, skipResolved bool) ([]byte, node) {
 	for {
 		switch n := tn.(type) {
 		case *shortNode:
@@ -152,9 +547,15 @@ func get(tn node, key []byte) ([]byte, node) {
 			}
 			tn = n.Val
 			key = key[len(n.Key):]
+			if !skipResolved {
+				return key, tn
+			}
 		case *fullNode:
 			tn = n.Children[key[0]]
 			key = key[1:]
+			if !skipResolved {
+				return key, tn
+			}
 		case hashNode:
 			return key, n
 		case nil:
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -88,6 +89,181 @@ func TestVerifyBadProof(t *testing.T) {
 	}
 }
 
+// Tests that contiguous ranges of a trie can be proven with two edge proofs,
+// and that the nodes rebuilt from the range are genuine nodes of the trie.
+func TestRangeProof(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := start + mrand.Intn(len(entries)-start)
+
+		proof := ethdb.NewMemDatabase()
+		if err := trie.Prove(entries[start].k, 0, proof); err != nil {
+			t.Fatalf("Failed to prove the first node %v", err)
+		}
+		if err := trie.Prove(entries[end].k, 0, proof); err != nil {
+			t.Fatalf("Failed to prove the last node %v", err)
+		}
+		var keys, values [][]byte
+		for j := start; j <= end; j++ {
+			keys = append(keys, entries[j].k)
+			values = append(values, entries[j].v)
+		}
+		nodes, more, err := VerifyRangeProof(root, keys[0], keys, values, proof)
+		if err != nil {
+			t.Fatalf("Case %d(%d->%d) expect no error, got %v", i, start, end, err)
+		}
+		if more != (end < len(entries)-1) {
+			t.Fatalf("Case %d(%d->%d) more elements mismatch: have %v", i, start, end, more)
+		}
+		if blob, _ := nodes.Get(root[:]); blob == nil {
+			t.Fatalf("Case %d(%d->%d) root node missing from rebuilt nodes", i, start, end)
+		}
+		for _, key := range nodes.Keys() {
+			blob, _ := nodes.Get(key)
+			if !bytes.Equal(crypto.Keccak256(blob), key) {
+				t.Fatalf("Case %d(%d->%d) node %x stored under wrong hash", i, start, end, key)
+			}
+		}
+	}
+}
+
+// Tests that ranges starting at a non-existent key (e.g. the boundary of a
+// sync task) can be proven too.
+func TestRangeProofWithNonExistentProof(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries)-1) + 1
+		end := start + mrand.Intn(len(entries)-start)
+
+		first := decreaseKey(common.CopyBytes(entries[start].k))
+		if bytes.Equal(first, entries[start-1].k) {
+			continue
+		}
+		proof := ethdb.NewMemDatabase()
+		if err := trie.Prove(first, 0, proof); err != nil {
+			t.Fatalf("Failed to prove the first node %v", err)
+		}
+		if err := trie.Prove(entries[end].k, 0, proof); err != nil {
+			t.Fatalf("Failed to prove the last node %v", err)
+		}
+		var keys, values [][]byte
+		for j := start; j <= end; j++ {
+			keys = append(keys, entries[j].k)
+			values = append(values, entries[j].v)
+		}
+		if _, _, err := VerifyRangeProof(root, first, keys, values, proof); err != nil {
+			t.Fatalf("Case %d(%d->%d) expect no error, got %v", i, start, end, err)
+		}
+	}
+}
+
+// Tests that tampered ranges (missing, modified or extra elements) are rejected.
+func TestBadRangeProof(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := start + mrand.Intn(len(entries)-start)
+		if end-start < 2 {
+			continue
+		}
+		proof := ethdb.NewMemDatabase()
+		trie.Prove(entries[start].k, 0, proof)
+		trie.Prove(entries[end].k, 0, proof)
+
+		var keys, values [][]byte
+		for j := start; j <= end; j++ {
+			keys = append(keys, entries[j].k)
+			values = append(values, entries[j].v)
+		}
+		index := 1 + mrand.Intn(len(keys)-2)
+		switch mrand.Intn(2) {
+		case 0:
+			// Drop an interior element
+			keys = append(keys[:index], keys[index+1:]...)
+			values = append(values[:index], values[index+1:]...)
+		case 1:
+			// Modify an interior value
+			values[index] = randBytes(20)
+		}
+		if _, _, err := VerifyRangeProof(root, keys[0], keys, values, proof); err == nil {
+			t.Fatalf("Case %d(%d->%d) expected error, got nil", i, start, end)
+		}
+	}
+}
+
+// Tests the special cases of the whole trie being delivered without proofs,
+// and an empty range proving there are no more elements.
+func TestRangeProofSpecialCases(t *testing.T) {
+	trie, vals := randomTrie(64)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	var keys, values [][]byte
+	for _, entry := range entries {
+		keys = append(keys, entry.k)
+		values = append(values, entry.v)
+	}
+	if _, more, err := VerifyRangeProof(root, nil, keys, values, nil); err != nil || more {
+		t.Fatalf("full range verification failed: more %v, err %v", more, err)
+	}
+	if _, _, err := VerifyRangeProof(root, nil, keys[1:], values[1:], nil); err == nil {
+		t.Fatalf("partial range accepted without proofs")
+	}
+	// Prove that nothing exists after the last element
+	last := increaseKey(common.CopyBytes(entries[len(entries)-1].k))
+	proof := ethdb.NewMemDatabase()
+	trie.Prove(last, 0, proof)
+	if _, more, err := VerifyRangeProof(root, last, nil, nil, proof); err != nil || more {
+		t.Fatalf("empty tail range verification failed: more %v, err %v", more, err)
+	}
+	// Prove that an empty range in the middle is rejected
+	first := decreaseKey(common.CopyBytes(entries[len(entries)/2].k))
+	proof = ethdb.NewMemDatabase()
+	trie.Prove(first, 0, proof)
+	if _, _, err := VerifyRangeProof(root, first, nil, nil, proof); err == nil {
+		t.Fatalf("empty range accepted with entries remaining")
+	}
+}
+
+func sortedEntries(vals map[string]*kv) []*kv {
+	var entries []*kv
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].k, entries[j].k) < 0 })
+	return entries
+}
+
+func increaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]++
+		if key[i] != 0x0 {
+			break
+		}
+	}
+	return key
+}
+
+func decreaseKey(key []byte) []byte {
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i]--
+		if key[i] != 0xff {
+			break
+		}
+	}
+	return key
+}
+
 // mutateByte changes one byte in b.
 func mutateByte(b []byte) {
 	for r := mrand.Intn(len(b)); ; {
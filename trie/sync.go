@@ -42,6 +42,7 @@ type request struct {
 	// 노드의 데이터. 모든 서브트리가 완성될때까지 캐싱됨
 	raw  bool        // Whether this is a raw entry (code) or a trie node
 	// raw entry인지 trie노드인지 구분
+	local bool // Whether the node is known locally and only walked to heal its subtrie
 
 	parents []*request // Parent state nodes referencing this entry (notify all upon completion)
 	// 이 엔트리를 참조하는 부모상태노드들
@@ -100,6 +101,7 @@ type TrieSync struct {
 	// 키 해시에 관계된 대기중인 요청
 	queue    *prque.Prque             // Priority queue with the pending requests
 	// 대기중 요청을 위한 우선순위큐
+	heal bool // Whether to descend into locally known nodes to find missing ones
 }
 
 // NewTrieSync creates a new trie data download scheduler.
@@ -115,6 +117,20 @@ func NewTrieSync(root common.Hash, database DatabaseReader, callback LeafCallbac
 	return ts
 }
 
+// NewTrieHeal creates a trie healing scheduler. Contrary to a regular sync, the
+// healer doesn't assume locally known nodes to have complete subtries, but walks
+// them to retrieve any missing descendants, as left behind by a partial import
+// (e.g. from range proofs). The tries to heal are added via AddSubTrie.
+func NewTrieHeal(database DatabaseReader) *TrieSync {
+	return &TrieSync{
+		database: database,
+		membatch: newSyncMemBatch(),
+		requests: make(map[common.Hash]*request),
+		queue:    prque.New(),
+		heal:     true,
+	}
+}
+
 // AddSubTrie registers a new trie to the sync code, rooted at the designated parent.
 // AddSubTrie함수는 새로운 트라이를 싱크 코드에 등록하고, 지정된 부모에 자리잡도록 한다
 func (s *TrieSync) AddSubTrie(root common.Hash, depth int, parent common.Hash, callback LeafCallback) {
@@ -128,7 +144,22 @@ func (s *TrieSync) AddSubTrie(root common.Hash, depth int, parent common.Hash, c
 	key := root.Bytes()
 	blob, _ := s.database.Get(key)
 	if local, err := decodeNode(key, blob, 0); local != nil && err == nil {
-		return
+		if !s.heal {
+			return
+		}
+		// When healing, walk the local trie unless already doing so
+		if _, pending := s.requests[root]; !pending {
+			var ancestor *request
+			if parent != (common.Hash{}) {
+				if ancestor = s.requests[parent]; ancestor == nil {
+					panic(fmt.Sprintf("sub-trie ancestor not found: %x", parent))
+				}
+			}
+			if req, _ := s.resolveLocal(root, local, blob, depth, ancestor, callback); req != nil && ancestor != nil {
+				ancestor.deps++
+			}
+			return
+		}
 	}
 	// Assemble the new sub-trie sync request
 	req := &request{
@@ -275,6 +306,10 @@ func (s *TrieSync) Pending() int {
 // 만약 이미 이노드를 위한 대기 요청이 있을경우, 새로운 요청은 무시되고 
 // 부모의 참조만 기존것으로 추가된다
 func (s *TrieSync) schedule(req *request) {
+	// Locally known nodes are tracked when expanded, never retrieved
+	if req.local {
+		return
+	}
 	// If we're already requesting this node, add a new reference and stop
 	if old, ok := s.requests[req.hash]; ok {
 		old.parents = append(old.parents, req.parents...)
@@ -333,7 +368,28 @@ func (s *TrieSync) children(req *request, object node) ([]*request, error) {
 				continue
 			}
 			if ok, _ := s.database.Has(node); ok {
-				continue
+				if !s.heal {
+					continue
+				}
+				// When healing, walk the local node unless already doing so
+				if _, pending := s.requests[hash]; !pending {
+					blob, err := s.database.Get(node)
+					if err != nil {
+						return nil, err
+					}
+					local, err := decodeNode(node, blob, 0)
+					if err != nil {
+						return nil, err
+					}
+					incomplete, err := s.resolveLocal(hash, local, blob, child.depth, req, req.callback)
+					if err != nil {
+						return nil, err
+					}
+					if incomplete != nil {
+						requests = append(requests, incomplete)
+					}
+					continue
+				}
 			}
 			// Locally unknown node, schedule for retrieval
 			requests = append(requests, &request{
@@ -353,9 +409,11 @@ func (s *TrieSync) children(req *request, object node) ([]*request, error) {
 // coomit 함수는 반환요청을 최종화하고, 메모리 배치에 저장한다. 
 // 만약 참조하는 부모의 요청들이 이 commit으로 완료되면 그들도 스스로 commit 된다
 func (s *TrieSync) commit(req *request) (err error) {
-	// Write the node content to the membatch
-	s.membatch.batch[req.hash] = req.data
-	s.membatch.order = append(s.membatch.order, req.hash)
+	// Write the node content to the membatch, unless already stored
+	if !req.local {
+		s.membatch.batch[req.hash] = req.data
+		s.membatch.order = append(s.membatch.order, req.hash)
+	}
 
 	delete(s.requests, req.hash)
 
@@ -370,3 +428,46 @@ func (s *TrieSync) commit(req *request) (err error) {
 	}
 	return nil
 }
+
+// resolveLocal walks a locally known trie node, returning the request tracking
+// it if any of its descendants are missing, or nil if its subtrie is complete.
+func (s *TrieSync) resolveLocal(hash common.Hash, object node, blob []byte, depth int, parent *request, callback LeafCallback) (*request, error) {
+	req := &request{
+		hash:     hash,
+		data:     blob,
+		local:    true,
+		depth:    depth,
+		callback: callback,
+	}
+	if parent != nil {
+		req.parents = []*request{parent}
+	}
+	incomplete, err := s.expand(req, object)
+	if err != nil || !incomplete {
+		return nil, err
+	}
+	return req, nil
+}
+
+// expand schedules the retrieval of the missing children of a locally known trie
+// node, returning whether the node is incomplete. Incomplete nodes are tracked as
+// pending until all their descendants are retrieved, complete ones are dropped.
+func (s *TrieSync) expand(req *request, object node) (bool, error) {
+	// Track the node while walking it, leaf callbacks may link to it
+	s.requests[req.hash] = req
+
+	requests, err := s.children(req, object)
+	if err != nil {
+		delete(s.requests, req.hash)
+		return false, err
+	}
+	if len(requests) == 0 && req.deps == 0 {
+		delete(s.requests, req.hash)
+		return false, nil
+	}
+	req.deps += len(requests)
+	for _, child := range requests {
+		s.schedule(child)
+	}
+	return true, nil
+}
@@ -356,3 +356,70 @@ func TestIncompleteTrieSync(t *testing.T) {
 		diskdb.Put(key, value)
 	}
 }
+
+// Tests that a partially imported trie, with its root and some inner nodes known
+// but their subtries incomplete, is healed by walking the local nodes.
+func TestTrieHeal(t *testing.T) {
+	// Create a random trie to copy
+	srcDb, srcTrie, srcData := makeTestTrie()
+
+	// Import every node apart from a few inner ones into the destination
+	diskdb := ethdb.NewMemDatabase()
+	triedb := NewDatabase(diskdb)
+
+	var (
+		nodes   []common.Hash
+		skipped = make(map[common.Hash]bool)
+	)
+	for it := srcTrie.NodeIterator(nil); it.Next(true); {
+		if hash := it.Hash(); hash != (common.Hash{}) && !skipped[hash] {
+			nodes = append(nodes, hash)
+			skipped[hash] = true
+		}
+	}
+	for i, hash := range nodes {
+		if i == 0 || i%7 != 0 {
+			data, err := srcDb.Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x: %v", hash, err)
+			}
+			diskdb.Put(hash[:], data)
+			delete(skipped, hash)
+		}
+	}
+	// A regular sync considers the trie complete, healing must find the gaps
+	if sched := NewTrieSync(srcTrie.Hash(), diskdb, nil); sched.Pending() != 0 {
+		t.Fatalf("regular sync pending mismatch: have %d, want 0", sched.Pending())
+	}
+	sched := NewTrieHeal(diskdb)
+	sched.AddSubTrie(srcTrie.Hash(), 0, common.Hash{}, nil)
+
+	requested := 0
+	queue := append([]common.Hash{}, sched.Missing(0)...)
+	for len(queue) > 0 {
+		results := make([]SyncResult, len(queue))
+		for i, hash := range queue {
+			data, err := srcDb.Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x: %v", hash, err)
+			}
+			results[i] = SyncResult{hash, data}
+		}
+		requested += len(queue)
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		if index, err := sched.Commit(diskdb); err != nil {
+			t.Fatalf("failed to commit data #%d: %v", index, err)
+		}
+		queue = append(queue[:0], sched.Missing(0)...)
+	}
+	if requested != len(skipped) {
+		t.Errorf("healed node count mismatch: have %d, want %d", requested, len(skipped))
+	}
+	if sched.Pending() != 0 {
+		t.Errorf("pending requests after heal: %d", sched.Pending())
+	}
+	// Cross check that the two tries are in sync
+	checkTrieContents(t, triedb, srcTrie.Root(), srcData)
+}